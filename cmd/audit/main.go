@@ -0,0 +1,66 @@
+// Command audit checks the stored ticker history of pairs over a period for gaps, duplicate and
+// out-of-order tickers, streaks of candles without volume and outlier prices. It exits with status 1
+// when it finds issues.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/audit"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/cli"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/report"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+	_ "github.com/mattn/go-sqlite3" // Driver for database/sql
+)
+
+func main() {
+	cfg := audit.DefaultConfig()
+	dbPath := flag.String("db", "ticks.db", "path of the SQLite database")
+	symbols := flag.String("symbol", "BTCUSDT", "comma-separated pairs to audit")
+	fromFlag := flag.String("from", "", "start of the period (YYYY-MM-DD or RFC3339)")
+	toFlag := flag.String("to", "", "end of the period (YYYY-MM-DD or RFC3339), defaults to now")
+	tfFlag := flag.String("timeframe", string(cfg.Timeframe), "timeframe of the candles checked for volume")
+	flag.DurationVar(&cfg.MaxGap, "max-gap", cfg.MaxGap, "longest time without a ticker that is not a gap, 0 to skip the check")
+	flag.IntVar(&cfg.MaxZeroVolume, "max-zero-volume", cfg.MaxZeroVolume, "most consecutive candles without volume, 0 to skip the check")
+	flag.Float64Var(&cfg.OutlierPercent, "outlier", cfg.OutlierPercent, "largest distance of a price from the median before it, in percent, 0 to skip the check")
+	flag.IntVar(&cfg.OutlierWindow, "outlier-window", cfg.OutlierWindow, "tickers the median of the outlier check is taken over")
+	limit := flag.Int("limit", 50, "issues to list per pair, 0 for all")
+	flag.Parse()
+
+	var err error
+	if cfg.Timeframe, err = domain.ParseTimeframe(*tfFlag); err != nil {
+		log.Fatalf("Invalid timeframe: %v", err)
+	}
+	from, to, err := cli.ParsePeriod(*fromFlag, *toFlag)
+	if err != nil {
+		log.Fatalf("Invalid period: %v", err)
+	}
+
+	ctx := context.Background()
+	repo, err := storage.NewSqliteRepository(ctx, *dbPath)
+	if err != nil {
+		log.Fatalf("Database initialization failed: %v", err)
+	}
+	defer repo.Close()
+
+	clean := true
+	for _, s := range strings.Split(*symbols, ",") {
+		rep, err := audit.Run(ctx, repo, strings.ToUpper(strings.TrimSpace(s)), from, to, cfg)
+		if err != nil {
+			log.Fatalf("Audit failed: %v", err)
+		}
+		if err := report.WriteAudit(os.Stdout, rep, *limit); err != nil {
+			log.Fatalf("Writing audit failed: %v", err)
+		}
+		clean = clean && rep.OK()
+	}
+	if !clean {
+		repo.Close()
+		os.Exit(1)
+	}
+}
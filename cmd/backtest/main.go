@@ -0,0 +1,204 @@
+// Command backtest replays the stored history of a pair, or of a portfolio of pairs sharing one
+// balance, through a strategy and reports the simulated trades and the resulting equity.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/audit"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/cli"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/report"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+	_ "github.com/mattn/go-sqlite3" // Driver for database/sql
+)
+
+func main() {
+	cfg := backtest.DefaultConfig()
+	dbPath := flag.String("db", "ticks.db", "path of the SQLite database")
+	strategyFlag := flag.String("strategy", "stoch-bb", "built-in strategy (stoch-bb or rsi-divergence) or strategy definition file")
+	symbol := flag.String("symbol", "BTCUSDT", "pair to backtest")
+	symbols := flag.String("symbols", "", "comma-separated pairs to backtest as a portfolio on one balance, in order of priority, instead of -symbol")
+	maxPositions := flag.Int("max-positions", 0, "portfolio: most positions open at the same time, 0 for no limit")
+	allocation := flag.String("allocation", "plan", "portfolio: size of a new position: plan, equal or percent:<value> of the equity")
+	signalWindow := flag.Duration("signal-window", 10*time.Second, "portfolio: time after a candle close the signals of all pairs compete for the free positions")
+	tfFlag := flag.String("timeframe", "1m", "timeframe of a built-in strategy and of the equity curve")
+	fromFlag := flag.String("from", "", "start of the period (YYYY-MM-DD or RFC3339)")
+	toFlag := flag.String("to", "", "end of the period (YYYY-MM-DD or RFC3339), defaults to now")
+	flag.Float64Var(&cfg.InitialBalance, "balance", cfg.InitialBalance, "initial balance in the quote asset")
+	flag.BoolVar(&cfg.AllowShort, "short", false, "let sell signals open short positions")
+	flag.DurationVar(&cfg.WarmUp, "warmup", cfg.WarmUp, "history before -from replayed to warm up the strategy; extended to what the strategy needs")
+	feeTier := flag.String("fees", "VIP0", "Binance spot fee tier (VIP0 to VIP9), or none")
+	slippage := flag.Float64("slippage", 0, "fixed slippage of market orders, in percent")
+	slippageATR := flag.Float64("slippage-atr", 0, "slippage of market orders as a multiple of the 14-candle ATR, instead of -slippage")
+	depth := flag.Bool("depth", false, "fill market orders through the stored order book snapshots")
+	flag.DurationVar(&cfg.Latency, "latency", 0, "time between a signal and its order reaching the exchange")
+	mcCfg := backtest.DefaultMonteCarloConfig()
+	monteCarlo := flag.String("montecarlo", "", "comma-separated Monte Carlo simulations of the trades: shuffle, bootstrap, skip")
+	flag.IntVar(&mcCfg.Runs, "mc-runs", mcCfg.Runs, "runs per Monte Carlo simulation")
+	flag.Float64Var(&mcCfg.SkipPercent, "mc-skip", mcCfg.SkipPercent, "probability, in percent, that the skip simulation drops a trade")
+	flag.Int64Var(&mcCfg.Seed, "mc-seed", mcCfg.Seed, "seed of the Monte Carlo simulations")
+	jsonPath := flag.String("json", "", "also write the report as JSON to this file")
+	htmlPath := flag.String("html", "", "also write the report as an HTML page to this file")
+	save := flag.Bool("save", true, "store the run in the database, to list and compare it with cmd/runs")
+	auditCfg := audit.DefaultConfig()
+	auditMode := flag.String("audit", "warn", "data audit of the history before the backtest: off, warn or refuse")
+	flag.DurationVar(&auditCfg.MaxGap, "audit-max-gap", auditCfg.MaxGap, "longest time without a ticker the data audit accepts")
+	flag.Parse()
+
+	tf, err := domain.ParseTimeframe(*tfFlag)
+	if err != nil {
+		log.Fatalf("Invalid timeframe: %v", err)
+	}
+	from, to, err := cli.ParsePeriod(*fromFlag, *toFlag)
+	if err != nil {
+		log.Fatalf("Invalid period: %v", err)
+	}
+	cfg.Symbol, cfg.Timeframe, cfg.From, cfg.To = strings.ToUpper(*symbol), tf, from, to
+	if cfg.Fill, err = cli.FillModel(*feeTier, *slippage, *slippageATR, *depth); err != nil {
+		log.Fatalf("Invalid fill model: %v", err)
+	}
+	if *auditMode != "off" && *auditMode != "warn" && *auditMode != "refuse" {
+		log.Fatalf("Invalid audit mode %q, expected off, warn or refuse", *auditMode)
+	}
+
+	ctx := context.Background()
+	repo, err := storage.NewSqliteRepository(ctx, *dbPath)
+	if err != nil {
+		log.Fatalf("Database initialization failed: %v", err)
+	}
+	defer repo.Close()
+
+	engine := backtest.NewEngine(cfg, repo)
+	pairs := []string{cfg.Symbol}
+	if *symbols != "" {
+		pcfg := backtest.PortfolioConfig{Config: cfg, MaxPositions: *maxPositions, SignalWindow: *signalWindow}
+		for _, s := range strings.Split(*symbols, ",") {
+			pcfg.Symbols = append(pcfg.Symbols, strings.ToUpper(strings.TrimSpace(s)))
+		}
+		if pcfg.Allocation, err = backtest.ParseAllocation(*allocation); err != nil {
+			log.Fatalf("Invalid allocation: %v", err)
+		}
+		if *htmlPath != "" {
+			log.Fatalf("The HTML report charts a single pair, it is not available for a portfolio")
+		}
+		engine, pairs = backtest.NewPortfolioEngine(pcfg, repo), pcfg.Symbols
+	}
+	if err := addStrategy(engine, *strategyFlag, tf); err != nil {
+		log.Fatalf("Loading strategy failed: %v", err)
+	}
+	if *auditMode != "off" {
+		if clean := auditHistory(ctx, repo, pairs, cfg.From.Add(-cfg.WarmUp), cfg.To, auditCfg); !clean && *auditMode == "refuse" {
+			log.Fatalf("Refusing to backtest a history with issues; see cmd/audit, or run with -audit warn")
+		}
+	}
+	res, err := engine.Run(ctx)
+	if err != nil {
+		log.Fatalf("Backtest failed: %v", err)
+	}
+	rep := report.NewBacktestReport(res)
+	if *monteCarlo != "" && len(res.Trades) == 0 {
+		log.Printf("No trades for a Monte Carlo simulation")
+	} else if *monteCarlo != "" {
+		for _, method := range strings.Split(*monteCarlo, ",") {
+			mcCfg.Method = backtest.MonteCarloMethod(strings.TrimSpace(method))
+			mc, err := backtest.MonteCarlo(res, mcCfg)
+			if err != nil {
+				log.Fatalf("Monte Carlo simulation failed: %v", err)
+			}
+			rep.MonteCarlo = append(rep.MonteCarlo, mc)
+		}
+	}
+	if err := rep.WriteTable(os.Stdout); err != nil {
+		log.Fatalf("Writing report failed: %v", err)
+	}
+	if *save {
+		hash, err := cli.DefinitionHash(*strategyFlag)
+		if err != nil {
+			log.Fatalf("Hashing strategy failed: %v", err)
+		}
+		run, err := rep.NewBacktestRun(*strategyFlag, hash, cli.RunParams(runFlags))
+		if err != nil {
+			log.Fatalf("Storing run failed: %v", err)
+		}
+		id, err := repo.SaveBacktestRun(ctx, run)
+		if err != nil {
+			log.Fatalf("Storing run failed: %v", err)
+		}
+		log.Printf("Run stored as %d", id)
+	}
+	if *jsonPath != "" {
+		if err := cli.WriteFile(*jsonPath, func(f *os.File) error { return rep.WriteJSON(f) }); err != nil {
+			log.Fatalf("Writing JSON failed: %v", err)
+		}
+		log.Printf("Report written to %s", *jsonPath)
+	}
+	if *htmlPath != "" {
+		if err := cli.WriteFile(*htmlPath, func(f *os.File) error { return rep.WriteHTML(f, res) }); err != nil {
+			log.Fatalf("Writing HTML failed: %v", err)
+		}
+		log.Printf("Report written to %s", *htmlPath)
+	}
+}
+
+// addStrategy adds a built-in strategy on a timeframe, or a strategy definition file on its own timeframes.
+func addStrategy(engine *backtest.Engine, name string, tf domain.Timeframe) error {
+	switch name {
+	case "stoch-bb":
+		engine.Add(tf, func() strategy.Strategy { return strategy.NewStochBB(strategy.DefaultStochBBConfig()) })
+	case "rsi-divergence":
+		engine.Add(tf, func() strategy.Strategy { return strategy.NewRSIDivergence(strategy.DefaultRSIDivergenceConfig()) })
+	default:
+		def, err := strategy.LoadDefinition(name)
+		if err != nil {
+			return err
+		}
+		factory, err := def.Factory()
+		if err != nil {
+			return err
+		}
+		for _, dtf := range def.ParsedTimeframes() {
+			engine.Add(dtf, factory)
+		}
+	}
+	return nil
+}
+
+// auditHistory audits the stored history of the pairs over a period and logs the issues it finds. It
+// reports whether the history is clean.
+func auditHistory(ctx context.Context, history storage.StoredTickerHistory, pairs []string, from, to time.Time, cfg audit.Config) bool {
+	clean := true
+	for _, pair := range pairs {
+		rep, err := audit.Run(ctx, history, pair, from, to, cfg)
+		if err != nil {
+			log.Fatalf("Data audit failed: %v", err)
+		}
+		if rep.OK() {
+			continue
+		}
+		clean = false
+		log.Printf("Data audit of %s: %s", pair, rep.Summary())
+		for i, issue := range rep.Issues {
+			if i == 5 {
+				log.Printf("  ... and %d more", len(rep.Issues)-i)
+				break
+			}
+			log.Printf("  %s %s: %s", issue.Kind, issue.From.Format("2006-01-02 15:04:05"), issue.Detail)
+		}
+	}
+	return clean
+}
+
+// runFlags are the flags stored in their own columns of a run, or that do not change its results.
+var runFlags = map[string]bool{
+	"db": true, "strategy": true, "symbol": true, "timeframe": true, "from": true, "to": true,
+	"json": true, "html": true, "save": true, "montecarlo": true, "mc-runs": true, "mc-skip": true, "mc-seed": true,
+	"audit": true, "audit-max-gap": true,
+}
@@ -0,0 +1,239 @@
+// Command optimize backtests a built-in strategy, or a strategy definition file on the parameters
+// of its indicators, over a grid or random samples of its parameters, in parallel, and ranks the
+// results by an objective. With -out-of-sample it runs a walk-forward analysis instead,
+// re-optimizing on rolling in-sample windows and trading the best parameters on the periods that
+// follow them.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/cli"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/optimize"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/report"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+	_ "github.com/mattn/go-sqlite3" // Driver for database/sql
+)
+
+func main() {
+	cfg := backtest.DefaultConfig()
+	var space paramsFlag
+	var objective optimize.Objective
+	dbPath := flag.String("db", "ticks.db", "path of the SQLite database")
+	strategyName := flag.String("strategy", "stoch-bb", "built-in strategy ("+strings.Join(optimize.Strategies, " or ")+") or strategy definition file")
+	flag.Var(&space, "param", "parameter to search as name=min:max:step, or name=value to fix it; repeatable")
+	symbol := flag.String("symbol", "BTCUSDT", "pair to backtest")
+	tfFlag := flag.String("timeframe", "1m", "timeframe of a built-in strategy and of the equity curve")
+	fromFlag := flag.String("from", "", "start of the period (YYYY-MM-DD or RFC3339)")
+	toFlag := flag.String("to", "", "end of the period (YYYY-MM-DD or RFC3339), defaults to now")
+	flag.Float64Var(&cfg.InitialBalance, "balance", cfg.InitialBalance, "initial balance in the quote asset")
+	flag.BoolVar(&cfg.AllowShort, "short", false, "let sell signals open short positions")
+	flag.DurationVar(&cfg.WarmUp, "warmup", cfg.WarmUp, "history before -from replayed to warm up the strategy")
+	feeTier := flag.String("fees", "VIP0", "Binance spot fee tier (VIP0 to VIP9), or none")
+	slippage := flag.Float64("slippage", 0, "fixed slippage of market orders, in percent")
+	flag.DurationVar(&cfg.Latency, "latency", 0, "time between a signal and its order reaching the exchange")
+	flag.StringVar(&objective.Metric, "objective", "profit_factor", "metric to maximize: "+strings.Join(optimize.Metrics, ", "))
+	flag.Float64Var(&objective.MaxDrawdown, "max-drawdown", 0, "largest max equity drawdown, in percent, of a ranked result; 0 for no limit")
+	flag.IntVar(&objective.MinTrades, "min-trades", 1, "fewest trades of a ranked result")
+	random := flag.Int("random", 0, "backtest this many random samples of the parameters instead of the whole grid")
+	seed := flag.Int64("seed", 1, "seed of -random")
+	workers := flag.Int("workers", 0, "backtests to run in parallel, 0 for one per CPU")
+	top := flag.Int("top", 20, "results to print, 0 for all")
+	csvPath := flag.String("csv", "", "also write all results as CSV to this file")
+	heatmapPath := flag.String("heatmap", "", "write an SVG heatmap of the objective to this file; needs exactly two searched parameters")
+	inSample := flag.Duration("in-sample", 30*24*time.Hour, "walk-forward: period to optimize the parameters on")
+	outOfSample := flag.Duration("out-of-sample", 0, "walk-forward: period to trade the optimized parameters on; 0 runs a single search")
+	anchored := flag.Bool("anchored", false, "walk-forward: keep every in-sample period starting at -from")
+	htmlPath := flag.String("html", "", "walk-forward: write the stitched out-of-sample backtest as an HTML page to this file")
+	save := flag.Bool("save", true, "store the backtest of the best parameters, or walk-forward the out-of-sample backtests, in the database, to list and compare them with cmd/runs")
+	flag.Parse()
+
+	names, err := optimize.ParamNames(*strategyName)
+	if err != nil {
+		log.Fatalf("Invalid strategy: %v", err)
+	}
+	if len(space) == 0 {
+		log.Fatalf("No parameters to search, use -param with one of %s", strings.Join(names, ", "))
+	}
+	if err := objective.Validate(); err != nil {
+		log.Fatalf("Invalid objective: %v", err)
+	}
+	tf, err := domain.ParseTimeframe(*tfFlag)
+	if err != nil {
+		log.Fatalf("Invalid timeframe: %v", err)
+	}
+	from, to, err := cli.ParsePeriod(*fromFlag, *toFlag)
+	if err != nil {
+		log.Fatalf("Invalid period: %v", err)
+	}
+	cfg.Symbol, cfg.Timeframe, cfg.From, cfg.To = strings.ToUpper(*symbol), tf, from, to
+	if cfg.Fill, err = cli.FillModel(*feeTier, *slippage, 0, false); err != nil {
+		log.Fatalf("Invalid fill model: %v", err)
+	}
+	var swept []string
+	for _, p := range space {
+		if p.Max > p.Min {
+			swept = append(swept, p.Name)
+		}
+	}
+	if *heatmapPath != "" && len(swept) != 2 {
+		log.Fatalf("A heatmap needs exactly two searched parameters, got %d", len(swept))
+	}
+
+	ctx := context.Background()
+	repo, err := storage.NewSqliteRepository(ctx, *dbPath)
+	if err != nil {
+		log.Fatalf("Database initialization failed: %v", err)
+	}
+	defer repo.Close()
+
+	points := optimize.Grid(space)
+	if *random > 0 {
+		points = optimize.Random(space, *random, *seed)
+	}
+	// The history is loaded once for all backtests, so it covers the longest warm-up among them.
+	warmUp, err := optimize.WarmUpPeriod(cfg, *strategyName, points)
+	if err != nil {
+		log.Fatalf("Invalid strategy: %v", err)
+	}
+	history, err := backtest.LoadHistory(ctx, repo, cfg.Symbol, cfg.From.Add(-warmUp), cfg.To)
+	if err != nil {
+		log.Fatalf("Loading history failed: %v", err)
+	}
+
+	var columns []string
+	for _, p := range space {
+		columns = append(columns, p.Name)
+	}
+
+	if *outOfSample > 0 {
+		windows, err := optimize.Windows(cfg.From, cfg.To, *inSample, *outOfSample, *anchored)
+		if err != nil {
+			log.Fatalf("Invalid walk-forward windows: %v", err)
+		}
+		log.Printf("Walking forward over %d windows of %d backtests of %s on %s %s", len(windows), len(points), *strategyName, cfg.Symbol, tf)
+		newBacktest := func(c backtest.Config) optimize.Backtest { return optimize.EngineBacktest(c, history, *strategyName) }
+		wf, err := optimize.WalkForward(ctx, cfg, windows, points, objective, *workers, newBacktest)
+		if err != nil {
+			log.Fatalf("Walk-forward analysis failed: %v", err)
+		}
+		if err := report.WriteWalkForward(os.Stdout, wf, columns); err != nil {
+			log.Fatalf("Writing results failed: %v", err)
+		}
+		if *save {
+			hash, err := cli.DefinitionHash(*strategyName)
+			if err != nil {
+				log.Fatalf("Hashing strategy failed: %v", err)
+			}
+			runs, err := report.NewWalkForwardRuns(wf, *strategyName, hash, cli.RunParams(runFlags))
+			if err != nil {
+				log.Fatalf("Storing runs failed: %v", err)
+			}
+			for _, run := range runs {
+				id, err := repo.SaveBacktestRun(ctx, run)
+				if err != nil {
+					log.Fatalf("Storing runs failed: %v", err)
+				}
+				log.Printf("Run %s stored as %d", run.Params["walk_forward"], id)
+			}
+		}
+		if *htmlPath != "" {
+			rep := report.NewBacktestReport(wf.OutOfSample)
+			if err := cli.WriteFile(*htmlPath, func(f *os.File) error { return rep.WriteHTML(f, wf.OutOfSample) }); err != nil {
+				log.Fatalf("Writing HTML failed: %v", err)
+			}
+			log.Printf("Report written to %s", *htmlPath)
+		}
+		return
+	}
+
+	log.Printf("Running %d backtests of %s on %s %s", len(points), *strategyName, cfg.Symbol, tf)
+	start := time.Now()
+	trials, err := optimize.Run(ctx, points, objective, *workers, optimize.EngineBacktest(cfg, history, *strategyName))
+	if err != nil {
+		log.Fatalf("Optimization failed: %v", err)
+	}
+	log.Printf("Finished in %s", time.Since(start).Round(time.Millisecond))
+
+	if err := report.WriteTrialsTable(os.Stdout, trials, columns, *top); err != nil {
+		log.Fatalf("Writing results failed: %v", err)
+	}
+	if *save {
+		saveBest(ctx, repo, cfg, history, *strategyName, trials)
+	}
+	if *csvPath != "" {
+		if err := cli.WriteFile(*csvPath, func(f *os.File) error { return report.WriteTrialsCSV(f, trials, columns) }); err != nil {
+			log.Fatalf("Writing CSV failed: %v", err)
+		}
+		log.Printf("Results written to %s", *csvPath)
+	}
+	if *heatmapPath != "" {
+		title := fmt.Sprintf("%s %s %s: %s", *strategyName, cfg.Symbol, tf, objective.Metric)
+		heatmap := report.TrialsHeatmap(title, trials, swept[0], swept[1])
+		if err := cli.WriteFile(*heatmapPath, func(f *os.File) error { return heatmap.WriteSVG(f) }); err != nil {
+			log.Fatalf("Writing heatmap failed: %v", err)
+		}
+		log.Printf("Heatmap written to %s", *heatmapPath)
+	}
+}
+
+// paramsFlag collects the repeated -param flags.
+type paramsFlag []optimize.Param
+
+func (f *paramsFlag) String() string {
+	var parts []string
+	for _, p := range *f {
+		parts = append(parts, fmt.Sprintf("%s=%g:%g:%g", p.Name, p.Min, p.Max, p.Step))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (f *paramsFlag) Set(s string) error {
+	p, err := optimize.ParseParam(s)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, p)
+	return nil
+}
+
+// runFlags are the flags stored in their own columns of a run, or that do not change its results.
+var runFlags = map[string]bool{
+	"db": true, "strategy": true, "symbol": true, "timeframe": true, "from": true, "to": true, "param": true,
+	"workers": true, "top": true, "csv": true, "heatmap": true, "html": true, "save": true,
+}
+
+// saveBest stores the backtest of the best trial, with the parameters it ran with. The trials only
+// keep their metrics, so it is backtested again for its trades.
+func saveBest(ctx context.Context, repo storage.BacktestRunRepository, cfg backtest.Config, history storage.TickerHistory, name string, trials []optimize.Trial) {
+	if len(trials) == 0 || !trials[0].Feasible {
+		log.Printf("No trial met the objective, no run stored")
+		return
+	}
+	best := trials[0].Params
+	res, err := optimize.EngineBacktest(cfg, history, name)(ctx, best)
+	if err != nil {
+		log.Fatalf("Backtesting the best parameters failed: %v", err)
+	}
+	hash, err := cli.DefinitionHash(name)
+	if err != nil {
+		log.Fatalf("Hashing strategy failed: %v", err)
+	}
+	run, err := report.NewTrialRun(res, name, hash, cli.RunParams(runFlags), best)
+	if err != nil {
+		log.Fatalf("Storing run failed: %v", err)
+	}
+	id, err := repo.SaveBacktestRun(ctx, run)
+	if err != nil {
+		log.Fatalf("Storing run failed: %v", err)
+	}
+	log.Printf("Run of %s stored as %d", best, id)
+}
@@ -0,0 +1,85 @@
+// Command runs lists the backtest runs stored by cmd/backtest, and compares them side by side.
+//
+//	runs [flags] list              lists the latest runs
+//	runs [flags] show ID           shows the settings and metrics of a run
+//	runs [flags] diff ID ID        shows what differs between two runs, with the change of every metric
+//	runs [flags] compare ID ID...  shows runs side by side
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/report"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+	_ "github.com/mattn/go-sqlite3" // Driver for database/sql
+)
+
+func main() {
+	dbPath := flag.String("db", "ticks.db", "path of the SQLite database")
+	symbol := flag.String("symbol", "", "list the runs of this pair only")
+	limit := flag.Int("limit", 20, "runs to list, 0 for all")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] list | show ID | diff ID ID | compare ID ID...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	repo, err := storage.NewSqliteRepository(ctx, *dbPath)
+	if err != nil {
+		log.Fatalf("Database initialization failed: %v", err)
+	}
+	defer repo.Close()
+
+	command, args := flag.Arg(0), flag.Args()[1:]
+	var runs []domain.BacktestRun
+	switch {
+	case command == "list" && len(args) == 0:
+		if runs, err = repo.ListBacktestRuns(ctx, strings.ToUpper(*symbol), *limit); err != nil {
+			log.Fatalf("Listing runs failed: %v", err)
+		}
+		err = report.WriteRuns(os.Stdout, runs)
+	case command == "show" && len(args) == 1, command == "diff" && len(args) == 2, command == "compare" && len(args) >= 2:
+		if runs, err = loadRuns(ctx, repo, args); err != nil {
+			log.Fatalf("Loading runs failed: %v", err)
+		}
+		err = report.WriteRunComparison(os.Stdout, runs, command == "diff")
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("Writing runs failed: %v", err)
+	}
+}
+
+// loadRuns loads the runs with the IDs of the arguments.
+func loadRuns(ctx context.Context, repo storage.BacktestRunRepository, args []string) ([]domain.BacktestRun, error) {
+	var runs []domain.BacktestRun
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid run ID %q", arg)
+		}
+		run, err := repo.GetBacktestRun(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if run == nil {
+			return nil, fmt.Errorf("no run with ID %d", id)
+		}
+		runs = append(runs, *run)
+	}
+	return runs, nil
+}
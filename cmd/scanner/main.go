@@ -2,18 +2,43 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/dorpsen/cryptotradingbot-starter/internal/app"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
 	"github.com/dorpsen/cryptotradingbot-starter/internal/exchange"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/opportunity"
 	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/trend"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/universe"
 	_ "github.com/mattn/go-sqlite3" // Driver for database/sql
 )
 
 func main() {
+	strategiesDir := flag.String("strategies", "strategies", "directory with strategy definition files (*.json)")
+	universeCfg := universe.DefaultConfig()
+	selectPairs := flag.Bool("universe", false, "also run the strategies on the pairs selected from the all-market feed")
+	flag.Var(boundFlag{&universeCfg.Volume24h.Min}, "min-volume", "minimum 24h volume, in USDT, of a selected pair")
+	flag.Var(boundFlag{&universeCfg.Volume24h.Max}, "max-volume", "maximum 24h volume, in USDT, of a selected pair")
+	flag.Var(boundFlag{&universeCfg.Change24h.Min}, "min-change", "minimum 24h change, in percent, of a selected pair")
+	flag.Var(boundFlag{&universeCfg.Change24h.Max}, "max-change", "maximum 24h change, in percent, of a selected pair")
+	flag.IntVar(&universeCfg.MaxPairs, "max-pairs", universeCfg.MaxPairs, "maximum number of selected pairs, 0 for no limit")
+	sizerCfg := opportunity.DefaultSizerConfig()
+	balance := flag.Float64("balance", 1000, "free USDT balance the entry maximum of an opportunity is calculated on")
+	flag.Float64Var(&sizerCfg.Budget.Value, "risk", sizerCfg.Budget.Value, "percent of the balance risked per entry, at the stop-loss")
+	flag.Float64Var(&sizerCfg.Slippage, "slippage", sizerCfg.Slippage, "percent beyond the best price an entry may fill at in the order book")
+	saveBooks := flag.Bool("save-books", false, "store a snapshot of the order book of every monitored pair each second, for backtests that fill through the book")
+	httpAddr := flag.String("http", "", "address to serve the Trading Opportunities list on, e.g. :8080; empty to not serve it")
+	flag.Parse()
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
@@ -36,9 +61,98 @@ func main() {
 		log.Fatalf("Streamer connection failed: %v", err)
 	}
 
+	// Tickers are stored under the exchange's upper-case symbol.
+	pair := strings.ToUpper(symbol)
+
 	// Create the main application object, injecting the dependencies.
 	application := app.New(streamer, repo)
 
+	// The chart trend resumes from its stored state for every monitored pair.
+	chartTrend := trend.NewChartTrendService(trend.DefaultChartConfig(), repo)
+	application.AddCandleHandler(chartTrend)
+
+	// Strategies run per pair and timeframe on the live candles and tickers.
+	runner := app.NewStrategyRunner("BINANCE", repo)
+	monitored := &pairs{repo: repo, chartTrend: chartTrend, runner: runner, fixed: pair, strategies: []pairStrategy{
+		{timeframe: domain.Minute1, factory: func() strategy.Strategy {
+			return strategy.NewStochBB(strategy.DefaultStochBBConfig())
+		}},
+		{timeframe: domain.Hour1, factory: func() strategy.Strategy {
+			return strategy.NewRSIDivergence(strategy.DefaultRSIDivergenceConfig())
+		}},
+	}}
+
+	// Strategies defined in files run next to the built-in ones.
+	defs, err := strategy.LoadDefinitions(*strategiesDir)
+	if err != nil {
+		log.Fatalf("Loading strategy definitions failed: %v", err)
+	}
+	for _, def := range defs {
+		factory, err := def.Factory()
+		if err != nil {
+			log.Fatalf("Compiling strategy %q failed: %v", def.Name, err)
+		}
+		for _, tf := range def.ParsedTimeframes() {
+			monitored.strategies = append(monitored.strategies, pairStrategy{timeframe: tf, factory: factory})
+		}
+	}
+	if err := monitored.Start(ctx, pair); err != nil {
+		log.Fatalf("Starting %s failed: %v", pair, err)
+	}
+	// The selected pairs run the same strategies, so they need the same timeframes.
+	if err := application.AddTimeframes(runner.Timeframes(pair)...); err != nil {
+		log.Fatalf("Starting %s failed: %v", pair, err)
+	}
+	application.AddRunner(runner)
+
+	// Every signal becomes an entry of the Trading Opportunities list, with the trends at that moment.
+	marketTrend := trend.NewMarketTrendService("BINANCE", trend.DefaultMarketConfig(), repo)
+	runner.SetTrends(trend.Source{Chart: chartTrend, Market: marketTrend})
+	opportunities := opportunity.NewService(opportunity.DefaultConfig(), repo, chartTrend, marketTrend)
+	if err := opportunities.Load(ctx); err != nil {
+		log.Fatalf("Loading trading opportunities failed: %v", err)
+	}
+	runner.AddSignalHandler(opportunities)
+	application.AddCandleHandler(opportunities)
+	if *httpAddr != "" {
+		go serveOpportunities(ctx, *httpAddr, opportunities)
+	}
+
+	// The entry maximum of an opportunity comes from the risk budget, the free balance, the order
+	// filters of the exchange and the liquidity in the order book of the monitored pair.
+	// Only the quote balance is known: a sell needs the base asset, so sells get an entry maximum of 0.
+	sizer := opportunity.NewSizer(sizerCfg, opportunity.FixedBalances{universeCfg.QuoteAsset: *balance})
+	if symbols, err := exchange.FetchBinanceSymbols(ctx, exchange.BinanceAPIURL); err != nil {
+		log.Printf("Entry maximum disabled, loading the exchange filters failed: %v", err)
+	} else {
+		sizer.SetSymbols(symbols)
+		opportunities.SetSizer(sizer)
+	}
+	// Every monitored pair, the selected ones included, gets the order book of its own depth stream.
+	depth := &depthFeeds{sizer: sizer}
+	if *saveBooks {
+		depth.repo = repo
+	}
+	depth.Start(ctx, pair)
+	monitored.SetDepth(depth)
+
+	// The market trend is measured over all pairs of the exchange, next to the monitored symbol.
+	// The same feed selects the extra pairs the strategies run on.
+	marketStreamer, err := exchange.NewBinanceMarketStreamer(ctx, exchange.BinanceMarketURL)
+	if err != nil {
+		log.Printf("Market trend disabled, all-market stream connection failed: %v", err)
+	} else {
+		var selector *universe.Selector
+		if *selectPairs {
+			selector = universe.NewSelector(universeCfg, monitored)
+		}
+		go func() {
+			if err := runMarket(ctx, marketStreamer, marketTrend, selector, monitored, application, pair); err != nil {
+				log.Printf("Market stream error: %v", err)
+			}
+		}()
+	}
+
 	// Run the application.
 	if err := application.Run(ctx, symbol); err != nil {
 		log.Fatalf("Application run failed: %v", err)
@@ -46,3 +160,122 @@ func main() {
 
 	log.Println("Application finished gracefully.")
 }
+
+// runMarket feeds the all-market tickers to the market trend and, when selector is not nil, to the
+// universe selection. The tickers of the selected pairs are handled like those of the monitored pair
+// once they are warmed up.
+func runMarket(ctx context.Context, streamer exchange.MarketStreamer, marketTrend *trend.MarketTrendService, selector *universe.Selector, monitored *pairs, application *app.Application, pair string) error {
+	batchChan, errChan := streamer.StreamMarket(ctx)
+	for {
+		select {
+		case batch, ok := <-batchChan:
+			if !ok {
+				return nil
+			}
+			if _, err := marketTrend.OnTickers(ctx, batch); err != nil {
+				log.Printf("Error updating market trend: %v", err)
+			}
+			if selector == nil {
+				continue
+			}
+			selector.OnTickers(ctx, batch)
+			for _, t := range batch {
+				// The monitored pair has a stream of its own.
+				if t.Symbol != pair && selector.Selected(t.Symbol) && monitored.Active(t.Symbol) {
+					application.HandleTicker(ctx, t)
+				}
+			}
+		case err := <-errChan:
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// serveOpportunities serves the Trading Opportunities list, where the user marks the opportunities
+// seen or acted on, until the context is done.
+func serveOpportunities(ctx context.Context, addr string, opportunities *opportunity.Service) {
+	server := &http.Server{Addr: addr, Handler: opportunity.NewHandler(opportunities)}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	log.Printf("Serving the trading opportunities on %s", addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("Opportunities server error: %v", err)
+	}
+}
+
+// depthFeeds keeps a depth stream per monitored pair, so the entry maximum of every pair the
+// strategies run on is limited by its liquidity.
+type depthFeeds struct {
+	mu    sync.Mutex
+	sizer *opportunity.Sizer
+	repo  storage.OrderBookHistory // Nil to not store the order books.
+	stops map[string]context.CancelFunc
+}
+
+// Start connects the depth stream of a pair, unless it has one.
+func (d *depthFeeds) Start(ctx context.Context, symbol string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.stops[symbol]; ok {
+		return
+	}
+	if d.stops == nil {
+		d.stops = make(map[string]context.CancelFunc)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	d.stops[symbol] = cancel
+	go func() {
+		streamer, err := exchange.NewBinanceDepthStreamer(ctx, exchange.BinanceDepthURL(symbol))
+		if err != nil {
+			log.Printf("Entry maximum of %s not limited by liquidity, depth stream connection failed: %v", symbol, err)
+			return
+		}
+		runDepth(ctx, streamer, d.sizer, d.repo, symbol)
+	}()
+}
+
+// Stop disconnects the depth stream of a pair.
+func (d *depthFeeds) Stop(symbol string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if stop, ok := d.stops[symbol]; ok {
+		stop()
+		delete(d.stops, symbol)
+	}
+}
+
+// bookSaveInterval is how often a snapshot of the order book is stored, for fills in backtests.
+const bookSaveInterval = time.Second
+
+// runDepth keeps the local order book of a pair up to date and, with a repository, stores
+// snapshots of it.
+func runDepth(ctx context.Context, streamer exchange.DepthStreamer, sizer *opportunity.Sizer, repo storage.OrderBookHistory, pair string) {
+	bookChan, errChan := streamer.StreamDepth(ctx, pair)
+	var saved time.Time
+	for {
+		select {
+		case book, ok := <-bookChan:
+			if !ok {
+				return
+			}
+			sizer.OnOrderBook(book)
+			if repo != nil && book.Time.Sub(saved) >= bookSaveInterval {
+				if err := repo.SaveOrderBook(ctx, book); err != nil {
+					log.Printf("Error saving order book: %v", err)
+				}
+				saved = book.Time
+			}
+		case err := <-errChan:
+			if err != nil {
+				log.Printf("Depth stream error: %v", err)
+			}
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
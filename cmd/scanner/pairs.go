@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/app"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/candle"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/trend"
+)
+
+// historyWindow is how much stored ticker history a pair is warmed up with, unless its strategies
+// need more.
+const historyWindow = 7 * 24 * time.Hour
+
+// pairStrategy is a strategy to run on a timeframe of every monitored pair.
+type pairStrategy struct {
+	timeframe domain.Timeframe
+	factory   strategy.Factory
+}
+
+// pairs starts and stops the chart trend and the strategies of the monitored pairs.
+// It is the universe.Membership of the scanner.
+type pairs struct {
+	mu         sync.Mutex
+	repo       storage.TickerHistory
+	chartTrend *trend.ChartTrendService
+	runner     *app.StrategyRunner
+	strategies []pairStrategy
+	fixed      string // The pair with a stream of its own, which is never removed.
+	active     map[string]bool
+	warming    map[string]pendingWarmUp // The pairs being warmed up in the background.
+	warmUps    uint64                   // The token of the last warm-up started.
+	depth      *depthFeeds              // Nil until the order books are streamed.
+}
+
+// pendingWarmUp is a warm-up running in the background. Its token tells it apart from a later
+// warm-up of the same pair.
+type pendingWarmUp struct {
+	token  uint64
+	wanted bool // False once the pair is removed during the warm-up.
+}
+
+// SetDepth streams the order books of the pairs that are started from now on.
+func (p *pairs) SetDepth(depth *depthFeeds) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.depth = depth
+}
+
+// Start starts a pair right away, e.g. the pair with a stream of its own before the stream starts.
+func (p *pairs) Start(ctx context.Context, symbol string) error {
+	if err := p.warmUp(ctx, symbol); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.activate(ctx, symbol)
+	return nil
+}
+
+// AddPair starts a pair in the background, so the all-market feed does not wait for the stored
+// history to load. The pair becomes Active when it is warmed up. A pair that is already monitored
+// is left as it is; a pair that is warming up, even one removed since, keeps its pending warm-up.
+func (p *pairs) AddPair(ctx context.Context, symbol string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.active[symbol] {
+		return nil
+	}
+	if w, ok := p.warming[symbol]; ok {
+		w.wanted = true
+		p.warming[symbol] = w
+		return nil
+	}
+	if p.warming == nil {
+		p.warming = make(map[string]pendingWarmUp)
+	}
+	p.warmUps++
+	token := p.warmUps
+	p.warming[symbol] = pendingWarmUp{token: token, wanted: true}
+	go func() {
+		err := p.warmUp(ctx, symbol)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		w, ok := p.warming[symbol]
+		if !ok || w.token != token {
+			return // Not the current warm-up of the pair; its strategies belong to that one.
+		}
+		delete(p.warming, symbol)
+		switch {
+		case err != nil:
+			log.Printf("Universe: could not start %s: %v", symbol, err)
+		case !w.wanted:
+			p.runner.Remove(symbol)
+		default:
+			p.activate(ctx, symbol)
+		}
+	}()
+	return nil
+}
+
+// activate marks a warmed-up pair as monitored and streams its order book. The caller must hold the lock.
+func (p *pairs) activate(ctx context.Context, symbol string) {
+	if p.active == nil {
+		p.active = make(map[string]bool)
+	}
+	p.active[symbol] = true
+	if p.depth != nil {
+		p.depth.Start(ctx, symbol)
+	}
+}
+
+// warmUp resumes the chart trend of a pair and starts its strategies, warmed up on the stored history.
+// The live tickers of the pair must not be handled before it is done.
+func (p *pairs) warmUp(ctx context.Context, symbol string) error {
+	window := historyWindow
+	for _, s := range p.strategies {
+		window = max(window, strategy.WarmUpPeriod(s.factory(), s.timeframe))
+	}
+	now := time.Now()
+	history, err := p.repo.ListTickers(ctx, symbol, now.Add(-window), now)
+	if err != nil {
+		return fmt.Errorf("could not load ticker history: %w", err)
+	}
+	for _, s := range p.strategies {
+		p.runner.Add(symbol, s.timeframe, s.factory)
+	}
+	// The live candles are those of the default timeframes and of the timeframes the strategies need.
+	timeframes := append(append([]domain.Timeframe{}, candle.DefaultTimeframes...), p.runner.Timeframes(symbol)...)
+	candles := candle.Build(history, timeframes...)
+	if err := p.chartTrend.Resume(ctx, symbol, candles); err != nil {
+		p.runner.Remove(symbol)
+		return fmt.Errorf("could not resume chart trend: %w", err)
+	}
+	log.Printf("Chart trend for %s: %s", symbol, p.chartTrend.Overall(symbol))
+
+	for _, tf := range p.runner.Timeframes(symbol) {
+		p.runner.Preload(symbol, tf, candle.OfTimeframe(candles, tf))
+	}
+	return nil
+}
+
+// RemovePair stops the strategies of a pair, or the warm-up of a pair that is not started yet.
+func (p *pairs) RemovePair(ctx context.Context, symbol string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if symbol == p.fixed {
+		return nil
+	}
+	if w, ok := p.warming[symbol]; ok {
+		w.wanted = false
+		p.warming[symbol] = w
+		return nil
+	}
+	if !p.active[symbol] {
+		return nil
+	}
+	p.runner.Remove(symbol)
+	delete(p.active, symbol)
+	if p.depth != nil {
+		p.depth.Stop(symbol)
+	}
+	return nil
+}
+
+// Active reports whether a pair is warmed up, so its live tickers can be handled.
+func (p *pairs) Active(symbol string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.active[symbol]
+}
+
+// boundFlag is an optional float flag; it stays nil unless set.
+type boundFlag struct {
+	value **float64
+}
+
+func (f boundFlag) String() string {
+	if f.value == nil || *f.value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(**f.value, 'f', -1, 64)
+}
+
+func (f boundFlag) Set(s string) error {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*f.value = &v
+	return nil
+}
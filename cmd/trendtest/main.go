@@ -0,0 +1,111 @@
+// Command trendtest recomputes the chart trend over a stored history range, or reads the market trend
+// the scanner stored for it, and writes the time series as CSV and as an SVG chart aligned with the price.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/candle"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/cli"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/report"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/trend"
+)
+
+func main() {
+	dbPath := flag.String("db", "ticks.db", "path of the SQLite database")
+	mode := flag.String("mode", "chart", "trend to test: chart or market")
+	symbol := flag.String("symbol", "BTCUSDT", "symbol for the chart trend and the price line")
+	exchangeName := flag.String("exchange", "BINANCE", "exchange of the stored market trend")
+	tfFlag := flag.String("timeframe", "1h", "timeframe to calculate the trend on")
+	fromFlag := flag.String("from", "", "start of the period (YYYY-MM-DD or RFC3339)")
+	toFlag := flag.String("to", "", "end of the period (YYYY-MM-DD or RFC3339), defaults to now")
+	csvPath := flag.String("csv", "trend.csv", "CSV output file")
+	svgPath := flag.String("svg", "trend.svg", "SVG output file")
+	flag.Parse()
+
+	tf, err := domain.ParseTimeframe(*tfFlag)
+	if err != nil {
+		log.Fatalf("Invalid timeframe: %v", err)
+	}
+	from, to, err := cli.ParsePeriod(*fromFlag, *toFlag)
+	if err != nil {
+		log.Fatalf("Invalid period: %v", err)
+	}
+
+	ctx := context.Background()
+	repo, err := storage.NewSqliteRepository(ctx, *dbPath)
+	if err != nil {
+		log.Fatalf("Database initialization failed: %v", err)
+	}
+	defer repo.Close()
+
+	sym := strings.ToUpper(*symbol)
+	tickers, err := repo.ListTickers(ctx, sym, from, to)
+	if err != nil {
+		log.Fatalf("Loading ticker history failed: %v", err)
+	}
+	candles := candle.Build(tickers, tf)
+
+	var points []report.TrendPoint
+	var title, valueName string
+	switch *mode {
+	case "chart":
+		title = fmt.Sprintf("Chart trend %s %s", sym, tf)
+		valueName = "strength"
+		for _, t := range trend.ChartHistory(trend.DefaultChartConfig(), candles) {
+			points = append(points, report.TrendPoint{Time: t.CandleTime, Trend: t.Trend, Value: t.Strength})
+		}
+	case "market":
+		// The breadth is measured over every pair of the exchange, which only the scanner sees; the
+		// ticks table holds the monitored pairs only.
+		exchange := strings.ToUpper(*exchangeName)
+		trends, err := repo.ListMarketTrends(ctx, exchange, tf, from, to)
+		if err != nil {
+			log.Fatalf("Loading market trend failed: %v", err)
+		}
+		if len(trends) == 0 {
+			log.Printf("No stored %s market trend on %s; the scanner stores it on %v", exchange, tf, trend.DefaultMarketConfig().Timeframes)
+		}
+		title = fmt.Sprintf("Market trend %s %s", exchange, tf)
+		valueName = "breadth"
+		for _, m := range trends {
+			points = append(points, report.TrendPoint{Time: m.Time, Trend: m.Trend, Value: m.Breadth})
+		}
+	default:
+		log.Fatalf("Unknown mode %q, expected chart or market", *mode)
+	}
+	alignPrices(points, candles)
+	log.Printf("%d trend points, prices from %d tickers", len(points), len(tickers))
+
+	if err := cli.WriteFile(*csvPath, func(f *os.File) error { return report.WriteTrendCSV(f, valueName, points) }); err != nil {
+		log.Fatalf("Writing CSV failed: %v", err)
+	}
+	chart := report.TrendChart(title, valueName, points)
+	if err := cli.WriteFile(*svgPath, func(f *os.File) error { return chart.WriteSVG(f) }); err != nil {
+		log.Fatalf("Writing SVG failed: %v", err)
+	}
+	log.Printf("Trend written to %s and %s", *csvPath, *svgPath)
+}
+
+// alignPrices sets the price of each point to the close of the candle that closed at the point's time.
+func alignPrices(points []report.TrendPoint, candles []domain.Candle) {
+	closes := make(map[int64]float64, len(candles))
+	for _, c := range candles {
+		closes[c.CloseTime.UnixMilli()] = c.Close
+	}
+	for i := range points {
+		price, ok := closes[points[i].Time.UnixMilli()]
+		if !ok {
+			price = math.NaN()
+		}
+		points[i].Price = price
+	}
+}
@@ -2,24 +2,94 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sort"
+	"sync"
 
+	"github.com/dorpsen/cryptotradingbot-starter/internal/candle"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
 	"github.com/dorpsen/cryptotradingbot-starter/internal/exchange"
 	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
 )
 
+// CandleHandler is notified of every candle the application closes from the live stream.
+type CandleHandler interface {
+	OnCandle(ctx context.Context, c domain.Candle) error
+}
+
 // Application holds the core components and orchestrates the application's logic.
 type Application struct {
-	streamer exchange.Streamer
-	repo     storage.Repository
+	mu             sync.Mutex // Serializes the tickers of the live stream and of HandleTicker.
+	streamer       exchange.Streamer
+	repo           storage.Repository
+	aggregator     *candle.Aggregator
+	feed           *candle.Feed
+	transforms     []candle.Transform
+	bars           map[domain.Timeframe]bool // The non-time bars the feed or the transforms build.
+	candleHandlers []CandleHandler
+	tickerHandlers []TickerHandler
 }
 
-// New creates a new Application.
+// New creates a new Application that aggregates the candles of the default timeframes. Strategies
+// on other timeframes need AddTimeframes.
 func New(streamer exchange.Streamer, repo storage.Repository) *Application {
+	aggregator := candle.NewAggregator(candle.DefaultTimeframes...)
 	return &Application{
-		streamer: streamer,
-		repo:     repo,
+		streamer:   streamer,
+		repo:       repo,
+		aggregator: aggregator,
+		feed:       candle.NewFeed(aggregator),
+		bars:       make(map[domain.Timeframe]bool),
+	}
+}
+
+// AddTimeframes makes the application build the candles of timeframes, e.g. the timeframes the
+// strategies of a StrategyRunner run and confirm on, so none of them waits for candles that never come.
+// Non-time bars get their builder, or their transform and its base timeframe; a timeframe the
+// application already builds is left as it is.
+func (a *Application) AddTimeframes(tfs ...domain.Timeframe) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, tf := range tfs {
+		if tf.Duration() > 0 {
+			a.aggregator.AddTimeframe(tf)
+			continue
+		}
+		if a.bars[tf] {
+			continue
+		}
+		builder, transform, err := candle.NewBars(tf)
+		if err != nil {
+			return fmt.Errorf("could not build timeframe %s: %w", tf, err)
+		}
+		if builder != nil {
+			a.feed.AddBuilder(builder)
+		} else {
+			a.aggregator.AddTimeframe(transform.Base())
+			a.transforms = append(a.transforms, transform)
+		}
+		a.bars[tf] = true
 	}
+	return nil
+}
+
+// AddCandleHandler registers a handler for the candles closed from the live stream.
+// Handlers must be registered before Run is called.
+func (a *Application) AddCandleHandler(h CandleHandler) {
+	a.candleHandlers = append(a.candleHandlers, h)
+}
+
+// AddTickerHandler registers a handler for the live tickers.
+// Handlers must be registered before Run is called.
+func (a *Application) AddTickerHandler(h TickerHandler) {
+	a.tickerHandlers = append(a.tickerHandlers, h)
+}
+
+// AddRunner registers a strategy runner for both the closed candles and the live tickers.
+func (a *Application) AddRunner(r *StrategyRunner) {
+	a.AddCandleHandler(r)
+	a.AddTickerHandler(r)
 }
 
 // Run starts the main application loop.
@@ -37,10 +107,7 @@ func (a *Application) Run(ctx context.Context, symbol string) error {
 				return nil
 			}
 			log.Printf("Symbol: %s, Price: %s", ticker.Symbol, ticker.LastPrice.Float.Text('f', 2))
-
-			if err := a.repo.SaveTicker(ctx, ticker); err != nil {
-				log.Printf("Error saving ticker: %v", err)
-			}
+			a.HandleTicker(ctx, ticker)
 		case err := <-errChan:
 			log.Printf("Stream error: %v", err)
 			return err
@@ -50,3 +117,48 @@ func (a *Application) Run(ctx context.Context, symbol string) error {
 		}
 	}
 }
+
+// HandleTicker saves a ticker, closes the candles it completes and passes both to the handlers.
+// Run calls it for the live stream; it can also be called for tickers of other pairs, e.g. the
+// pairs selected from the all-market feed, and is safe to call concurrently with Run.
+func (a *Application) HandleTicker(ctx context.Context, ticker domain.Ticker) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.repo.SaveTicker(ctx, ticker); err != nil {
+		log.Printf("Error saving ticker: %v", err)
+	}
+	a.dispatchCandles(ctx, a.feed.AddTicker(ticker))
+	for _, h := range a.tickerHandlers {
+		if err := h.OnTicker(ctx, ticker); err != nil {
+			log.Printf("Error handling ticker: %v", err)
+		}
+	}
+}
+
+// dispatchCandles hands the closed candles, and the bars transformed from them, to every registered handler.
+// Candles closed by the same ticker are handled from the longest timeframe down, so a strategy
+// confirming on a higher timeframe sees the higher candle that closed at the same moment.
+func (a *Application) dispatchCandles(ctx context.Context, candles []domain.Candle) {
+	sort.SliceStable(candles, func(i, j int) bool {
+		return candles[i].Timeframe.Duration() > candles[j].Timeframe.Duration()
+	})
+	for _, c := range candles {
+		a.handleCandle(ctx, c)
+		for _, t := range a.transforms {
+			if t.Base() != c.Timeframe {
+				continue
+			}
+			for _, derived := range t.Apply(c) {
+				a.handleCandle(ctx, derived)
+			}
+		}
+	}
+}
+
+func (a *Application) handleCandle(ctx context.Context, c domain.Candle) {
+	for _, h := range a.candleHandlers {
+		if err := h.OnCandle(ctx, c); err != nil {
+			log.Printf("Error handling %s %s candle: %v", c.Symbol, c.Timeframe, err)
+		}
+	}
+}
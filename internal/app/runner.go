@@ -0,0 +1,324 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/indicator"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+)
+
+// TickerHandler is notified of every live ticker the application receives.
+type TickerHandler interface {
+	OnTicker(ctx context.Context, ticker domain.Ticker) error
+}
+
+// SignalHandler is notified of every signal emitted by the strategies.
+type SignalHandler interface {
+	OnSignal(ctx context.Context, signal domain.Signal) error
+}
+
+// minHistory is the minimum number of closed candles the runner keeps per pair and timeframe.
+const minHistory = 300
+
+type runnerKey struct {
+	symbol    string
+	timeframe domain.Timeframe
+}
+
+// strategyRun is one strategy instance bound to a pair and timeframe.
+type strategyRun struct {
+	strategy strategy.Strategy
+	higher   map[domain.Timeframe]int // Candles needed per higher timeframe, for MultiTimeframe strategies.
+	gate     *signalGate
+}
+
+// StrategyRunner drives strategies per pair and timeframe from live candles and tickers.
+// Signals are filtered by the trigger rules, so a condition that keeps holding does not signal
+// over and over. Every emitted signal gets a metrics snapshot, is saved and is passed to the
+// signal handlers.
+type StrategyRunner struct {
+	mu             sync.Mutex
+	exchange       string
+	repo           storage.SignalRepository
+	runs           map[runnerKey][]*strategyRun
+	history        map[runnerKey][]domain.Candle
+	historySize    map[runnerKey]int
+	tickers        map[string]*domain.Ticker
+	signalHandlers []SignalHandler
+	metrics        indicator.MetricsConfig
+	trigger        strategy.Trigger
+	trends         strategy.Trends
+}
+
+// NewStrategyRunner creates a StrategyRunner for the data of an exchange.
+// repo may be nil when signals should not be persisted.
+func NewStrategyRunner(exchangeName string, repo storage.SignalRepository) *StrategyRunner {
+	return &StrategyRunner{
+		exchange:    exchangeName,
+		repo:        repo,
+		runs:        make(map[runnerKey][]*strategyRun),
+		history:     make(map[runnerKey][]domain.Candle),
+		historySize: make(map[runnerKey]int),
+		tickers:     make(map[string]*domain.Ticker),
+		metrics:     indicator.DefaultMetricsConfig(),
+		trigger:     strategy.DefaultTrigger(),
+	}
+}
+
+// SetTrigger sets the trigger rules of the strategies that do not implement strategy.Triggered.
+func (r *StrategyRunner) SetTrigger(t strategy.Trigger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trigger = t
+}
+
+// SetTrends sets the trends the strategies confirm their entries on.
+func (r *StrategyRunner) SetTrends(t strategy.Trends) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trends = t
+}
+
+// AddSignalHandler registers a handler for the emitted signals.
+func (r *StrategyRunner) AddSignalHandler(h SignalHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.signalHandlers = append(r.signalHandlers, h)
+}
+
+// Add runs a new instance of a strategy on a pair and timeframe.
+func (r *StrategyRunner) Add(symbol string, tf domain.Timeframe, newStrategy strategy.Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := runnerKey{symbol: symbol, timeframe: tf}
+	s := newStrategy()
+	run := &strategyRun{strategy: s, gate: newSignalGate()}
+	r.runs[key] = append(r.runs[key], run)
+	r.growHistory(key, s.WarmUp()+minHistory)
+	if mtf, ok := s.(strategy.MultiTimeframe); ok {
+		run.higher = mtf.HigherTimeframes()
+		for htf, n := range run.higher {
+			r.growHistory(runnerKey{symbol: symbol, timeframe: htf}, n+minHistory)
+		}
+	}
+	log.Printf("Running strategy %q on %s %s", s.Name(), symbol, tf)
+}
+
+// growHistory raises the history size of a key. The caller must hold the lock.
+func (r *StrategyRunner) growHistory(key runnerKey, size int) {
+	if size > r.historySize[key] {
+		r.historySize[key] = size
+	}
+}
+
+// Remove stops all strategies running on a pair and drops its history.
+func (r *StrategyRunner) Remove(symbol string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key := range r.historySize {
+		if key.symbol == symbol {
+			delete(r.runs, key)
+			delete(r.history, key)
+			delete(r.historySize, key)
+		}
+	}
+	delete(r.tickers, symbol)
+}
+
+// Symbols returns the pairs with at least one running strategy.
+func (r *StrategyRunner) Symbols() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[string]bool)
+	var symbols []string
+	for key := range r.runs {
+		if !seen[key.symbol] {
+			seen[key.symbol] = true
+			symbols = append(symbols, key.symbol)
+		}
+	}
+	return symbols
+}
+
+// Timeframes returns the timeframes the runner keeps candles of for a pair, including the higher
+// timeframes its strategies confirm on, ordered from short to long.
+func (r *StrategyRunner) Timeframes(symbol string) []domain.Timeframe {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var tfs []domain.Timeframe
+	for key := range r.historySize {
+		if key.symbol == symbol {
+			tfs = append(tfs, key.timeframe)
+		}
+	}
+	sort.Slice(tfs, func(i, j int) bool { return tfs[i].Duration() < tfs[j].Duration() })
+	return tfs
+}
+
+// Preload seeds the candle history of a pair and timeframe, so strategies are warmed up
+// before the first live candle closes. Candles must be ordered by close time.
+func (r *StrategyRunner) Preload(symbol string, tf domain.Timeframe, candles []domain.Candle) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := runnerKey{symbol: symbol, timeframe: tf}
+	r.history[key] = r.trim(key, append(r.history[key], candles...))
+}
+
+// OnCandle appends a closed candle to the history and calls the strategies of its pair and timeframe.
+// Candles of a timeframe that is only used to confirm signals on a lower one are kept as well.
+func (r *StrategyRunner) OnCandle(ctx context.Context, c domain.Candle) error {
+	r.mu.Lock()
+	key := runnerKey{symbol: c.Symbol, timeframe: c.Timeframe}
+	if r.historySize[key] == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	r.history[key] = r.trim(key, append(r.history[key], c))
+	type call struct {
+		run *strategyRun
+		in  strategy.Input
+	}
+	var calls []call
+	for _, run := range r.runs[key] {
+		if in := r.input(key, run); len(in.Candles) >= run.strategy.WarmUp() {
+			calls = append(calls, call{run: run, in: in})
+		}
+	}
+	r.mu.Unlock()
+
+	var signals []domain.Signal
+	for _, c := range calls {
+		fired := r.filter(c.run, candleSource, c.run.strategy.OnCandle(c.in))
+		signals = append(signals, r.complete(c.run.strategy, c.in, fired)...)
+	}
+	return r.emit(ctx, signals)
+}
+
+// OnTicker records the latest ticker of a pair and calls its strategies on every timeframe.
+func (r *StrategyRunner) OnTicker(ctx context.Context, ticker domain.Ticker) error {
+	r.mu.Lock()
+	t := ticker
+	r.tickers[ticker.Symbol] = &t
+	type call struct {
+		run *strategyRun
+		in  strategy.Input
+	}
+	var calls []call
+	for key, runs := range r.runs {
+		if key.symbol != ticker.Symbol {
+			continue
+		}
+		for _, run := range runs {
+			if in := r.input(key, run); len(in.Candles) >= run.strategy.WarmUp() {
+				calls = append(calls, call{run: run, in: in})
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	var signals []domain.Signal
+	for _, c := range calls {
+		fired := r.filter(c.run, tickerSource, c.run.strategy.OnTicker(c.in, ticker))
+		signals = append(signals, r.complete(c.run.strategy, c.in, fired)...)
+	}
+	return r.emit(ctx, signals)
+}
+
+// input builds the strategy input of a run on a pair and timeframe, with its higher timeframes
+// aligned to the last closed candle. The caller must hold the lock.
+func (r *StrategyRunner) input(key runnerKey, run *strategyRun) strategy.Input {
+	candles := r.history[key]
+	in := strategy.Input{
+		Exchange:  r.exchange,
+		Symbol:    key.symbol,
+		Timeframe: key.timeframe,
+		Candles:   candles[:len(candles):len(candles)], // Appends by a strategy must not touch the history.
+		Ticker:    r.tickers[key.symbol],
+		Trends:    r.trends,
+	}
+	if len(run.higher) > 0 && len(candles) > 0 {
+		in.Higher = make(map[domain.Timeframe][]domain.Candle, len(run.higher))
+		for htf := range run.higher {
+			higher := r.history[runnerKey{symbol: key.symbol, timeframe: htf}]
+			in.Higher[htf] = strategy.ClosedBy(higher, in.Last().CloseTime)
+		}
+	}
+	return in
+}
+
+// filter applies the trigger rules of a run to the signals of one evaluation.
+func (r *StrategyRunner) filter(run *strategyRun, source triggerSource, signals []domain.Signal) []domain.Signal {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.trigger
+	if own, ok := run.strategy.(strategy.Triggered); ok {
+		t = own.Trigger()
+	}
+	return run.gate.filter(t, source, signals)
+}
+
+// trim drops the oldest candles beyond the history size of a key. The caller must hold the lock.
+func (r *StrategyRunner) trim(key runnerKey, candles []domain.Candle) []domain.Candle {
+	size := r.historySize[key]
+	if size == 0 {
+		size = minHistory
+	}
+	if len(candles) > size {
+		candles = append([]domain.Candle{}, candles[len(candles)-size:]...)
+	}
+	return candles
+}
+
+// complete fills in the fields a strategy left empty, including the metrics snapshot.
+func (r *StrategyRunner) complete(s strategy.Strategy, in strategy.Input, signals []domain.Signal) []domain.Signal {
+	for i := range signals {
+		sig := &signals[i]
+		if sig.Strategy == "" {
+			sig.Strategy = s.Name()
+		}
+		if sig.Exchange == "" {
+			sig.Exchange = in.Exchange
+		}
+		if sig.Symbol == "" {
+			sig.Symbol = in.Symbol
+		}
+		if sig.Timeframe == "" {
+			sig.Timeframe = in.Timeframe
+		}
+		if sig.Metrics.CandleTime.IsZero() && len(in.Candles) > 0 {
+			sig.Metrics = indicator.CaptureMetrics(in.Candles, in.Ticker, r.metrics)
+		}
+	}
+	return signals
+}
+
+// emit saves the signals and passes them to the signal handlers.
+func (r *StrategyRunner) emit(ctx context.Context, signals []domain.Signal) error {
+	r.mu.Lock()
+	handlers := append([]SignalHandler{}, r.signalHandlers...)
+	r.mu.Unlock()
+
+	var firstErr error
+	for _, sig := range signals {
+		log.Printf("Signal: %s %s %s %s at %v", sig.Strategy, sig.Symbol, sig.Timeframe, sig.Direction, sig.Price)
+		if r.repo != nil {
+			id, err := r.repo.SaveSignal(ctx, sig)
+			if err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("could not save signal: %w", err)
+			}
+			sig.ID = id
+		}
+		for _, h := range handlers {
+			if err := h.OnSignal(ctx, sig); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
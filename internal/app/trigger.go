@@ -0,0 +1,94 @@
+package app
+
+import (
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+)
+
+// triggerSource separates the evaluations on closed candles from those on tickers, so the tickers
+// between two candles do not count as evaluations without a signal for a candle-based strategy.
+type triggerSource int
+
+const (
+	candleSource triggerSource = iota
+	tickerSource
+)
+
+type triggerKey struct {
+	source    triggerSource
+	direction domain.Direction
+}
+
+// triggerState is the arming state of one direction of a strategy run.
+type triggerState struct {
+	disarmed bool
+	quiet    int // Evaluations without a signal since the last one.
+}
+
+// signalGate applies the trigger rules to the signals of one strategy run.
+type signalGate struct {
+	states map[triggerKey]*triggerState
+	last   map[domain.Direction]time.Time // Time of the last signal passed per direction.
+}
+
+func newSignalGate() *signalGate {
+	return &signalGate{states: make(map[triggerKey]*triggerState), last: make(map[domain.Direction]time.Time)}
+}
+
+func (g *signalGate) state(key triggerKey) *triggerState {
+	st, ok := g.states[key]
+	if !ok {
+		st = &triggerState{}
+		g.states[key] = st
+	}
+	return st
+}
+
+// filter is called with the signals of every evaluation, also when there are none, and returns
+// the signals that pass the rules. A signal that is not newer than the last one passed in its
+// direction is a duplicate and never passes.
+func (g *signalGate) filter(t strategy.Trigger, source triggerSource, signals []domain.Signal) []domain.Signal {
+	fired := make(map[domain.Direction]bool)
+	for _, sig := range signals {
+		fired[sig.Direction] = true
+	}
+	for _, dir := range []domain.Direction{domain.Buy, domain.Sell} {
+		if st := g.state(triggerKey{source: source, direction: dir}); !fired[dir] {
+			st.quiet++
+			if st.quiet >= t.ReArmAfter {
+				st.disarmed = false
+			}
+		}
+	}
+
+	var passed []domain.Signal
+	for _, sig := range signals {
+		st := g.state(triggerKey{source: source, direction: sig.Direction})
+		st.quiet = 0
+		last, seen := g.last[sig.Direction]
+		switch {
+		case seen && !sig.Time.After(last):
+			continue
+		case t.Mode != strategy.LevelTriggered && st.disarmed:
+			continue
+		case seen && t.Cooldown > 0 && sig.Time.Sub(last) < t.Cooldown:
+			continue
+		}
+		st.disarmed = true
+		g.last[sig.Direction] = sig.Time
+		if other := g.states[triggerKey{source: source, direction: opposite(sig.Direction)}]; other != nil {
+			other.disarmed = false
+		}
+		passed = append(passed, sig)
+	}
+	return passed
+}
+
+func opposite(d domain.Direction) domain.Direction {
+	if d == domain.Buy {
+		return domain.Sell
+	}
+	return domain.Buy
+}
@@ -0,0 +1,260 @@
+// Package audit checks the quality of the stored ticker history of a pair: gaps, duplicate and
+// out-of-order tickers, streaks of candles without volume, and outlier prices.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/candle"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+)
+
+// Kind is the kind of an issue found in the history.
+type Kind string
+
+const (
+	// Gap is a period without tickers, e.g. while the scanner was down.
+	Gap Kind = "gap"
+	// Duplicate is a ticker with the event time of the previous one, or that repeats its 24h
+	// statistics: the same update stored twice.
+	Duplicate Kind = "duplicate"
+	// OutOfOrder is a ticker stored after a ticker with a later event time.
+	OutOfOrder Kind = "out-of-order"
+	// ZeroVolume is a streak of candles without traded volume.
+	ZeroVolume Kind = "zero-volume"
+	// Outlier is a price far from the median of the prices before it.
+	Outlier Kind = "outlier"
+)
+
+// Kinds are the kinds of issues, in the order they are summarized.
+var Kinds = []Kind{Gap, Duplicate, OutOfOrder, ZeroVolume, Outlier}
+
+// Config holds the thresholds of an audit. A zero threshold disables its check.
+type Config struct {
+	// MaxGap is the longest time without a ticker that is not a gap. The all-market stream only
+	// sends the pairs that changed, so quiet pairs can go a while without one.
+	MaxGap time.Duration
+	// Timeframe is the timeframe of the candles checked for volume.
+	Timeframe domain.Timeframe
+	// MaxZeroVolume is the most consecutive candles without volume that are not reported.
+	MaxZeroVolume int
+	// OutlierPercent is how far, in percent, a price may be from the median of the OutlierWindow
+	// prices before it.
+	OutlierPercent float64
+	OutlierWindow  int
+}
+
+// DefaultConfig returns the thresholds the backtester audits its history with.
+func DefaultConfig() Config {
+	return Config{
+		MaxGap:         5 * time.Minute,
+		Timeframe:      domain.Minute1,
+		MaxZeroVolume:  5,
+		OutlierPercent: 10,
+		OutlierWindow:  20,
+	}
+}
+
+// Issue is a problem found in the history: a single ticker, or the period of a gap or a streak.
+type Issue struct {
+	Kind     Kind
+	From, To time.Time
+	Detail   string
+}
+
+// Report is the result of the audit of the history of a pair over a period.
+type Report struct {
+	Symbol   string
+	From, To time.Time
+	Tickers  int
+	Candles  int           // The candles of the Timeframe of the audit built from the tickers.
+	Missing  time.Duration // The total time of the gaps.
+	Issues   []Issue       // Ordered by time.
+}
+
+// Count returns the number of issues of a kind.
+func (r *Report) Count(kind Kind) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// OK reports whether the audit found no issues.
+func (r *Report) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Summary returns the number of issues of every kind found, e.g. "gap: 2 (3h0m0s missing), outlier: 1".
+func (r *Report) Summary() string {
+	var parts []string
+	for _, kind := range Kinds {
+		n := r.Count(kind)
+		switch {
+		case n == 0:
+			continue
+		case kind == Gap:
+			parts = append(parts, fmt.Sprintf("%s: %d (%s missing)", kind, n, r.Missing))
+		default:
+			parts = append(parts, fmt.Sprintf("%s: %d", kind, n))
+		}
+	}
+	if len(parts) == 0 {
+		return "no issues"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (r *Report) add(kind Kind, from, to time.Time, detail string) {
+	r.Issues = append(r.Issues, Issue{Kind: kind, From: from, To: to, Detail: detail})
+}
+
+// Run audits the tickers of a symbol stored with an event time in [from, to).
+func Run(ctx context.Context, history storage.StoredTickerHistory, symbol string, from, to time.Time, cfg Config) (*Report, error) {
+	tickers, err := history.ListTickersAsStored(ctx, symbol, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("could not load ticker history: %w", err)
+	}
+	return Check(symbol, from, to, tickers, cfg), nil
+}
+
+// Check audits the tickers of a symbol over [from, to), in the order they were stored.
+func Check(symbol string, from, to time.Time, tickers []domain.Ticker, cfg Config) *Report {
+	r := &Report{Symbol: symbol, From: from, To: to, Tickers: len(tickers)}
+	var latest int64
+	for i, t := range tickers {
+		if i > 0 && t.EventTime < latest {
+			r.add(OutOfOrder, eventTime(t), eventTime(t), fmt.Sprintf("stored after a ticker of %s", formatTime(time.UnixMilli(latest))))
+		}
+		latest = max(latest, t.EventTime)
+	}
+
+	sorted := append([]domain.Ticker(nil), tickers...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].EventTime < sorted[j].EventTime })
+	var unique []domain.Ticker
+	for _, t := range sorted {
+		if n := len(unique); n > 0 {
+			if detail, ok := repeats(unique[n-1], t); ok {
+				r.add(Duplicate, eventTime(t), eventTime(t), detail)
+				continue
+			}
+		}
+		unique = append(unique, t)
+	}
+
+	if cfg.MaxGap > 0 {
+		r.checkGaps(unique, cfg.MaxGap)
+	}
+	if cfg.Timeframe != "" {
+		r.checkVolume(unique, cfg)
+	}
+	if cfg.OutlierPercent > 0 && cfg.OutlierWindow > 0 {
+		r.checkOutliers(unique, cfg)
+	}
+	sort.SliceStable(r.Issues, func(i, j int) bool { return r.Issues[i].From.Before(r.Issues[j].From) })
+	return r
+}
+
+// repeats reports whether a ticker repeats the previous one, and how.
+func repeats(prev, t domain.Ticker) (string, bool) {
+	if t.EventTime == prev.EventTime {
+		return "same event time as the previous ticker", true
+	}
+	if t.CloseTime != 0 && t.CloseTime == prev.CloseTime && t.Count == prev.Count &&
+		t.LastPrice.Float64Value() == prev.LastPrice.Float64Value() && t.Volume.Float64Value() == prev.Volume.Float64Value() {
+		return fmt.Sprintf("same 24h statistics as the ticker of %s", formatTime(eventTime(prev))), true
+	}
+	return "", false
+}
+
+// checkGaps reports the periods longer than the maximum gap without tickers, including those at the
+// start and the end of the audited period. The end is capped at the current time.
+func (r *Report) checkGaps(tickers []domain.Ticker, maxGap time.Duration) {
+	end := r.To
+	if now := time.Now(); end.After(now) {
+		end = now
+	}
+	gap := func(from, to time.Time) {
+		if d := to.Sub(from); d > maxGap {
+			r.Missing += d
+			r.add(Gap, from, to, fmt.Sprintf("no tickers for %s", d))
+		}
+	}
+	prev := r.From
+	for _, t := range tickers {
+		gap(prev, eventTime(t))
+		prev = eventTime(t)
+	}
+	gap(prev, end)
+}
+
+// checkVolume reports the streaks of more consecutive candles without volume than allowed.
+func (r *Report) checkVolume(tickers []domain.Ticker, cfg Config) {
+	candles := candle.Build(tickers, cfg.Timeframe)
+	r.Candles = len(candles)
+	if cfg.MaxZeroVolume <= 0 {
+		return
+	}
+	var streak []domain.Candle
+	flush := func() {
+		if len(streak) > cfg.MaxZeroVolume {
+			first, last := streak[0], streak[len(streak)-1]
+			r.add(ZeroVolume, first.OpenTime, last.CloseTime, fmt.Sprintf("%d %s candles without volume", len(streak), cfg.Timeframe))
+		}
+		streak = nil
+	}
+	for _, c := range candles {
+		if c.Volume > 0 {
+			flush()
+			continue
+		}
+		streak = append(streak, c)
+	}
+	flush()
+}
+
+// checkOutliers reports the prices that are not positive, or further from the median of the prices
+// before them than allowed. A real move stops being reported once it holds for half the window.
+func (r *Report) checkOutliers(tickers []domain.Ticker, cfg Config) {
+	window := make([]float64, 0, cfg.OutlierWindow)
+	sorted := make([]float64, cfg.OutlierWindow)
+	for _, t := range tickers {
+		price := t.LastPrice.Float64Value()
+		switch {
+		case price <= 0:
+			r.add(Outlier, eventTime(t), eventTime(t), fmt.Sprintf("price %g", price))
+		case len(window) == cfg.OutlierWindow:
+			copy(sorted, window)
+			sort.Float64s(sorted)
+			median := sorted[len(sorted)/2]
+			if len(sorted)%2 == 0 {
+				median = (sorted[len(sorted)/2-1] + median) / 2
+			}
+			if deviation := (price/median - 1) * 100; math.Abs(deviation) > cfg.OutlierPercent {
+				r.add(Outlier, eventTime(t), eventTime(t), fmt.Sprintf("price %g is %+.1f%% from the median %g", price, deviation, median))
+			}
+		}
+		if len(window) == cfg.OutlierWindow {
+			copy(window, window[1:])
+			window = window[:len(window)-1]
+		}
+		window = append(window, price)
+	}
+}
+
+func eventTime(t domain.Ticker) time.Time {
+	return time.UnixMilli(t.EventTime).UTC()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
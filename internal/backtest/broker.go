@@ -0,0 +1,241 @@
+package backtest
+
+import (
+	"math"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// position is an open simulated position.
+type position struct {
+	trade      Trade
+	stopLoss   float64 // Zero when the trade plan has none.
+	takeProfit float64
+}
+
+// value returns the unrealized profit or loss of the position at a price, before fees.
+func (p *position) value(price float64) float64 {
+	diff := price - p.trade.EntryPrice
+	if p.trade.Direction == domain.Sell {
+		diff = -diff
+	}
+	return diff * p.trade.Quantity
+}
+
+// exitAt returns the reason the plan of the position closes it at a price, if it does.
+func (p *position) exitAt(price float64) (ExitReason, bool) {
+	long := p.trade.Direction != domain.Sell
+	switch {
+	case p.stopLoss > 0 && ((long && price <= p.stopLoss) || (!long && price >= p.stopLoss)):
+		return ExitStopLoss, true
+	case p.takeProfit > 0 && ((long && price >= p.takeProfit) || (!long && price <= p.takeProfit)):
+		return ExitTakeProfit, true
+	}
+	return "", false
+}
+
+// pendingOrder is the order of a signal waiting for the latency to pass.
+type pendingOrder struct {
+	signal domain.Signal
+	due    time.Time
+}
+
+// broker is the simulated account. Signals become market orders that fill, through the fill model,
+// on the first tick of their pair after the signal once the latency has passed, never at a price
+// known when the signal fired. It holds at most one position per pair.
+type broker struct {
+	cfg       Config
+	portfolio PortfolioConfig // The zero value for a backtest of a single pair.
+	fills     FillModel
+	cash      float64
+	positions map[string]*position
+	last      map[string]float64 // The last price of every pair.
+	pending   []pendingOrder
+	trades    []Trade
+	rejected  []domain.Signal
+}
+
+func newBroker(cfg Config) *broker {
+	fills := cfg.Fill
+	if fills == nil {
+		fills = IdealFill{}
+	}
+	return &broker{cfg: cfg, fills: fills, cash: cfg.InitialBalance, positions: map[string]*position{}, last: map[string]float64{}}
+}
+
+// equity returns the cash plus the value of the open positions at the last prices.
+func (b *broker) equity() float64 {
+	equity := b.cash
+	for symbol, p := range b.positions {
+		equity += p.value(b.last[symbol])
+	}
+	return equity
+}
+
+// free returns the cash not held by the open positions.
+func (b *broker) free() float64 {
+	free := b.cash
+	for _, p := range b.positions {
+		free -= p.trade.EntryPrice * p.trade.Quantity
+	}
+	return math.Max(free, 0)
+}
+
+// mark records the last price of a pair.
+func (b *broker) mark(symbol string, price float64) {
+	b.last[symbol] = price
+}
+
+// submit queues the order of a signal fired at a time. In a portfolio, entries wait for the signal
+// window after the close of their candle, so the signals of that candle on other pairs are known.
+func (b *broker) submit(sig domain.Signal, at time.Time) {
+	due := at.Add(b.cfg.Latency)
+	if window := sig.Time.Add(b.portfolio.SignalWindow); window.After(due) {
+		if _, open := b.positions[sig.Symbol]; !open {
+			due = window
+		}
+	}
+	b.pending = append(b.pending, pendingOrder{signal: sig, due: due})
+}
+
+// onTick fills the pending orders of the pair of the tick that are due, then tracks its open
+// position and closes it when the price reaches its stop-loss or take-profit.
+func (b *broker) onTick(m Market) {
+	b.mark(m.Symbol, m.Price)
+	var waiting, due []pendingOrder
+	for _, o := range b.pending {
+		if o.signal.Symbol != m.Symbol || m.Time.Before(o.due) {
+			waiting = append(waiting, o)
+			continue
+		}
+		due = append(due, o)
+	}
+	b.pending = waiting
+	for _, o := range due {
+		b.execute(o.signal, m)
+	}
+
+	p := b.positions[m.Symbol]
+	if p == nil {
+		return
+	}
+	v := p.value(m.Price)
+	p.trade.RunUp = math.Max(p.trade.RunUp, v)
+	p.trade.Drawdown = math.Max(p.trade.Drawdown, -v)
+	if reason, ok := p.exitAt(m.Price); ok {
+		b.close(m, reason)
+	}
+}
+
+// execute fills the order of a signal: it closes a position in the opposite direction, or opens one
+// when there is none. A signal in the direction of the open position is ignored.
+func (b *broker) execute(sig domain.Signal, m Market) {
+	if p := b.positions[sig.Symbol]; p != nil {
+		if p.trade.Direction != sig.Direction {
+			b.close(m, ExitSignal)
+		}
+		return
+	}
+	if sig.Direction == domain.Sell && !b.cfg.AllowShort {
+		return
+	}
+	if !b.admit(sig) {
+		b.rejected = append(b.rejected, sig)
+		return
+	}
+
+	plan := domain.TradePlan{Entry: m.Price, Size: b.cfg.Size}
+	if sig.Plan != nil {
+		plan.StopLoss, plan.TakeProfit = sig.Plan.StopLoss, sig.Plan.TakeProfit
+		if plan.Size.Kind == "" {
+			plan.Size = sig.Plan.Size
+		}
+	}
+	if plan.Size.Kind == "" {
+		plan.Size = domain.SizeRule{Kind: domain.SizeBalancePercent, Value: 100}
+	}
+	equity, free := b.equity(), b.free()
+	quantity := plan.Quantity(equity)
+	if amount, ok := b.portfolio.Allocation.amount(equity, b.portfolio.slots()); ok {
+		quantity = amount / m.Price
+	}
+	order := Order{Direction: sig.Direction, Quantity: quantity}
+	if order.Quantity <= 0 {
+		return
+	}
+	if free <= 0 {
+		b.rejected = append(b.rejected, sig)
+		return
+	}
+	fill := b.fills.Fill(order, m)
+	// The position is sized at the last price; slippage and fees must not spend more than the cash
+	// the other positions leave.
+	if cost := order.Quantity*fill.Price + fill.Fee; cost > free {
+		scale := free / cost
+		order.Quantity *= scale
+		fill.Fee *= scale
+	}
+	b.cash -= fill.Fee
+	b.positions[sig.Symbol] = &position{
+		trade: Trade{
+			Strategy:   sig.Strategy,
+			Symbol:     sig.Symbol,
+			Direction:  sig.Direction,
+			EntryTime:  m.Time,
+			EntryPrice: fill.Price,
+			Quantity:   order.Quantity,
+			Fees:       fill.Fee,
+		},
+		stopLoss:   plan.StopLoss,
+		takeProfit: plan.TakeProfit,
+	}
+}
+
+// admit reports whether a new position fits the position limit. The slots go to the signals of the
+// same candle in the priority order of the portfolio, so the entries of higher priority that still
+// wait for their pair to tick keep their slot.
+func (b *broker) admit(sig domain.Signal) bool {
+	limit := b.portfolio.MaxPositions
+	if limit <= 0 {
+		return true
+	}
+	rank := b.portfolio.rank(sig.Symbol)
+	reserved := map[string]bool{}
+	for _, o := range b.pending {
+		other := o.signal
+		if _, open := b.positions[other.Symbol]; open || !other.Time.Equal(sig.Time) || other.Symbol == sig.Symbol {
+			continue
+		}
+		if (other.Direction == domain.Buy || b.cfg.AllowShort) && b.portfolio.rank(other.Symbol) < rank {
+			reserved[other.Symbol] = true
+		}
+	}
+	return len(b.positions)+len(reserved) < limit
+}
+
+// close closes the open position of the pair of a market with an order in the opposite direction.
+func (b *broker) close(m Market, reason ExitReason) {
+	p := b.positions[m.Symbol]
+	delete(b.positions, m.Symbol)
+	t := p.trade
+	exit := domain.Buy
+	if t.Direction == domain.Buy {
+		exit = domain.Sell
+	}
+	order := Order{Direction: exit, Quantity: t.Quantity}
+	if reason == ExitTakeProfit {
+		// The take-profit is a limit order resting in the book: it fills at its own price, even when
+		// the tick that reached it traded beyond it.
+		order.Maker, m.Price = true, p.takeProfit
+	}
+	fill := b.fills.Fill(order, m)
+	gross := p.value(fill.Price)
+	t.ExitTime, t.ExitPrice, t.ExitReason = m.Time, fill.Price, reason
+	t.Fees += fill.Fee
+	t.PnL = gross - t.Fees
+	t.RunUp, t.Drawdown = math.Max(t.RunUp, gross), math.Max(t.Drawdown, -gross)
+	t.PnLPercent = t.PnL / (t.EntryPrice * t.Quantity) * 100
+	b.cash += gross - fill.Fee
+	b.trades = append(b.trades, t)
+}
@@ -0,0 +1,310 @@
+// Package backtest replays stored history through the same application, candle and strategy code
+// the scanner runs live, and simulates the trades of the signals on a simulated account.
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/app"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+)
+
+// EngineVersion identifies the simulation rules of the engine. It changes whenever a change of the
+// engine changes the results of a backtest, so stored runs of different versions are not compared
+// as if only their settings differed.
+const EngineVersion = "3"
+
+// Config holds the settings of a backtest run.
+type Config struct {
+	Exchange string
+	Symbol   string
+	// Timeframe is the timeframe the equity curve is recorded on.
+	Timeframe domain.Timeframe
+	// From and To bound the period trades are simulated in.
+	From, To time.Time
+	// WarmUp is the stored history before From that is replayed, without trading, so the
+	// strategies and indicators are warmed up when the period starts. It is extended to the
+	// strategy.WarmUpPeriod of the strategies when that is longer. Signals of the warm-up are
+	// not traded, but they do count for the trigger rules, as they would have live.
+	WarmUp         time.Duration
+	InitialBalance float64
+	// Size is the position size of every entry. When its Kind is empty, the size of the trade plan
+	// of the signal is used, or the whole balance for signals without one.
+	Size domain.SizeRule
+	// AllowShort lets sell signals open short positions; otherwise they only close long ones.
+	AllowShort bool
+	// Trigger is the trigger rule of the strategies that do not have one of their own.
+	Trigger strategy.Trigger
+	// Fill is the model of the prices and fees of the simulated orders; nil fills at the last
+	// price without fees.
+	Fill FillModel
+	// Latency is the time between a signal and its order reaching the exchange.
+	Latency time.Duration
+}
+
+// DefaultConfig backtests on Binance with a balance of 10000 and a week of warm-up history.
+func DefaultConfig() Config {
+	return Config{
+		Exchange:       "BINANCE",
+		Timeframe:      domain.Hour1,
+		WarmUp:         7 * 24 * time.Hour,
+		InitialBalance: 10000,
+		Trigger:        strategy.DefaultTrigger(),
+	}
+}
+
+// Clock is the simulated clock of a backtest: it is at the time of the tick being replayed.
+type Clock struct {
+	now time.Time
+}
+
+// Now returns the simulated time. It can be used wherever a component takes a clock function.
+func (c *Clock) Now() time.Time {
+	return c.now
+}
+
+type engineStrategy struct {
+	symbol    string // Empty for every pair of the backtest.
+	timeframe domain.Timeframe
+	factory   strategy.Factory
+}
+
+// Engine runs backtests on the ticker history of a repository.
+type Engine struct {
+	cfg        Config
+	portfolio  PortfolioConfig
+	symbols    []string
+	history    storage.TickerHistory
+	strategies []engineStrategy
+	clock      *Clock
+}
+
+// NewEngine creates an Engine on a ticker history, e.g. the SqliteRepository.
+func NewEngine(cfg Config, history storage.TickerHistory) *Engine {
+	return &Engine{cfg: cfg, symbols: []string{cfg.Symbol}, history: history, clock: &Clock{}}
+}
+
+// NewPortfolioEngine creates an Engine that trades several pairs on one balance.
+func NewPortfolioEngine(cfg PortfolioConfig, history storage.TickerHistory) *Engine {
+	e := NewEngine(cfg.Config, history)
+	e.cfg.Symbol = strings.Join(cfg.Symbols, ",")
+	e.portfolio, e.symbols = cfg, cfg.Symbols
+	return e
+}
+
+// Add runs a strategy on a timeframe of every backtested pair.
+func (e *Engine) Add(tf domain.Timeframe, factory strategy.Factory) {
+	e.strategies = append(e.strategies, engineStrategy{timeframe: tf, factory: factory})
+}
+
+// AddPair runs a strategy on a timeframe of one of the backtested pairs.
+func (e *Engine) AddPair(symbol string, tf domain.Timeframe, factory strategy.Factory) {
+	e.strategies = append(e.strategies, engineStrategy{symbol: symbol, timeframe: tf, factory: factory})
+}
+
+// Clock returns the simulated clock of the engine.
+func (e *Engine) Clock() *Clock {
+	return e.clock
+}
+
+// Run replays the stored tickers of the period, and of the warm-up before it, one by one through an
+// app.Application, exactly as the live stream would deliver them. Candles close and strategies are
+// called on the same ticks as live, so a strategy only ever sees the past; the order of a signal
+// fills on a later tick, after the latency, at the price of the fill model. The tickers of the pairs
+// of a portfolio are replayed merged in time order.
+func (e *Engine) Run(ctx context.Context) (*Result, error) {
+	cfg := e.cfg
+	if len(e.strategies) == 0 {
+		return nil, fmt.Errorf("no strategies to backtest")
+	}
+	if len(e.symbols) == 0 {
+		return nil, fmt.Errorf("no pairs to backtest")
+	}
+	if !cfg.To.After(cfg.From) {
+		return nil, fmt.Errorf("the end of the period must be after its start")
+	}
+	for _, s := range e.strategies {
+		if need := strategy.WarmUpPeriod(s.factory(), s.timeframe); need > cfg.WarmUp {
+			log.Printf("Warming up for %s instead of %s, the strategies on %s need it", need, cfg.WarmUp, s.timeframe)
+			cfg.WarmUp = need
+		}
+	}
+	var tickers []domain.Ticker
+	for _, symbol := range e.symbols {
+		symbolTickers, err := e.history.ListTickers(ctx, symbol, cfg.From.Add(-cfg.WarmUp), cfg.To)
+		if err != nil {
+			return nil, fmt.Errorf("could not load ticker history: %w", err)
+		}
+		if len(symbolTickers) == 0 {
+			return nil, fmt.Errorf("no stored tickers for %s between %s and %s", symbol, cfg.From.Format(time.RFC3339), cfg.To.Format(time.RFC3339))
+		}
+		tickers = append(tickers, symbolTickers...)
+	}
+	// Ties keep the order of the pairs, so a replay is repeatable.
+	sort.SliceStable(tickers, func(i, j int) bool { return tickers[i].EventTime < tickers[j].EventTime })
+
+	// Stored order books are only needed to fill through the book.
+	books := map[string][]domain.OrderBook{}
+	if _, depth := cfg.Fill.(DepthFill); depth {
+		bookHistory, ok := e.history.(storage.OrderBookHistory)
+		if !ok {
+			return nil, fmt.Errorf("the history has no order books to fill through")
+		}
+		for _, symbol := range e.symbols {
+			symbolBooks, err := bookHistory.ListOrderBooks(ctx, symbol, cfg.From.Add(-time.Hour), cfg.To)
+			if err != nil {
+				return nil, fmt.Errorf("could not load order books: %w", err)
+			}
+			if len(symbolBooks) == 0 {
+				log.Printf("No stored order books for %s, orders fill without depth", symbol)
+			}
+			books[symbol] = symbolBooks
+		}
+	}
+
+	b := newBroker(cfg)
+	b.portfolio = e.portfolio
+	rec := &recorder{
+		cfg:     cfg,
+		symbols: map[string]bool{},
+		broker:  b,
+		clock:   e.clock,
+		result:  &Result{Symbol: cfg.Symbol, Timeframe: cfg.Timeframe, From: cfg.From, To: cfg.To, InitialBalance: cfg.InitialBalance},
+		recent:  map[string][]domain.Candle{},
+	}
+	trends, err := loadTrends(ctx, e.history, e.clock, cfg.Exchange, cfg.From.Add(-cfg.WarmUp), cfg.To)
+	if err != nil {
+		return nil, err
+	}
+	runner := app.NewStrategyRunner(cfg.Exchange, nil)
+	runner.SetTrigger(cfg.Trigger)
+	runner.SetTrends(trends)
+	for _, symbol := range e.symbols {
+		rec.symbols[symbol] = true
+		for _, s := range e.strategies {
+			if s.symbol == "" || s.symbol == symbol {
+				runner.Add(symbol, s.timeframe, s.factory)
+			}
+		}
+	}
+	runner.AddSignalHandler(rec)
+	application := app.New(nil, discard{})
+	if err := application.AddTimeframes(cfg.Timeframe); err != nil {
+		return nil, err
+	}
+	for _, symbol := range e.symbols {
+		if err := application.AddTimeframes(runner.Timeframes(symbol)...); err != nil {
+			return nil, err
+		}
+	}
+	// The chart trend is updated before the strategies see the candle, as it is live.
+	application.AddCandleHandler(trends.chart)
+	application.AddRunner(runner)
+	application.AddCandleHandler(rec)
+
+	markets := map[string]Market{}
+	latest := map[string]*domain.OrderBook{}
+	for _, t := range tickers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		e.clock.now = time.UnixMilli(t.EventTime).UTC()
+		pending := books[t.Symbol]
+		for len(pending) > 0 && !pending[0].Time.After(e.clock.now) {
+			latest[t.Symbol], pending = &pending[0], pending[1:]
+		}
+		books[t.Symbol] = pending
+		m := Market{Symbol: t.Symbol, Time: e.clock.now, Price: t.LastPrice.Float64Value(), Candles: rec.recent[t.Symbol], Book: latest[t.Symbol]}
+		markets[t.Symbol] = m
+		if !e.clock.now.Before(cfg.From) {
+			// Orders of the previous ticks fill before the strategies see this one.
+			b.onTick(m)
+		}
+		application.HandleTicker(ctx, t)
+	}
+	for _, symbol := range e.symbols {
+		if b.positions[symbol] != nil {
+			m := markets[symbol]
+			m.Candles = rec.recent[symbol]
+			b.close(m, ExitEnd)
+		}
+	}
+
+	res := rec.result
+	res.Trades = b.trades
+	res.Rejected = b.rejected
+	res.FinalEquity = b.cash
+	log.Printf("Backtest %s %s to %s: %d tickers, %d signals, %d trades, equity %.2f → %.2f", cfg.Symbol,
+		cfg.From.Format(time.RFC3339), cfg.To.Format(time.RFC3339), len(tickers), len(res.Signals), len(res.Trades), res.InitialBalance, res.FinalEquity)
+	return res, nil
+}
+
+// recentCandles is the number of candles of the backtest timeframe a fill model gets.
+const recentCandles = 100
+
+// recorder passes the signals of the period to the broker and records the equity curve.
+type recorder struct {
+	cfg     Config
+	symbols map[string]bool
+	broker  *broker
+	clock   *Clock
+	result  *Result
+	// recent are the last candles of the timeframe of every pair, including the warm-up.
+	recent map[string][]domain.Candle
+}
+
+// OnSignal implements app.SignalHandler.
+func (r *recorder) OnSignal(ctx context.Context, sig domain.Signal) error {
+	if !r.symbols[sig.Symbol] || r.clock.now.Before(r.cfg.From) {
+		return nil
+	}
+	r.result.Signals = append(r.result.Signals, sig)
+	r.broker.submit(sig, r.clock.now)
+	return nil
+}
+
+// OnCandle implements app.CandleHandler. The equity curve gets a point per candle close; in a
+// portfolio, the candles of the pairs that close at the same time update the same point.
+func (r *recorder) OnCandle(ctx context.Context, c domain.Candle) error {
+	if !r.symbols[c.Symbol] || c.Timeframe != r.cfg.Timeframe {
+		return nil
+	}
+	recent := append(r.recent[c.Symbol], c)
+	if len(recent) > recentCandles {
+		recent = append([]domain.Candle{}, recent[len(recent)-recentCandles:]...)
+	}
+	r.recent[c.Symbol] = recent
+	r.broker.mark(c.Symbol, c.Close)
+	if c.OpenTime.Before(r.cfg.From) {
+		return nil
+	}
+	point := EquityPoint{Time: c.CloseTime, Equity: r.broker.equity(), InPosition: len(r.broker.positions) > 0}
+	if len(r.symbols) == 1 {
+		point.Price = c.Close
+		r.result.Candles = append(r.result.Candles, c)
+	}
+	if n := len(r.result.Equity); n > 0 && r.result.Equity[n-1].Time.Equal(point.Time) {
+		r.result.Equity[n-1] = point
+		return nil
+	}
+	r.result.Equity = append(r.result.Equity, point)
+	return nil
+}
+
+// discard is the repository of the replayed application: the tickers are already stored.
+type discard struct{}
+
+func (discard) SaveTicker(ctx context.Context, ticker domain.Ticker) error { return nil }
+
+func (discard) GetTickerByEventTime(ctx context.Context, eventTime int64) (*domain.Ticker, error) {
+	return nil, nil
+}
+
+func (discard) Close() error { return nil }
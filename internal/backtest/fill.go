@@ -0,0 +1,183 @@
+package backtest
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/indicator"
+)
+
+// Order is a simulated order. Entries and exits on a signal or a stop-loss are market orders that
+// take liquidity; an exit at the take-profit is a resting limit order that provides it.
+type Order struct {
+	Direction domain.Direction
+	Quantity  float64 // In the base asset.
+	Maker     bool
+}
+
+// Market is what a fill model knows of the market when an order fills.
+type Market struct {
+	Symbol string
+	Time   time.Time
+	// Price is the last price, at the tick the order fills on.
+	Price float64
+	// Candles are the most recent closed candles of the timeframe of the backtest, oldest first.
+	Candles []domain.Candle
+	// Book is the latest stored order book snapshot, or nil when there is none.
+	Book *domain.OrderBook
+}
+
+// Fill is the result of a simulated order.
+type Fill struct {
+	Price float64
+	Fee   float64 // In the quote asset.
+}
+
+// FillModel decides the price and the fee of a simulated order.
+type FillModel interface {
+	Fill(o Order, m Market) Fill
+}
+
+// IdealFill fills every order at the last price without fees.
+type IdealFill struct{}
+
+// Fill implements FillModel.
+func (IdealFill) Fill(o Order, m Market) Fill {
+	return Fill{Price: m.Price}
+}
+
+// FeeTier is the maker and taker fee, in percent of the order value, of a fee tier of an exchange.
+type FeeTier struct {
+	Name  string
+	Maker float64
+	Taker float64
+}
+
+// fee returns the fee of an order filled at a price.
+func (t FeeTier) fee(o Order, price float64) float64 {
+	rate := t.Taker
+	if o.Maker {
+		rate = t.Maker
+	}
+	return o.Quantity * price * rate / 100
+}
+
+// BinanceSpotTiers are the spot trading fee tiers of Binance, without the BNB discount.
+var BinanceSpotTiers = []FeeTier{
+	{Name: "VIP0", Maker: 0.1, Taker: 0.1},
+	{Name: "VIP1", Maker: 0.09, Taker: 0.1},
+	{Name: "VIP2", Maker: 0.08, Taker: 0.1},
+	{Name: "VIP3", Maker: 0.042, Taker: 0.06},
+	{Name: "VIP4", Maker: 0.042, Taker: 0.054},
+	{Name: "VIP5", Maker: 0.036, Taker: 0.048},
+	{Name: "VIP6", Maker: 0.03, Taker: 0.042},
+	{Name: "VIP7", Maker: 0.024, Taker: 0.036},
+	{Name: "VIP8", Maker: 0.018, Taker: 0.03},
+	{Name: "VIP9", Maker: 0.012, Taker: 0.024},
+}
+
+// FeeTierByName returns the Binance spot fee tier with a name like "VIP0", ignoring case.
+func FeeTierByName(name string) (FeeTier, bool) {
+	for _, t := range BinanceSpotTiers {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return FeeTier{}, false
+}
+
+// Slippage is how far, in percent, a market order fills from the last price.
+type Slippage interface {
+	Percent(m Market) float64
+}
+
+// FixedSlippage is a slippage of a fixed percentage.
+type FixedSlippage float64
+
+// Percent implements Slippage.
+func (s FixedSlippage) Percent(m Market) float64 {
+	return float64(s)
+}
+
+// VolatilitySlippage is a slippage proportional to the volatility: Factor times the ATR over
+// Period candles, in percent of the price. It is zero until there are enough candles.
+type VolatilitySlippage struct {
+	Factor float64
+	Period int
+}
+
+// Percent implements Slippage.
+func (s VolatilitySlippage) Percent(m Market) float64 {
+	if len(m.Candles) <= s.Period || m.Price <= 0 {
+		return 0
+	}
+	atr := indicator.ATR(m.Candles, s.Period)
+	last := atr[len(atr)-1]
+	if math.IsNaN(last) {
+		return 0
+	}
+	return s.Factor * last / m.Price * 100
+}
+
+// CostFill fills orders at the last price moved against the order by the slippage, and charges
+// the fee of the tier. Maker orders rest in the book, so they do not slip.
+type CostFill struct {
+	Fees     FeeTier
+	Slippage Slippage // Nil for none.
+}
+
+// Fill implements FillModel.
+func (f CostFill) Fill(o Order, m Market) Fill {
+	price := m.Price
+	if f.Slippage != nil && !o.Maker {
+		price = slip(o.Direction, price, f.Slippage.Percent(m))
+	}
+	return Fill{Price: price, Fee: f.Fees.fee(o, price)}
+}
+
+// DepthFill fills market orders by walking the stored order book snapshot, so large orders get a
+// worse average price. The part of an order beyond the depth of the snapshot fills at its last
+// level. Orders without a snapshot, and maker orders, fill as with Fallback.
+type DepthFill struct {
+	Fees     FeeTier
+	Fallback FillModel // Nil for IdealFill with the fees of the tier.
+	// MaxBookAge is how old a snapshot may be when the order fills; zero means a minute.
+	MaxBookAge time.Duration
+}
+
+// Fill implements FillModel.
+func (f DepthFill) Fill(o Order, m Market) Fill {
+	maxAge := f.MaxBookAge
+	if maxAge == 0 {
+		maxAge = time.Minute
+	}
+	var levels []domain.PriceLevel
+	if m.Book != nil && m.Time.Sub(m.Book.Time) <= maxAge {
+		levels = m.Book.Asks
+		if o.Direction == domain.Sell {
+			levels = m.Book.Bids
+		}
+	}
+	if o.Maker || len(levels) == 0 || o.Quantity <= 0 {
+		if f.Fallback != nil {
+			return f.Fallback.Fill(o, m)
+		}
+		return Fill{Price: m.Price, Fee: f.Fees.fee(o, m.Price)}
+	}
+	base, quote := m.Book.Fill(o.Direction, o.Quantity, math.Inf(1))
+	if rest := o.Quantity - base; rest > 0 {
+		quote += rest * levels[len(levels)-1].Price
+	}
+	price := quote / o.Quantity
+	return Fill{Price: price, Fee: f.Fees.fee(o, price)}
+}
+
+// slip moves a price against an order by a percentage: up for a buy, down for a sell.
+func slip(d domain.Direction, price, percent float64) float64 {
+	if d == domain.Sell {
+		return price * (1 - percent/100)
+	}
+	return price * (1 + percent/100)
+}
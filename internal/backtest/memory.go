@@ -0,0 +1,88 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+)
+
+// MemoryHistory is stored history loaded into memory once, so many backtests of the same period,
+// e.g. of an optimization, do not each read the database. It is safe for concurrent use.
+type MemoryHistory struct {
+	from    time.Time // Start of the loaded history; there is nothing before it to warm up on.
+	tickers []domain.Ticker
+	books   []domain.OrderBook
+	trends  storage.MarketTrendRepository // Nil when the history stores no market trends.
+}
+
+// LoadHistory loads the tickers of a symbol with an event time in [from, to), and its order books
+// when the history stores them.
+func LoadHistory(ctx context.Context, history storage.TickerHistory, symbol string, from, to time.Time) (*MemoryHistory, error) {
+	tickers, err := history.ListTickers(ctx, symbol, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("could not load ticker history: %w", err)
+	}
+	m := &MemoryHistory{from: from, tickers: tickers}
+	// The market trends are few next to the tickers; they are read from the history itself.
+	m.trends, _ = history.(storage.MarketTrendRepository)
+	if bookHistory, ok := history.(storage.OrderBookHistory); ok {
+		if m.books, err = bookHistory.ListOrderBooks(ctx, symbol, from, to); err != nil {
+			return nil, fmt.Errorf("could not load order books: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// ListTickers implements storage.TickerHistory for the loaded symbol; other symbols have no tickers.
+// It fails for tickers from before the loaded history, which a backtest would silently warm up
+// without.
+func (m *MemoryHistory) ListTickers(ctx context.Context, symbol string, from, to time.Time) ([]domain.Ticker, error) {
+	if from.Before(m.from) {
+		return nil, fmt.Errorf("tickers from %s were asked for, but the history was loaded from %s", from.Format(time.RFC3339), m.from.Format(time.RFC3339))
+	}
+	i := sort.Search(len(m.tickers), func(i int) bool { return m.tickers[i].EventTime >= from.UnixMilli() })
+	j := sort.Search(len(m.tickers), func(i int) bool { return m.tickers[i].EventTime >= to.UnixMilli() })
+	var out []domain.Ticker
+	for _, t := range m.tickers[i:j] {
+		if symbol == "" || t.Symbol == symbol {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// SaveOrderBook implements storage.OrderBookHistory; the history is read-only.
+func (m *MemoryHistory) SaveOrderBook(ctx context.Context, book domain.OrderBook) error {
+	return fmt.Errorf("could not save order book: the history is read-only")
+}
+
+// ListOrderBooks implements storage.OrderBookHistory.
+func (m *MemoryHistory) ListOrderBooks(ctx context.Context, symbol string, from, to time.Time) ([]domain.OrderBook, error) {
+	i := sort.Search(len(m.books), func(i int) bool { return !m.books[i].Time.Before(from) })
+	j := sort.Search(len(m.books), func(i int) bool { return !m.books[i].Time.Before(to) })
+	var out []domain.OrderBook
+	for _, b := range m.books[i:j] {
+		if b.Symbol == symbol {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+// SaveMarketTrend implements storage.MarketTrendRepository; the history is read-only.
+func (m *MemoryHistory) SaveMarketTrend(ctx context.Context, trend domain.MarketTrend) error {
+	return fmt.Errorf("could not save market trend: the history is read-only")
+}
+
+// ListMarketTrends implements storage.MarketTrendRepository with the market trends of the history
+// it was loaded from.
+func (m *MemoryHistory) ListMarketTrends(ctx context.Context, exchange string, tf domain.Timeframe, from, to time.Time) ([]domain.MarketTrend, error) {
+	if m.trends == nil {
+		return nil, nil
+	}
+	return m.trends.ListMarketTrends(ctx, exchange, tf, from, to)
+}
@@ -0,0 +1,213 @@
+package backtest
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// Ratio is a metric that is undefined in some runs, e.g. the profit factor without losing trades.
+// An undefined ratio is NaN, or infinite when it is unbounded, and is written to JSON as null.
+type Ratio float64
+
+// MarshalJSON implements json.Marshaler.
+func (r Ratio) MarshalJSON() ([]byte, error) {
+	f := float64(r)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(f)
+}
+
+// Duration is a time.Duration written to JSON as a string like "1h30m0s".
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Metrics is the performance of a backtest run, as listed by the strategy tester. Amounts are in
+// the quote asset, percentages relative to the initial balance unless noted otherwise.
+type Metrics struct {
+	InitialBalance   float64 `json:"initial_balance"`
+	FinalEquity      float64 `json:"final_equity"`
+	NetProfit        float64 `json:"net_profit"`
+	NetProfitPercent float64 `json:"net_profit_percent"`
+	GrossProfit      float64 `json:"gross_profit"`
+	GrossLoss        float64 `json:"gross_loss"`
+	// Fees are the fees of all trades; the other amounts are after fees.
+	Fees float64 `json:"fees"`
+	// MaxDrawdown is the largest fall of the equity from a previous peak; the percentage is
+	// relative to that peak.
+	MaxDrawdown        float64 `json:"max_drawdown"`
+	MaxDrawdownPercent float64 `json:"max_drawdown_percent"`
+	TotalTrades        int     `json:"total_trades"`
+	WinningTrades      int     `json:"winning_trades"`
+	LosingTrades       int     `json:"losing_trades"`
+	// ProfitableTrades is the percentage of winning trades.
+	ProfitableTrades Ratio `json:"profitable_trades_percent"`
+	// ProfitFactor is the gross profit divided by the gross loss.
+	ProfitFactor Ratio   `json:"profit_factor"`
+	AverageTrade Ratio   `json:"average_trade"`
+	AverageWin   Ratio   `json:"average_win"`
+	AverageLoss  Ratio   `json:"average_loss"`
+	LargestWin   float64 `json:"largest_win"`
+	LargestLoss  float64 `json:"largest_loss"`
+	// AverageRunUp and AverageTradeDrawdown are the averages of the largest unrealized profit and
+	// loss of the trades; MaxRunUp and MaxTradeDrawdown the extremes.
+	AverageRunUp         Ratio   `json:"average_run_up"`
+	AverageTradeDrawdown Ratio   `json:"average_trade_drawdown"`
+	MaxRunUp             float64 `json:"max_run_up"`
+	MaxTradeDrawdown     float64 `json:"max_trade_drawdown"`
+	// BuyHold is the profit of buying with the whole initial balance at the start of the period and
+	// selling at its end.
+	BuyHold        float64 `json:"buy_hold"`
+	BuyHoldPercent float64 `json:"buy_hold_percent"`
+	// Sharpe and Sortino are annualized from the returns of the equity curve per candle, without
+	// a risk-free rate.
+	Sharpe  Ratio `json:"sharpe"`
+	Sortino Ratio `json:"sortino"`
+	// Exposure is the percentage of the period a position was open.
+	Exposure             float64  `json:"exposure_percent"`
+	AverageTradeDuration Duration `json:"average_trade_duration"`
+}
+
+// Analyze calculates the metrics of a backtest run.
+func Analyze(res *Result) Metrics {
+	m := Metrics{
+		InitialBalance:   res.InitialBalance,
+		FinalEquity:      res.FinalEquity,
+		NetProfit:        res.FinalEquity - res.InitialBalance,
+		TotalTrades:      len(res.Trades),
+		ProfitableTrades: Ratio(math.NaN()),
+		AverageTrade:     Ratio(math.NaN()),
+	}
+	m.NetProfitPercent = percentOf(m.NetProfit, res.InitialBalance)
+
+	var runUp, drawdown float64
+	var open time.Duration
+	for _, t := range res.Trades {
+		if t.PnL > 0 {
+			m.WinningTrades++
+			m.GrossProfit += t.PnL
+			m.LargestWin = math.Max(m.LargestWin, t.PnL)
+		} else {
+			m.LosingTrades++
+			m.GrossLoss -= t.PnL
+			m.LargestLoss = math.Min(m.LargestLoss, t.PnL)
+		}
+		m.Fees += t.Fees
+		runUp += t.RunUp
+		drawdown += t.Drawdown
+		m.MaxRunUp = math.Max(m.MaxRunUp, t.RunUp)
+		m.MaxTradeDrawdown = math.Max(m.MaxTradeDrawdown, t.Drawdown)
+		open += t.Duration()
+	}
+	n := float64(len(res.Trades))
+	m.ProfitableTrades = Ratio(float64(m.WinningTrades) / n * 100)
+	m.ProfitFactor = Ratio(m.GrossProfit / m.GrossLoss)
+	if m.GrossLoss == 0 && m.GrossProfit == 0 {
+		m.ProfitFactor = Ratio(math.NaN())
+	}
+	m.AverageTrade = Ratio((m.GrossProfit - m.GrossLoss) / n)
+	m.AverageWin = Ratio(m.GrossProfit / float64(m.WinningTrades))
+	m.AverageLoss = Ratio(-m.GrossLoss / float64(m.LosingTrades))
+	m.AverageRunUp = Ratio(runUp / n)
+	m.AverageTradeDrawdown = Ratio(drawdown / n)
+	if len(res.Trades) > 0 {
+		m.AverageTradeDuration = Duration(open / time.Duration(len(res.Trades)))
+	}
+
+	m.MaxDrawdown, m.MaxDrawdownPercent = maxDrawdown(res)
+	if len(res.Candles) > 0 {
+		first, last := res.Candles[0].Open, res.Candles[len(res.Candles)-1].Close
+		if first > 0 {
+			m.BuyHoldPercent = (last/first - 1) * 100
+			m.BuyHold = res.InitialBalance * m.BuyHoldPercent / 100
+		}
+	}
+	m.Sharpe, m.Sortino = riskRatios(res)
+	if span := period(res); span > 0 {
+		m.Exposure = math.Min(float64(open)/float64(span)*100, 100)
+	}
+	return m
+}
+
+// maxDrawdown returns the largest fall of the equity curve from a previous peak, starting from the
+// initial balance and ending at the final equity.
+func maxDrawdown(res *Result) (amount, percent float64) {
+	peak := res.InitialBalance
+	visit := func(equity float64) {
+		peak = math.Max(peak, equity)
+		if dd := peak - equity; dd > amount {
+			amount, percent = dd, percentOf(dd, peak)
+		}
+	}
+	for _, p := range res.Equity {
+		visit(p.Equity)
+	}
+	visit(res.FinalEquity)
+	return amount, percent
+}
+
+// riskRatios returns the annualized Sharpe and Sortino ratios of the returns per candle of the
+// equity curve.
+func riskRatios(res *Result) (sharpe, sortino Ratio) {
+	sharpe, sortino = Ratio(math.NaN()), Ratio(math.NaN())
+	step := res.Timeframe.Duration()
+	if len(res.Equity) < 2 || step <= 0 {
+		return sharpe, sortino
+	}
+	var returns []float64
+	prev := res.InitialBalance
+	for _, p := range res.Equity {
+		if prev > 0 {
+			returns = append(returns, p.Equity/prev-1)
+		}
+		prev = p.Equity
+	}
+	var mean, variance, downside float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	n := float64(len(returns))
+	annualize := math.Sqrt(float64(365*24*time.Hour) / float64(step))
+	if sd := math.Sqrt(variance / (n - 1)); sd > 0 {
+		sharpe = Ratio(mean / sd * annualize)
+	}
+	if dd := math.Sqrt(downside / n); dd > 0 {
+		sortino = Ratio(mean / dd * annualize)
+	} else if mean > 0 {
+		sortino = Ratio(math.Inf(1))
+	}
+	return sharpe, sortino
+}
+
+// period returns the part of the backtest period there was data for.
+func period(res *Result) time.Duration {
+	end := res.To
+	if len(res.Equity) > 0 {
+		if last := res.Equity[len(res.Equity)-1].Time; last.Before(end) {
+			end = last
+		}
+	}
+	if n := len(res.Trades); n > 0 && res.Trades[n-1].ExitTime.After(end) {
+		end = res.Trades[n-1].ExitTime
+	}
+	return end.Sub(res.From)
+}
+
+func percentOf(amount, base float64) float64 {
+	if base == 0 {
+		return 0
+	}
+	return amount / base * 100
+}
@@ -0,0 +1,167 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// MonteCarloMethod is how a Monte Carlo simulation resamples the trades of a backtest.
+type MonteCarloMethod string
+
+const (
+	// MonteCarloShuffle replays the trades in a random order. The final P&L is the same in every
+	// run, as the returns compound in any order; only the path, and so the drawdown, changes.
+	MonteCarloShuffle MonteCarloMethod = "shuffle"
+	// MonteCarloBootstrap draws as many trades as the backtest made, with replacement.
+	MonteCarloBootstrap MonteCarloMethod = "bootstrap"
+	// MonteCarloSkip drops every trade with the skip probability, as if its signal had been missed.
+	MonteCarloSkip MonteCarloMethod = "skip"
+)
+
+// MonteCarloConfig configures a Monte Carlo simulation.
+type MonteCarloConfig struct {
+	Method MonteCarloMethod
+	Runs   int
+	// SkipPercent is the probability, in percent, that MonteCarloSkip drops a trade.
+	SkipPercent float64
+	// Seed seeds the generator, so a simulation can be repeated.
+	Seed int64
+}
+
+// DefaultMonteCarloConfig returns 1000 shuffled runs, with a 10% skip probability for MonteCarloSkip.
+func DefaultMonteCarloConfig() MonteCarloConfig {
+	return MonteCarloConfig{Method: MonteCarloShuffle, Runs: 1000, SkipPercent: 10, Seed: 1}
+}
+
+// Distribution summarizes the values of a metric over the runs of a simulation.
+type Distribution struct {
+	Mean float64 `json:"mean"`
+	Min  float64 `json:"min"`
+	P5   float64 `json:"p5"`
+	P25  float64 `json:"p25"`
+	P50  float64 `json:"p50"`
+	P75  float64 `json:"p75"`
+	P95  float64 `json:"p95"`
+	Max  float64 `json:"max"`
+}
+
+// MonteCarloResult is the outcome of a Monte Carlo simulation.
+type MonteCarloResult struct {
+	Method MonteCarloMethod `json:"method"`
+	Runs   int              `json:"runs"`
+	// NetProfit is the distribution of the net profit, MaxDrawdownPercent of the max drawdown of the
+	// equity after every trade.
+	NetProfit          Distribution `json:"net_profit"`
+	MaxDrawdownPercent Distribution `json:"max_drawdown_percent"`
+	// LossPercent is the percentage of runs that lost money.
+	LossPercent float64 `json:"loss_percent"`
+	// NetProfitRank and DrawdownRank are the percentages of runs that did worse than the backtest:
+	// a lower net profit and a larger drawdown. A high rank means the backtest was lucky.
+	NetProfitRank float64 `json:"net_profit_rank"`
+	DrawdownRank  float64 `json:"drawdown_rank"`
+}
+
+// MonteCarlo resamples the trades of a backtest. Every trade is replayed as its return on the equity
+// it was entered with, so resampled trades compound like the backtest did.
+func MonteCarlo(res *Result, cfg MonteCarloConfig) (MonteCarloResult, error) {
+	if cfg.Runs <= 0 {
+		return MonteCarloResult{}, fmt.Errorf("the number of runs must be positive")
+	}
+	if len(res.Trades) == 0 {
+		return MonteCarloResult{}, fmt.Errorf("no trades to resample")
+	}
+	returns := make([]float64, len(res.Trades))
+	equity := res.InitialBalance
+	for i, t := range res.Trades {
+		if equity > 0 {
+			returns[i] = t.PnL / equity
+		}
+		equity += t.PnL
+	}
+	profit, drawdown := replay(returns, res.InitialBalance)
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	sample := make([]float64, len(returns))
+	profits, drawdowns := make([]float64, cfg.Runs), make([]float64, cfg.Runs)
+	out := MonteCarloResult{Method: cfg.Method, Runs: cfg.Runs}
+	for run := 0; run < cfg.Runs; run++ {
+		sample = sample[:0]
+		switch cfg.Method {
+		case MonteCarloShuffle:
+			sample = append(sample, returns...)
+			rng.Shuffle(len(sample), func(i, j int) { sample[i], sample[j] = sample[j], sample[i] })
+		case MonteCarloBootstrap:
+			for range returns {
+				sample = append(sample, returns[rng.Intn(len(returns))])
+			}
+		case MonteCarloSkip:
+			for _, r := range returns {
+				if rng.Float64()*100 >= cfg.SkipPercent {
+					sample = append(sample, r)
+				}
+			}
+		default:
+			return MonteCarloResult{}, fmt.Errorf("unknown Monte Carlo method %q", cfg.Method)
+		}
+		profits[run], drawdowns[run] = replay(sample, res.InitialBalance)
+		if profits[run] < 0 {
+			out.LossPercent++
+		}
+		// A tolerance keeps runs that only differ by rounding from counting as worse.
+		if profits[run] < profit-1e-9*math.Max(1, math.Abs(profit)) {
+			out.NetProfitRank++
+		}
+		if drawdowns[run] > drawdown+1e-9 {
+			out.DrawdownRank++
+		}
+	}
+	runs := float64(cfg.Runs)
+	out.LossPercent = out.LossPercent / runs * 100
+	out.NetProfitRank = out.NetProfitRank / runs * 100
+	out.DrawdownRank = out.DrawdownRank / runs * 100
+	out.NetProfit, out.MaxDrawdownPercent = distribution(profits), distribution(drawdowns)
+	return out, nil
+}
+
+// replay compounds returns from an initial balance and returns the net profit and the max drawdown,
+// in percent, of the equity after every trade.
+func replay(returns []float64, initial float64) (profit, drawdown float64) {
+	equity, peak := initial, initial
+	for _, r := range returns {
+		equity *= 1 + r
+		peak = math.Max(peak, equity)
+		drawdown = math.Max(drawdown, percentOf(peak-equity, peak))
+	}
+	return equity - initial, drawdown
+}
+
+// distribution sorts values and returns their distribution.
+func distribution(values []float64) Distribution {
+	sort.Float64s(values)
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return Distribution{
+		Mean: sum / float64(len(values)),
+		Min:  values[0],
+		P5:   percentile(values, 5),
+		P25:  percentile(values, 25),
+		P50:  percentile(values, 50),
+		P75:  percentile(values, 75),
+		P95:  percentile(values, 95),
+		Max:  values[len(values)-1],
+	}
+}
+
+// percentile returns the p-th percentile of sorted values, interpolating between the closest ranks.
+func percentile(sorted []float64, p float64) float64 {
+	pos := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	if lo >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lo] + (pos-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
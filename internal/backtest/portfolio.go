@@ -0,0 +1,94 @@
+package backtest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AllocationKind is how a portfolio sizes a new position.
+type AllocationKind string
+
+const (
+	// AllocatePlan sizes positions as a backtest of a single pair does: with the Size of the
+	// configuration, or the size of the trade plan of the signal, on the equity.
+	AllocatePlan AllocationKind = ""
+	// AllocateEqual gives every position an equal share of the equity: the equity divided by the
+	// maximum number of positions, or by the number of pairs without a maximum.
+	AllocateEqual AllocationKind = "equal"
+	// AllocatePercent gives every position Value percent of the equity.
+	AllocatePercent AllocationKind = "percent"
+)
+
+// Allocation is the rule a portfolio sizes new positions with. A position never spends more than the
+// cash the open positions leave.
+type Allocation struct {
+	Kind  AllocationKind
+	Value float64
+}
+
+// ParseAllocation parses an allocation written as "plan", "equal" or "percent:<value>".
+func ParseAllocation(s string) (Allocation, error) {
+	switch {
+	case s == "plan":
+		return Allocation{Kind: AllocatePlan}, nil
+	case s == "equal":
+		return Allocation{Kind: AllocateEqual}, nil
+	}
+	if rest, ok := strings.CutPrefix(s, "percent:"); ok {
+		if value, err := strconv.ParseFloat(rest, 64); err == nil && value > 0 {
+			return Allocation{Kind: AllocatePercent, Value: value}, nil
+		}
+	}
+	return Allocation{}, fmt.Errorf("allocation %q: expected plan, equal or percent:<value>", s)
+}
+
+// amount returns the amount of a new position, in the quote asset, for an equity shared by a number
+// of slots, or false when the trade plan sizes it.
+func (a Allocation) amount(equity float64, slots int) (float64, bool) {
+	switch a.Kind {
+	case AllocateEqual:
+		return equity / float64(slots), true
+	case AllocatePercent:
+		return equity * a.Value / 100, true
+	}
+	return 0, false
+}
+
+// PortfolioConfig holds the settings of a backtest of several pairs that share one balance. The
+// Symbol of the embedded Config is not used.
+type PortfolioConfig struct {
+	Config
+	// Symbols are the traded pairs, in order of priority: when signals of the same candle compete
+	// for the last free positions, the pairs listed first get them.
+	Symbols []string
+	// MaxPositions is the most positions open at the same time; zero for no limit.
+	MaxPositions int
+	Allocation   Allocation
+	// SignalWindow is how long after the close of a candle entries wait, so the candles of the
+	// other pairs close and their signals compete for the positions too. Zero lets entries fill on
+	// the next tick, first come, first served, as in a backtest of a single pair.
+	SignalWindow time.Duration
+}
+
+// slots returns the number of positions the equity is shared by.
+func (p PortfolioConfig) slots() int {
+	switch {
+	case p.MaxPositions > 0:
+		return p.MaxPositions
+	case len(p.Symbols) > 0:
+		return len(p.Symbols)
+	}
+	return 1
+}
+
+// rank returns the priority of a pair, lowest first.
+func (p PortfolioConfig) rank(symbol string) int {
+	for i, s := range p.Symbols {
+		if s == symbol {
+			return i
+		}
+	}
+	return len(p.Symbols)
+}
@@ -0,0 +1,75 @@
+package backtest
+
+import (
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// ExitReason tells why a simulated position was closed.
+type ExitReason string
+
+const (
+	// ExitSignal is a close on a signal in the opposite direction.
+	ExitSignal ExitReason = "signal"
+	// ExitStopLoss is a close at the stop-loss of the trade plan.
+	ExitStopLoss ExitReason = "stop_loss"
+	// ExitTakeProfit is a close at the take-profit of the trade plan.
+	ExitTakeProfit ExitReason = "take_profit"
+	// ExitEnd is the close of a position still open at the end of the period.
+	ExitEnd ExitReason = "end"
+)
+
+// Trade is a closed simulated position.
+type Trade struct {
+	Strategy  string           `json:"strategy"`
+	Symbol    string           `json:"symbol"`
+	Direction domain.Direction `json:"direction"` // Buy for a long position, Sell for a short one.
+	EntryTime time.Time        `json:"entry_time"`
+	// EntryPrice and ExitPrice are the prices the orders filled at.
+	EntryPrice float64   `json:"entry_price"`
+	ExitTime   time.Time `json:"exit_time"`
+	ExitPrice  float64   `json:"exit_price"`
+	Quantity   float64   `json:"quantity"` // In the base asset.
+	// Fees are the fees of the entry and the exit, in the quote asset.
+	Fees float64 `json:"fees"`
+	// PnL is the profit or loss in the quote asset, after fees; PnLPercent is relative to the entry value.
+	PnL        float64 `json:"pnl"`
+	PnLPercent float64 `json:"pnl_percent"`
+	// RunUp and Drawdown are the largest unrealized profit and loss, in the quote asset and before
+	// fees, while the position was open.
+	RunUp      float64    `json:"run_up"`
+	Drawdown   float64    `json:"drawdown"`
+	ExitReason ExitReason `json:"exit_reason"`
+}
+
+// Duration returns how long the position was open.
+func (t Trade) Duration() time.Duration {
+	return t.ExitTime.Sub(t.EntryTime)
+}
+
+// EquityPoint is the equity of the account, with open positions valued at the price, at a candle close.
+type EquityPoint struct {
+	Time   time.Time
+	Equity float64
+	Price  float64
+	// InPosition reports whether a position was open at that moment.
+	InPosition bool
+}
+
+// Result is the outcome of a backtest run.
+type Result struct {
+	Symbol         string
+	Timeframe      domain.Timeframe // The timeframe of the equity curve and of Candles.
+	From, To       time.Time
+	InitialBalance float64
+	FinalEquity    float64
+	Trades         []Trade
+	Equity         []EquityPoint
+	Signals        []domain.Signal
+	// Rejected are the signals whose entry was refused because the limit of open positions was
+	// reached or no cash was free.
+	Rejected []domain.Signal
+	// Candles are the closed candles of the period, for charting the price; a portfolio has none.
+	Candles []domain.Candle
+}
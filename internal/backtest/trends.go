@@ -0,0 +1,60 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/trend"
+)
+
+// replayedTrends are the trends the strategies of a backtest see: the chart trend of the replayed
+// candles, and the market trends the scanner stored, as they were at the time of the replayed tick.
+// It implements strategy.Trends.
+type replayedTrends struct {
+	clock  *Clock
+	chart  *trend.ChartTrendService
+	market map[domain.Timeframe][]domain.MarketTrend
+}
+
+// loadTrends loads the stored market trends of an exchange that complete in [from, to), when the
+// history stores them. The chart trend starts empty and follows the replayed candles.
+func loadTrends(ctx context.Context, history storage.TickerHistory, clock *Clock, exchange string, from, to time.Time) (*replayedTrends, error) {
+	t := &replayedTrends{
+		clock:  clock,
+		chart:  trend.NewChartTrendService(trend.DefaultChartConfig(), nil),
+		market: map[domain.Timeframe][]domain.MarketTrend{},
+	}
+	trends, ok := history.(storage.MarketTrendRepository)
+	if !ok {
+		return t, nil
+	}
+	for _, tf := range trend.DefaultMarketConfig().Timeframes {
+		// The period that completes before from is the market trend at from.
+		series, err := trends.ListMarketTrends(ctx, exchange, tf, from.Add(-tf.Duration()), to)
+		if err != nil {
+			return nil, fmt.Errorf("could not load market trends: %w", err)
+		}
+		t.market[tf] = series
+	}
+	return t, nil
+}
+
+// ChartTrend returns the chart trend of a pair on a timeframe, as of its last replayed candle.
+func (t *replayedTrends) ChartTrend(symbol string, tf domain.Timeframe) (domain.ChartTrend, bool) {
+	return t.chart.Trend(symbol, tf)
+}
+
+// MarketTrend returns the stored market trend of the last period of a timeframe completed at the
+// simulated time.
+func (t *replayedTrends) MarketTrend(tf domain.Timeframe) (domain.MarketTrend, bool) {
+	series := t.market[tf]
+	i := sort.Search(len(series), func(i int) bool { return series[i].Time.After(t.clock.now) })
+	if i == 0 {
+		return domain.MarketTrend{}, false
+	}
+	return series[i-1], true
+}
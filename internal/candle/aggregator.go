@@ -0,0 +1,166 @@
+// Package candle builds OHLCV candles from the live ticker stream.
+package candle
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// DefaultTimeframes are the timeframes the scanner aggregates when none are configured.
+var DefaultTimeframes = []domain.Timeframe{domain.Minute1, domain.Minute5, domain.Minute15, domain.Hour1, domain.Hour4, domain.Day1}
+
+type seriesKey struct {
+	symbol    string
+	timeframe domain.Timeframe
+}
+
+// Aggregator turns ticks into candles for several timeframes at once.
+// A candle is closed by the first tick that falls into the next candle's period,
+// so only the timeframes whose candle actually closed are reported for a tick.
+type Aggregator struct {
+	volumes    volumeTracker
+	mu         sync.Mutex
+	timeframes []domain.Timeframe
+	open       map[seriesKey]*domain.Candle
+}
+
+// NewAggregator creates an Aggregator for the given time-based timeframes, ordered from short to long.
+func NewAggregator(timeframes ...domain.Timeframe) *Aggregator {
+	tfs := append([]domain.Timeframe{}, timeframes...)
+	sort.Slice(tfs, func(i, j int) bool { return tfs[i].Duration() < tfs[j].Duration() })
+	return &Aggregator{
+		timeframes: tfs,
+		open:       make(map[seriesKey]*domain.Candle),
+	}
+}
+
+// Timeframes returns the timeframes of the aggregator, ordered from short to long.
+func (a *Aggregator) Timeframes() []domain.Timeframe {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]domain.Timeframe{}, a.timeframes...)
+}
+
+// AddTimeframe adds a time-based timeframe to the aggregator; a timeframe it already has is ignored.
+// Its first candle opens with the next tick.
+func (a *Aggregator) AddTimeframe(tf domain.Timeframe) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, have := range a.timeframes {
+		if have == tf {
+			return
+		}
+	}
+	a.timeframes = append(a.timeframes, tf)
+	sort.Slice(a.timeframes, func(i, j int) bool { return a.timeframes[i].Duration() < a.timeframes[j].Duration() })
+}
+
+// AddTicker adds a 24h ticker update and returns the candles it closed.
+func (a *Aggregator) AddTicker(t domain.Ticker) []domain.Candle {
+	at, price, volume := a.volumes.tick(t)
+	return a.Add(t.Symbol, at, price, volume)
+}
+
+// Add adds a single trade-like tick and returns the candles it closed, shortest timeframe first.
+// Ticks older than the currently open candle are ignored.
+func (a *Aggregator) Add(symbol string, at time.Time, price, volume float64) []domain.Candle {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var closed []domain.Candle
+	for _, tf := range a.timeframes {
+		key := seriesKey{symbol: symbol, timeframe: tf}
+		openTime := tf.Truncate(at)
+		c := a.open[key]
+		if c != nil && openTime.Before(c.OpenTime) {
+			continue
+		}
+		if c != nil && openTime.After(c.OpenTime) {
+			closed = append(closed, *c)
+			c = nil
+		}
+		if c == nil {
+			a.open[key] = &domain.Candle{
+				Symbol:    symbol,
+				Timeframe: tf,
+				OpenTime:  openTime,
+				CloseTime: openTime.Add(tf.Duration()),
+				Open:      price,
+				High:      price,
+				Low:       price,
+				Close:     price,
+				Volume:    volume,
+			}
+			continue
+		}
+		if price > c.High {
+			c.High = price
+		}
+		if price < c.Low {
+			c.Low = price
+		}
+		c.Close = price
+		c.Volume += volume
+	}
+	return closed
+}
+
+// Build aggregates stored tickers into the closed candles of the given timeframes, which may be
+// non-time bars as well; timeframes that are neither are skipped. The candle that is still open
+// after the last ticker is not included.
+func Build(tickers []domain.Ticker, timeframes ...domain.Timeframe) []domain.Candle {
+	agg := NewAggregator()
+	feed := NewFeed(agg)
+	var transforms []Transform
+	wanted := make(map[domain.Timeframe]bool)
+	for _, tf := range timeframes {
+		if wanted[tf] {
+			continue
+		}
+		if tf.Duration() > 0 {
+			wanted[tf] = true
+			agg.AddTimeframe(tf)
+			continue
+		}
+		builder, transform, err := NewBars(tf)
+		if err != nil {
+			continue
+		}
+		wanted[tf] = true
+		if builder != nil {
+			feed.AddBuilder(builder)
+			continue
+		}
+		transforms = append(transforms, transform)
+		agg.AddTimeframe(transform.Base())
+	}
+
+	var candles []domain.Candle
+	for _, t := range tickers {
+		for _, c := range feed.AddTicker(t) {
+			if wanted[c.Timeframe] {
+				candles = append(candles, c)
+			}
+			for _, transform := range transforms {
+				if transform.Base() == c.Timeframe {
+					candles = append(candles, transform.Apply(c)...)
+				}
+			}
+		}
+	}
+	return candles
+}
+
+// OfTimeframe returns the candles of one timeframe, keeping their order.
+func OfTimeframe(candles []domain.Candle, tf domain.Timeframe) []domain.Candle {
+	var out []domain.Candle
+	for _, c := range candles {
+		if c.Timeframe == tf {
+			out = append(out, c)
+		}
+	}
+	return out
+}
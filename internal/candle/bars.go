@@ -0,0 +1,74 @@
+package candle
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// ThresholdBars builds bars that close on a price range or on traded volume instead of on time.
+type ThresholdBars struct {
+	mu        sync.Mutex
+	timeframe domain.Timeframe
+	closed    func(c *domain.Candle) bool
+	open      map[string]*domain.Candle
+}
+
+// NewRangeBars creates bars that close as soon as their high-low range reaches size.
+func NewRangeBars(size float64) *ThresholdBars {
+	return &ThresholdBars{
+		timeframe: domain.Timeframe(fmt.Sprintf("range:%g", size)),
+		closed:    func(c *domain.Candle) bool { return c.High-c.Low >= size },
+		open:      make(map[string]*domain.Candle),
+	}
+}
+
+// NewVolumeBars creates bars that close as soon as their traded volume reaches size.
+func NewVolumeBars(size float64) *ThresholdBars {
+	return &ThresholdBars{
+		timeframe: domain.Timeframe(fmt.Sprintf("volume:%g", size)),
+		closed:    func(c *domain.Candle) bool { return c.Volume >= size },
+		open:      make(map[string]*domain.Candle),
+	}
+}
+
+// Timeframe returns the timeframe name of the bars.
+func (b *ThresholdBars) Timeframe() domain.Timeframe {
+	return b.timeframe
+}
+
+// Add feeds a tick and returns the bar it closed, if any.
+func (b *ThresholdBars) Add(symbol string, at time.Time, price, volume float64) []domain.Candle {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.open[symbol]
+	if !ok {
+		c = &domain.Candle{
+			Symbol:    symbol,
+			Timeframe: b.timeframe,
+			OpenTime:  at,
+			Open:      price,
+			High:      price,
+			Low:       price,
+		}
+		b.open[symbol] = c
+	}
+	if price > c.High {
+		c.High = price
+	}
+	if price < c.Low {
+		c.Low = price
+	}
+	c.Close = price
+	c.CloseTime = at
+	c.Volume += volume
+
+	if !b.closed(c) {
+		return nil
+	}
+	delete(b.open, symbol)
+	return []domain.Candle{*c}
+}
@@ -0,0 +1,126 @@
+package candle
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// Builder turns a stream of ticks into closed bars. The time-based Aggregator is a Builder,
+// as are the tick-driven Renko, range and volume bars.
+type Builder interface {
+	Add(symbol string, at time.Time, price, volume float64) []domain.Candle
+}
+
+// Transform turns the closed candles of a base timeframe into bars of another kind,
+// e.g. Heikin-Ashi candles or Renko bricks. The resulting bars carry their own Timeframe,
+// so the indicator and strategy layers treat them as just another timeframe.
+type Transform interface {
+	Base() domain.Timeframe
+	Apply(c domain.Candle) []domain.Candle
+}
+
+// NewBars returns the builder or the transform of a non-time timeframe (see domain.ParseBar):
+// a builder for bars built from the ticks, a transform for bars derived from a base timeframe.
+// The other one is nil.
+func NewBars(tf domain.Timeframe) (Builder, Transform, error) {
+	bar, err := domain.ParseBar(tf)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case bar.Kind == domain.HeikinAshiBars:
+		return nil, NewHeikinAshi(bar.Base), nil
+	case bar.Kind == domain.RangeBars:
+		return NewRangeBars(bar.Size), nil, nil
+	case bar.Kind == domain.VolumeBars:
+		return NewVolumeBars(bar.Size), nil, nil
+	case bar.Base == "":
+		return NewRenko("", bar.Size), nil, nil
+	case bar.ATRPeriod > 0:
+		return nil, NewATRRenko(bar.Base, bar.ATRPeriod), nil
+	default:
+		return nil, NewRenko(bar.Base, bar.Size), nil
+	}
+}
+
+// volumeTracker derives the traded volume of a tick from the rolling 24h volume of the tickers.
+type volumeTracker struct {
+	mu   sync.Mutex
+	last map[string]float64
+}
+
+// tick converts a ticker into a tick. The ticker only carries the rolling 24h volume, so the
+// traded volume is approximated by the increase of that volume since the previous ticker.
+func (v *volumeTracker) tick(t domain.Ticker) (time.Time, float64, float64) {
+	volume24h := t.Volume.Float64Value()
+
+	v.mu.Lock()
+	if v.last == nil {
+		v.last = make(map[string]float64)
+	}
+	prev, seen := v.last[t.Symbol]
+	v.last[t.Symbol] = volume24h
+	v.mu.Unlock()
+
+	var volume float64
+	if seen && volume24h > prev {
+		volume = volume24h - prev
+	}
+	return time.UnixMilli(t.EventTime).UTC(), t.LastPrice.Float64Value(), volume
+}
+
+// Feed converts tickers into ticks and passes them to several builders.
+type Feed struct {
+	volumes  volumeTracker
+	builders []Builder
+}
+
+// NewFeed creates a Feed for the given builders.
+func NewFeed(builders ...Builder) *Feed {
+	return &Feed{builders: builders}
+}
+
+// AddBuilder adds another builder to the feed.
+func (f *Feed) AddBuilder(b Builder) {
+	f.builders = append(f.builders, b)
+}
+
+// AddTicker adds a ticker to all builders and returns the bars it closed.
+func (f *Feed) AddTicker(t domain.Ticker) []domain.Candle {
+	at, price, volume := f.volumes.tick(t)
+	var closed []domain.Candle
+	for _, b := range f.builders {
+		closed = append(closed, b.Add(t.Symbol, at, price, volume)...)
+	}
+	return closed
+}
+
+// FromCandles replays candles through a tick builder. Each candle is replayed as the path
+// open, low, high, close for a rising candle (open, high, low, close for a falling one),
+// with the candle's volume assigned to the close.
+func FromCandles(b Builder, candles []domain.Candle) []domain.Candle {
+	var out []domain.Candle
+	for _, c := range candles {
+		path := []float64{c.Open, c.Low, c.High}
+		if c.Close < c.Open {
+			path = []float64{c.Open, c.High, c.Low}
+		}
+		step := c.CloseTime.Sub(c.OpenTime) / 4
+		for i, price := range path {
+			out = append(out, b.Add(c.Symbol, c.OpenTime.Add(time.Duration(i)*step), price, 0)...)
+		}
+		out = append(out, b.Add(c.Symbol, c.CloseTime, c.Close, c.Volume)...)
+	}
+	return out
+}
+
+// ApplyAll runs a transform over a series of candles.
+func ApplyAll(t Transform, candles []domain.Candle) []domain.Candle {
+	var out []domain.Candle
+	for _, c := range candles {
+		out = append(out, t.Apply(c)...)
+	}
+	return out
+}
@@ -0,0 +1,49 @@
+package candle
+
+import (
+	"math"
+	"sync"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// HeikinAshiTimeframe returns the timeframe name of Heikin-Ashi candles built on base, e.g. "ha:1h".
+func HeikinAshiTimeframe(base domain.Timeframe) domain.Timeframe {
+	return "ha:" + base
+}
+
+// HeikinAshi transforms the candles of a base timeframe into Heikin-Ashi candles.
+type HeikinAshi struct {
+	mu   sync.Mutex
+	base domain.Timeframe
+	prev map[string]domain.Candle
+}
+
+// NewHeikinAshi creates a Heikin-Ashi transform for the candles of base.
+func NewHeikinAshi(base domain.Timeframe) *HeikinAshi {
+	return &HeikinAshi{base: base, prev: make(map[string]domain.Candle)}
+}
+
+// Base returns the timeframe the transform is applied to.
+func (h *HeikinAshi) Base() domain.Timeframe {
+	return h.base
+}
+
+// Apply returns the Heikin-Ashi candle for the next candle of a symbol.
+func (h *HeikinAshi) Apply(c domain.Candle) []domain.Candle {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ha := c
+	ha.Timeframe = HeikinAshiTimeframe(c.Timeframe)
+	ha.Close = (c.Open + c.High + c.Low + c.Close) / 4
+	if prev, ok := h.prev[c.Symbol]; ok {
+		ha.Open = (prev.Open + prev.Close) / 2
+	} else {
+		ha.Open = (c.Open + c.Close) / 2
+	}
+	ha.High = math.Max(c.High, math.Max(ha.Open, ha.Close))
+	ha.Low = math.Min(c.Low, math.Min(ha.Open, ha.Close))
+	h.prev[c.Symbol] = ha
+	return []domain.Candle{ha}
+}
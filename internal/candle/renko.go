@@ -0,0 +1,160 @@
+package candle
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/indicator"
+)
+
+// renkoState is the brick series of one symbol.
+type renkoState struct {
+	started   bool
+	lastClose float64 // Close of the last brick, or the first price before any brick formed.
+	direction int     // +1 after an up brick, -1 after a down brick, 0 before the first brick.
+	openTime  time.Time
+	volume    float64
+
+	// ATR state for the ATR box size, updated from the base candles.
+	atr       float64
+	atrCount  int
+	atrSum    float64
+	prevClose float64
+}
+
+// Renko builds Renko bricks with a fixed box size, or with a box size equal to the ATR of the
+// base candles. A brick forms when the price moves one box beyond the last brick in the same
+// direction, or two boxes against it.
+type Renko struct {
+	mu        sync.Mutex
+	timeframe domain.Timeframe
+	base      domain.Timeframe
+	boxSize   float64
+	atrPeriod int
+	states    map[string]*renkoState
+}
+
+// NewRenko creates Renko bricks with a fixed box size. base is the timeframe of the candles the
+// bricks are built from when used as a Transform; it is ignored when fed with ticks.
+func NewRenko(base domain.Timeframe, boxSize float64) *Renko {
+	name := domain.Timeframe(fmt.Sprintf("renko:%g", boxSize))
+	if base != "" {
+		name = domain.Timeframe(fmt.Sprintf("renko:%s:%g", base, boxSize))
+	}
+	return &Renko{timeframe: name, base: base, boxSize: boxSize, states: make(map[string]*renkoState)}
+}
+
+// NewATRRenko creates Renko bricks on the candles of base, with the ATR over period candles as the
+// box size. No bricks form until the ATR has warmed up.
+func NewATRRenko(base domain.Timeframe, period int) *Renko {
+	return &Renko{
+		timeframe: domain.Timeframe(fmt.Sprintf("renko:%s:atr%d", base, period)),
+		base:      base,
+		atrPeriod: period,
+		states:    make(map[string]*renkoState),
+	}
+}
+
+// Timeframe returns the timeframe name of the bricks.
+func (r *Renko) Timeframe() domain.Timeframe {
+	return r.timeframe
+}
+
+// Base returns the timeframe the transform is applied to.
+func (r *Renko) Base() domain.Timeframe {
+	return r.base
+}
+
+// Apply feeds the close of a base candle and returns the bricks it completed.
+func (r *Renko) Apply(c domain.Candle) []domain.Candle {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := r.state(c.Symbol)
+	if r.atrPeriod > 0 {
+		r.updateATR(st, c)
+	}
+	return r.add(st, c.Symbol, c.CloseTime, c.Close, c.Volume)
+}
+
+// Add feeds a tick and returns the bricks it completed. Only fixed box sizes can be fed with ticks.
+func (r *Renko) Add(symbol string, at time.Time, price, volume float64) []domain.Candle {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.add(r.state(symbol), symbol, at, price, volume)
+}
+
+func (r *Renko) state(symbol string) *renkoState {
+	st, ok := r.states[symbol]
+	if !ok {
+		st = &renkoState{prevClose: math.NaN()}
+		r.states[symbol] = st
+	}
+	return st
+}
+
+func (r *Renko) updateATR(st *renkoState, c domain.Candle) {
+	tr := indicator.TrueRange(c, st.prevClose)
+	st.prevClose = c.Close
+	st.atrCount++
+	switch {
+	case st.atrCount < r.atrPeriod:
+		st.atrSum += tr
+	case st.atrCount == r.atrPeriod:
+		st.atr = (st.atrSum + tr) / float64(r.atrPeriod)
+	default:
+		st.atr = (st.atr*float64(r.atrPeriod-1) + tr) / float64(r.atrPeriod)
+	}
+}
+
+func (r *Renko) add(st *renkoState, symbol string, at time.Time, price, volume float64) []domain.Candle {
+	box := r.boxSize
+	if r.atrPeriod > 0 {
+		box = st.atr
+	}
+	st.volume += volume
+	if !st.started {
+		st.started, st.lastClose, st.openTime = true, price, at
+		return nil
+	}
+	if box <= 0 {
+		return nil
+	}
+
+	var bricks []domain.Candle
+	for {
+		var open, close float64
+		switch {
+		case price >= st.lastClose+box && st.direction >= 0:
+			open, close = st.lastClose, st.lastClose+box
+		case price <= st.lastClose-box && st.direction <= 0:
+			open, close = st.lastClose, st.lastClose-box
+		case price >= st.lastClose+2*box && st.direction < 0:
+			// Reversal: the new up brick starts at the top of the last down brick.
+			open, close = st.lastClose+box, st.lastClose+2*box
+		case price <= st.lastClose-2*box && st.direction > 0:
+			open, close = st.lastClose-box, st.lastClose-2*box
+		default:
+			return bricks
+		}
+		bricks = append(bricks, domain.Candle{
+			Symbol:    symbol,
+			Timeframe: r.timeframe,
+			OpenTime:  st.openTime,
+			CloseTime: at,
+			Open:      open,
+			High:      math.Max(open, close),
+			Low:       math.Min(open, close),
+			Close:     close,
+			Volume:    st.volume,
+		})
+		if close > open {
+			st.direction = 1
+		} else {
+			st.direction = -1
+		}
+		st.lastClose, st.openTime, st.volume = close, at, 0
+	}
+}
@@ -0,0 +1,114 @@
+// Package cli holds the flag handling shared by the commands that work on stored history: the
+// backtest, optimize, audit and trend test commands.
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+)
+
+// ParsePeriod parses the -from and -to flags; -from is required and -to defaults to now.
+func ParsePeriod(fromFlag, toFlag string) (time.Time, time.Time, error) {
+	if fromFlag == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("-from is required")
+	}
+	from, err := ParseTime(fromFlag)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to := time.Now().UTC()
+	if toFlag != "" {
+		if to, err = ParseTime(toFlag); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("-to must be after -from")
+	}
+	return from, to, nil
+}
+
+// ParseTime parses a date (YYYY-MM-DD) or an RFC3339 time.
+func ParseTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// FillModel builds the fill model of the fill flags: the fees of a Binance spot tier, or none,
+// a fixed slippage or one of a multiple of the ATR, and optionally fills through the stored order
+// books.
+func FillModel(tierName string, slippage, slippageATR float64, depth bool) (backtest.FillModel, error) {
+	var tier backtest.FeeTier
+	if tierName != "none" {
+		var ok bool
+		if tier, ok = backtest.FeeTierByName(tierName); !ok {
+			return nil, fmt.Errorf("unknown fee tier %q", tierName)
+		}
+	}
+	cost := backtest.CostFill{Fees: tier}
+	switch {
+	case slippageATR > 0:
+		cost.Slippage = backtest.VolatilitySlippage{Factor: slippageATR, Period: 14}
+	case slippage > 0:
+		cost.Slippage = backtest.FixedSlippage(slippage)
+	}
+	if depth {
+		return backtest.DepthFill{Fees: tier, Fallback: cost}, nil
+	}
+	return cost, nil
+}
+
+// WriteFile creates a file and writes it with write.
+func WriteFile(path string, write func(f *os.File) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// DefinitionHash returns the SHA-256 of a strategy definition file, or of the default configuration
+// of a built-in strategy.
+func DefinitionHash(name string) (string, error) {
+	var data []byte
+	var err error
+	switch name {
+	case "stoch-bb":
+		data, err = json.Marshal(strategy.DefaultStochBBConfig())
+	case "rsi-divergence":
+		data, err = json.Marshal(strategy.DefaultRSIDivergenceConfig())
+	default:
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(name+"\n"), data...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// RunParams returns the settings of a stored run: the values of the flags, but for those in skip,
+// which are stored in their own columns of the run or do not change its results.
+func RunParams(skip map[string]bool) map[string]string {
+	params := map[string]string{}
+	flag.VisitAll(func(f *flag.Flag) {
+		if !skip[f.Name] {
+			params[f.Name] = f.Value.String()
+		}
+	})
+	return params
+}
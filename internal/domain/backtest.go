@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// BacktestRun is a stored backtest run: what was tested, on which data, by which version of the
+// engine, and what came out of it.
+type BacktestRun struct {
+	ID        int64
+	CreatedAt time.Time
+	// Strategy is the name of a built-in strategy or the path of a strategy definition file;
+	// DefinitionHash identifies its exact configuration.
+	Strategy       string
+	DefinitionHash string
+	// Params are the settings of the run, e.g. the fee tier or the balance, by name.
+	Params        map[string]string
+	Symbol        string
+	Timeframe     Timeframe
+	From, To      time.Time
+	EngineVersion string
+	// Metrics and Trades are the JSON documents of the metrics and the trades of the backtest report.
+	Metrics json.RawMessage
+	Trades  json.RawMessage
+}
@@ -0,0 +1,178 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timeframe identifies the bar interval a candle was built for, e.g. "1m" or "1h".
+// Non-time bars (Heikin-Ashi, Renko, ...) use descriptive names and have no fixed duration.
+type Timeframe string
+
+const (
+	Minute1  Timeframe = "1m"
+	Minute5  Timeframe = "5m"
+	Minute15 Timeframe = "15m"
+	Minute30 Timeframe = "30m"
+	Hour1    Timeframe = "1h"
+	Hour4    Timeframe = "4h"
+	Day1     Timeframe = "1d"
+)
+
+// timeframeDurations maps the supported time-based timeframes to their length.
+var timeframeDurations = map[Timeframe]time.Duration{
+	Minute1:  time.Minute,
+	Minute5:  5 * time.Minute,
+	Minute15: 15 * time.Minute,
+	Minute30: 30 * time.Minute,
+	Hour1:    time.Hour,
+	Hour4:    4 * time.Hour,
+	Day1:     24 * time.Hour,
+}
+
+// ParseTimeframe accepts both the short notation ("15m") and the notation used in
+// the feature files ("15 minute", "1 hour"), and the names of the non-time bars (see ParseBar).
+func ParseTimeframe(s string) (Timeframe, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if _, ok := timeframeDurations[Timeframe(s)]; ok {
+		return Timeframe(s), nil
+	}
+	if strings.Contains(s, ":") {
+		bar, err := ParseBar(Timeframe(s))
+		if err != nil {
+			return "", err
+		}
+		return bar.Timeframe(), nil
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 2 {
+		unit := strings.TrimSuffix(fields[1], "s")
+		switch unit {
+		case "minute", "min":
+			unit = "m"
+		case "hour":
+			unit = "h"
+		case "day":
+			unit = "d"
+		}
+		tf := Timeframe(fields[0] + unit)
+		if _, ok := timeframeDurations[tf]; ok {
+			return tf, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported timeframe %q", s)
+}
+
+// The kinds of non-time bars.
+const (
+	HeikinAshiBars = "ha"
+	RenkoBars      = "renko"
+	RangeBars      = "range"
+	VolumeBars     = "volume"
+)
+
+// Bar describes a non-time timeframe: bars built from the ticks, or derived from the candles of a
+// time-based base timeframe.
+type Bar struct {
+	Kind string
+	// Base is the timeframe the bars are derived from; empty for bars built from the ticks.
+	Base Timeframe
+	// Size is the box size of Renko bricks, or the range or volume that closes a bar.
+	Size float64
+	// ATRPeriod sizes the Renko bricks on the ATR of the base candles instead of on Size.
+	ATRPeriod int
+}
+
+// ParseBar parses the name of a non-time timeframe: "ha:<base>", "renko:<size>",
+// "renko:<base>:<size>", "renko:<base>:atr<period>", "range:<size>" or "volume:<size>".
+func ParseBar(tf Timeframe) (Bar, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(string(tf))), ":")
+	bar := Bar{Kind: parts[0]}
+	var err error
+	switch {
+	case bar.Kind == HeikinAshiBars && len(parts) == 2:
+		bar.Base, err = parseBase(parts[1])
+	case bar.Kind == RenkoBars && len(parts) == 3:
+		if bar.Base, err = parseBase(parts[1]); err != nil {
+			break
+		}
+		if period, ok := strings.CutPrefix(parts[2], "atr"); ok {
+			bar.ATRPeriod, err = strconv.Atoi(period)
+			if err == nil && bar.ATRPeriod <= 0 {
+				err = fmt.Errorf("the ATR period must be positive")
+			}
+			break
+		}
+		bar.Size, err = parseSize(parts[2])
+	case (bar.Kind == RenkoBars || bar.Kind == RangeBars || bar.Kind == VolumeBars) && len(parts) == 2:
+		bar.Size, err = parseSize(parts[1])
+	default:
+		err = fmt.Errorf("unknown kind of bars")
+	}
+	if err != nil {
+		return Bar{}, fmt.Errorf("unsupported timeframe %q: %v", tf, err)
+	}
+	return bar, nil
+}
+
+// parseBase parses the time-based timeframe non-time bars are derived from.
+func parseBase(s string) (Timeframe, error) {
+	if _, ok := timeframeDurations[Timeframe(s)]; !ok {
+		return "", fmt.Errorf("the base timeframe must be one of the time-based timeframes, got %q", s)
+	}
+	return Timeframe(s), nil
+}
+
+// parseSize parses the box size or threshold of non-time bars.
+func parseSize(s string) (float64, error) {
+	size, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(size > 0) || math.IsInf(size, 0) {
+		return 0, fmt.Errorf("the size must be a positive number, got %q", s)
+	}
+	return size, nil
+}
+
+// Timeframe returns the timeframe name of the bars, as the candle package names them.
+func (b Bar) Timeframe() Timeframe {
+	switch {
+	case b.Kind == HeikinAshiBars:
+		return Timeframe(fmt.Sprintf("ha:%s", b.Base))
+	case b.Base == "":
+		return Timeframe(fmt.Sprintf("%s:%g", b.Kind, b.Size))
+	case b.ATRPeriod > 0:
+		return Timeframe(fmt.Sprintf("%s:%s:atr%d", b.Kind, b.Base, b.ATRPeriod))
+	default:
+		return Timeframe(fmt.Sprintf("%s:%s:%g", b.Kind, b.Base, b.Size))
+	}
+}
+
+// Duration returns the length of a time-based timeframe, or 0 for non-time bars.
+func (tf Timeframe) Duration() time.Duration {
+	return timeframeDurations[tf]
+}
+
+// Truncate returns the open time of the candle of this timeframe that contains t.
+func (tf Timeframe) Truncate(t time.Time) time.Time {
+	d := tf.Duration()
+	if d == 0 {
+		return t
+	}
+	return t.UTC().Truncate(d)
+}
+
+// Candle is an OHLCV bar for a single symbol and timeframe.
+// Prices are float64: candles feed the analysis layer, where big.Float precision is not required.
+type Candle struct {
+	Symbol    string
+	Timeframe Timeframe
+	OpenTime  time.Time
+	CloseTime time.Time
+	Open      float64
+	High      float64
+	Low       float64
+	Close     float64
+	Volume    float64
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// OpportunityStatus is the lifecycle state of a trading opportunity.
+type OpportunityStatus string
+
+const (
+	// OpportunityNew is an opportunity the user has not looked at yet.
+	OpportunityNew OpportunityStatus = "new"
+	// OpportunitySeen is an opportunity the user has opened.
+	OpportunitySeen OpportunityStatus = "seen"
+	// OpportunityActedOn is an opportunity the user traded on.
+	OpportunityActedOn OpportunityStatus = "acted_on"
+	// OpportunityExpired is an opportunity that is too old to act on.
+	OpportunityExpired OpportunityStatus = "expired"
+)
+
+// WGHMNone is the WGHM state without a WGHM signal, as the card shows it.
+const WGHMNone = "None - Neutral"
+
+// opportunityTransitions lists the statuses each status may change to.
+var opportunityTransitions = map[OpportunityStatus][]OpportunityStatus{
+	OpportunityNew:  {OpportunitySeen, OpportunityActedOn, OpportunityExpired},
+	OpportunitySeen: {OpportunityActedOn, OpportunityExpired},
+}
+
+// EntryMax is the largest position that can be entered for an opportunity, in both assets of the pair.
+type EntryMax struct {
+	Base  float64
+	Quote float64
+}
+
+// Opportunity is an entry of the Trading Opportunities list: a signal together with the market
+// context it fired in, and where the user is in handling it.
+type Opportunity struct {
+	ID     int64
+	Signal Signal
+	// EntryMax is zero when it could not be calculated.
+	EntryMax   EntryMax
+	ChartTrend Trend
+	// MarketExchange, MarketTrend and MarketBreadth describe the market trend when the signal fired;
+	// MarketBreadth is the percentage shown on the card.
+	MarketExchange string
+	MarketTrend    Trend
+	MarketBreadth  float64
+	// WGHM is the description of the WGHM indicator state, e.g. "None - Neutral".
+	WGHM   string
+	Status OpportunityStatus
+	// Age is the number of candles of the signal timeframe that closed after the signal candle.
+	Age int
+	// ExpireAfter is the age at which the opportunity expires; zero means it never does.
+	ExpireAfter int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Open reports whether the opportunity can still be acted on.
+func (o Opportunity) Open() bool {
+	return o.Status == OpportunityNew || o.Status == OpportunitySeen
+}
+
+// Transition changes the status of the opportunity, rejecting changes the lifecycle does not allow:
+// new → seen → acted on, where a new or seen opportunity can also expire.
+func (o *Opportunity) Transition(to OpportunityStatus, at time.Time) error {
+	if o.Status == to {
+		return nil
+	}
+	for _, allowed := range opportunityTransitions[o.Status] {
+		if allowed == to {
+			o.Status = to
+			o.UpdatedAt = at
+			return nil
+		}
+	}
+	return fmt.Errorf("opportunity %d cannot change from %s to %s", o.ID, o.Status, to)
+}
+
+// Tick ages an open opportunity by one closed candle and expires it when it reached its age limit.
+// It reports whether the opportunity expired.
+func (o *Opportunity) Tick(at time.Time) bool {
+	if !o.Open() {
+		return false
+	}
+	o.Age++
+	o.UpdatedAt = at
+	if o.ExpireAfter > 0 && o.Age >= o.ExpireAfter {
+		o.Status = OpportunityExpired
+		return true
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// PriceLevel is the total quantity, in the base asset, offered at one price of an order book.
+type PriceLevel struct {
+	Price    float64
+	Quantity float64
+}
+
+// OrderBook is a snapshot of the best levels of the order book of a pair.
+// Bids are ordered from the highest price down, asks from the lowest price up.
+type OrderBook struct {
+	Symbol string
+	Bids   []PriceLevel
+	Asks   []PriceLevel
+	Time   time.Time
+}
+
+// Fill walks the side of the book a market order in the given direction takes from, a buy taking
+// the asks and a sell the bids, and returns how much of maxQuantity can be filled without paying
+// more than slippage percent beyond the best price, with its cost in the quote asset.
+// A maxQuantity of zero or less means no limit.
+func (b OrderBook) Fill(d Direction, maxQuantity, slippage float64) (base, quote float64) {
+	levels, limit := b.Asks, 1+slippage/100
+	if d == Sell {
+		levels, limit = b.Bids, 1-slippage/100
+	}
+	if len(levels) == 0 {
+		return 0, 0
+	}
+	limit *= levels[0].Price
+	for _, l := range levels {
+		if (d == Sell && l.Price < limit) || (d != Sell && l.Price > limit) {
+			break
+		}
+		q := l.Quantity
+		if maxQuantity > 0 {
+			q = math.Min(q, maxQuantity-base)
+		}
+		base += q
+		quote += q * l.Price
+		if maxQuantity > 0 && base >= maxQuantity {
+			break
+		}
+	}
+	return base, quote
+}
@@ -0,0 +1,61 @@
+package domain
+
+import "math"
+
+// SizeKind determines how the position size of a trade plan is calculated.
+type SizeKind string
+
+const (
+	// SizeRiskPercent sizes the position so that hitting the stop-loss loses Value percent of the balance.
+	SizeRiskPercent SizeKind = "risk_percent"
+	// SizeBalancePercent spends Value percent of the balance.
+	SizeBalancePercent SizeKind = "balance_percent"
+	// SizeFixedQuote spends a fixed amount of Value in the quote asset.
+	SizeFixedQuote SizeKind = "fixed_quote"
+)
+
+// SizeRule is the rule for the position size of a trade plan.
+type SizeRule struct {
+	Kind  SizeKind
+	Value float64
+}
+
+// TradePlan is the trade a strategy suggests together with a signal. It is used to pre-fill
+// the trade interface when the user selects an opportunity.
+type TradePlan struct {
+	Entry      float64
+	StopLoss   float64
+	TakeProfit float64
+	Size       SizeRule
+}
+
+// Quantity returns the position size in the base asset for a balance in the quote asset.
+func (p TradePlan) Quantity(balance float64) float64 {
+	if p.Entry <= 0 {
+		return 0
+	}
+	switch p.Size.Kind {
+	case SizeRiskPercent:
+		risk := math.Abs(p.Entry - p.StopLoss)
+		if risk == 0 {
+			return 0
+		}
+		// Never spend more than the balance, however tight the stop.
+		return math.Min(balance*p.Size.Value/100/risk, balance/p.Entry)
+	case SizeBalancePercent:
+		return balance * p.Size.Value / 100 / p.Entry
+	case SizeFixedQuote:
+		return math.Min(p.Size.Value, balance) / p.Entry
+	default:
+		return 0
+	}
+}
+
+// RewardRisk returns the ratio between the distance to the take-profit and the distance to the stop-loss.
+func (p TradePlan) RewardRisk() float64 {
+	risk := math.Abs(p.Entry - p.StopLoss)
+	if risk == 0 {
+		return 0
+	}
+	return math.Abs(p.TakeProfit-p.Entry) / risk
+}
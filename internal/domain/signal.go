@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// Direction is the side of a trading signal.
+type Direction string
+
+const (
+	Buy  Direction = "buy"
+	Sell Direction = "sell"
+)
+
+// Signal is emitted by a strategy when its conditions for a possible buy or sell moment are met.
+type Signal struct {
+	ID        int64
+	Strategy  string
+	Exchange  string
+	Symbol    string
+	Timeframe Timeframe
+	Direction Direction
+	Time      time.Time
+	Price     float64
+	Metrics   SignalMetrics
+	// Plan is the trade the strategy suggests, or nil when it does not publish one.
+	Plan *TradePlan
+}
+
+// SignalMetrics is a snapshot of the market at the moment a signal fired.
+// It is stored together with the signal so an opportunity card can be rendered later
+// exactly as it looked at that time.
+type SignalMetrics struct {
+	CandleTime time.Time
+	BBWidth    float64 // Bollinger Band width as a percentage of the middle band.
+	StochK     float64
+	StochD     float64
+	Volume24h  float64 // 24h volume in the quote asset.
+	Change24h  float64 // 24h price change in percent.
+}
+
+// Moment returns the description of the action a signal suggests, as shown on the opportunity cards.
+func (d Direction) Moment() string {
+	switch d {
+	case Buy:
+		return "Possible buy moment"
+	case Sell:
+		return "Possible sell moment"
+	default:
+		return "Unknown moment"
+	}
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "math"
+
+// SymbolInfo holds the assets of a pair and the filters the exchange applies to its orders.
+// A filter of zero is not applied.
+type SymbolInfo struct {
+	Symbol     string
+	BaseAsset  string
+	QuoteAsset string
+	// StepSize is the increment of the order quantity; MinQuantity and MaxQuantity bound it.
+	StepSize    float64
+	MinQuantity float64
+	MaxQuantity float64
+	// MinNotional is the minimum order value in the quote asset.
+	MinNotional float64
+}
+
+// Quantity rounds a quantity down to the step size and caps it at the maximum quantity.
+// It returns zero when the result is below the minimum quantity or, at price, the minimum notional.
+func (s SymbolInfo) Quantity(quantity, price float64) float64 {
+	if s.MaxQuantity > 0 {
+		quantity = math.Min(quantity, s.MaxQuantity)
+	}
+	if s.StepSize > 0 {
+		// The small tolerance keeps quantities that are a whole number of steps from losing one
+		// to floating-point error.
+		quantity = math.Floor(quantity/s.StepSize+1e-9) * s.StepSize
+	}
+	if quantity <= 0 || quantity < s.MinQuantity || quantity*price < s.MinNotional {
+		return 0
+	}
+	return quantity
+}
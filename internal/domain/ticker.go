@@ -8,14 +8,16 @@ import (
 // Ticker represents a generic 24-hour ticker update.
 // This is the primary data structure used within the application's core logic.
 type Ticker struct {
-	EventType string
-	EventTime int64
-	Symbol    string
-	LastPrice BigString
-	Volume    BigString
-	OpenTime  int64
-	CloseTime int64
-	Count     int64
+	EventType          string
+	EventTime          int64
+	Symbol             string
+	LastPrice          BigString
+	Volume             BigString
+	QuoteVolume        BigString
+	PriceChangePercent BigString
+	OpenTime           int64
+	CloseTime          int64
+	Count              int64
 }
 
 // BigString is a custom type for handling high-precision numbers from JSON strings.
@@ -23,6 +25,15 @@ type BigString struct {
 	*big.Float
 }
 
+// Float64Value returns the value as a float64, or 0 when no value was set.
+func (b BigString) Float64Value() float64 {
+	if b.Float == nil {
+		return 0
+	}
+	f, _ := b.Float.Float64()
+	return f
+}
+
 func (b *BigString) UnmarshalJSON(data []byte) error {
 	if string(data) == "null" {
 		return &json.UnmarshalTypeError{}
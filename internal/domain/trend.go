@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+// Trend is the direction of a chart or of the market as a whole.
+type Trend string
+
+const (
+	Bullish Trend = "bullish"
+	Bearish Trend = "bearish"
+	Neutral Trend = "neutral"
+)
+
+// ChartTrend is the trend of one symbol on one timeframe, together with the
+// calculator state needed to continue from the next closed candle.
+type ChartTrend struct {
+	Symbol     string
+	Timeframe  Timeframe
+	Trend      Trend
+	Strength   float64 // Distance between the fast and slow EMA, in percent of the slow EMA.
+	FastEMA    float64
+	SlowEMA    float64
+	Samples    int
+	CandleTime time.Time // Close time of the last candle included in the calculation.
+}
+
+// MarketTrend is the breadth of all pairs of an exchange over one timeframe period.
+type MarketTrend struct {
+	Exchange  string
+	Timeframe Timeframe
+	Time      time.Time // End of the period the breadth was measured over.
+	Pairs     int
+	Advancing int
+	Declining int
+	// Breadth is the percentage of advancing pairs minus the percentage of declining pairs.
+	Breadth float64
+	// VolumeWeightedChange is the average price change in percent, weighted by 24h quote volume.
+	VolumeWeightedChange float64
+	Trend                Trend
+}
+
+// AdvancingPct returns the percentage of pairs that went up.
+func (m MarketTrend) AdvancingPct() float64 {
+	if m.Pairs == 0 {
+		return 0
+	}
+	return float64(m.Advancing) / float64(m.Pairs) * 100
+}
+
+// DecliningPct returns the percentage of pairs that went down.
+func (m MarketTrend) DecliningPct() float64 {
+	if m.Pairs == 0 {
+		return 0
+	}
+	return float64(m.Declining) / float64(m.Pairs) * 100
+}
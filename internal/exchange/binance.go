@@ -14,27 +14,31 @@ import (
 
 // binanceTicker represents the raw data structure from the Binance API.
 type binanceTicker struct {
-	EventType string           `json:"e"`
-	EventTime int64            `json:"E"`
-	Symbol    string           `json:"s"`
-	LastPrice domain.BigString `json:"c"`
-	Volume    domain.BigString `json:"v"`
-	OpenTime  int64            `json:"O"`
-	CloseTime int64            `json:"C"`
-	Count     int64            `json:"n"`
+	EventType          string           `json:"e"`
+	EventTime          int64            `json:"E"`
+	Symbol             string           `json:"s"`
+	LastPrice          domain.BigString `json:"c"`
+	Volume             domain.BigString `json:"v"`
+	QuoteVolume        domain.BigString `json:"q"`
+	PriceChangePercent domain.BigString `json:"P"`
+	OpenTime           int64            `json:"O"`
+	CloseTime          int64            `json:"C"`
+	Count              int64            `json:"n"`
 }
 
 // toDomain converts a Binance-specific ticker to the application's generic domain.Ticker.
 func (bt binanceTicker) toDomain() domain.Ticker {
 	return domain.Ticker{
-		EventType: bt.EventType,
-		EventTime: bt.EventTime,
-		Symbol:    bt.Symbol,
-		LastPrice: bt.LastPrice,
-		Volume:    bt.Volume,
-		OpenTime:  bt.OpenTime,
-		CloseTime: bt.CloseTime,
-		Count:     bt.Count,
+		EventType:          bt.EventType,
+		EventTime:          bt.EventTime,
+		Symbol:             bt.Symbol,
+		LastPrice:          bt.LastPrice,
+		Volume:             bt.Volume,
+		QuoteVolume:        bt.QuoteVolume,
+		PriceChangePercent: bt.PriceChangePercent,
+		OpenTime:           bt.OpenTime,
+		CloseTime:          bt.CloseTime,
+		Count:              bt.Count,
 	}
 }
 
@@ -45,9 +49,19 @@ type BinanceStreamer struct {
 
 // NewBinanceStreamer creates a new streamer connected to Binance.
 func NewBinanceStreamer(ctx context.Context, url string) (*BinanceStreamer, error) {
+	c, err := dialBinance(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return &BinanceStreamer{conn: c}, nil
+}
+
+// dialBinance opens a websocket connection to a Binance stream.
+func dialBinance(ctx context.Context, url string) (*websocket.Conn, error) {
 	log.Printf("Connecting to %s", url)
 
-	dialer := websocket.DefaultDialer
+	// A copy, as the depth streams of several pairs dial at once and must not share the default.
+	dialer := *websocket.DefaultDialer
 	dialer.HandshakeTimeout = 10 * time.Second
 
 	c, resp, err := dialer.DialContext(ctx, url, nil)
@@ -60,7 +74,7 @@ func NewBinanceStreamer(ctx context.Context, url string) (*BinanceStreamer, erro
 	if resp != nil {
 		log.Printf("WebSocket connected with status: %s", resp.Status)
 	}
-	return &BinanceStreamer{conn: c}, nil
+	return c, nil
 }
 
 // Stream starts listening to the websocket and sends tickers to a channel.
@@ -0,0 +1,191 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/gorilla/websocket"
+)
+
+// BinanceAPIURL is the base URL of the Binance REST API.
+const BinanceAPIURL = "https://api.binance.com"
+
+// BinanceDepthURL returns the stream with the 20 best order book levels of a pair, every 100ms.
+func BinanceDepthURL(symbol string) string {
+	return "wss://stream.binance.com:9443/ws/" + strings.ToLower(symbol) + "@depth20@100ms"
+}
+
+// binanceDepth represents a partial order book message of Binance; levels are [price, quantity] pairs.
+type binanceDepth struct {
+	LastUpdateID int64       `json:"lastUpdateId"`
+	Bids         [][2]string `json:"bids"`
+	Asks         [][2]string `json:"asks"`
+}
+
+// toDomain converts a Binance order book message to a domain.OrderBook.
+func (bd binanceDepth) toDomain(symbol string, at time.Time) (domain.OrderBook, error) {
+	book := domain.OrderBook{Symbol: symbol, Time: at}
+	var err error
+	if book.Bids, err = parseLevels(bd.Bids); err != nil {
+		return book, fmt.Errorf("could not parse bids: %w", err)
+	}
+	if book.Asks, err = parseLevels(bd.Asks); err != nil {
+		return book, fmt.Errorf("could not parse asks: %w", err)
+	}
+	return book, nil
+}
+
+func parseLevels(raw [][2]string) ([]domain.PriceLevel, error) {
+	levels := make([]domain.PriceLevel, len(raw))
+	for i, r := range raw {
+		price, err := strconv.ParseFloat(r[0], 64)
+		if err != nil {
+			return nil, err
+		}
+		quantity, err := strconv.ParseFloat(r[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		levels[i] = domain.PriceLevel{Price: price, Quantity: quantity}
+	}
+	return levels, nil
+}
+
+// BinanceDepthStreamer implements the DepthStreamer interface for the Binance partial book depth stream.
+type BinanceDepthStreamer struct {
+	conn *websocket.Conn
+}
+
+// NewBinanceDepthStreamer creates a new order book streamer connected to Binance.
+func NewBinanceDepthStreamer(ctx context.Context, url string) (*BinanceDepthStreamer, error) {
+	c, err := dialBinance(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return &BinanceDepthStreamer{conn: c}, nil
+}
+
+// StreamDepth starts listening to the websocket and sends each order book snapshot to a channel.
+// The stream of a connection carries a single pair; symbol is the name its books get.
+func (s *BinanceDepthStreamer) StreamDepth(ctx context.Context, symbol string) (<-chan domain.OrderBook, <-chan error) {
+	bookChan := make(chan domain.OrderBook, 10)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Recovered from panic in websocket read: %v", r)
+			}
+			close(bookChan)
+			close(errChan)
+			s.conn.Close()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Printf("Context cancelled, closing depth websocket")
+				s.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			default:
+			}
+
+			s.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, message, err := s.conn.ReadMessage()
+			if err != nil {
+				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+					continue // It's a read timeout, just loop again to check context.
+				}
+				errChan <- err // Report other errors.
+				return
+			}
+
+			var raw binanceDepth
+			if err := json.Unmarshal(message, &raw); err != nil {
+				log.Printf("Warning: could not unmarshal depth message: %v", err)
+				continue
+			}
+			book, err := raw.toDomain(symbol, time.Now())
+			if err != nil {
+				log.Printf("Warning: %v", err)
+				continue
+			}
+			select {
+			case bookChan <- book:
+			case <-ctx.Done():
+				// The reader stopped with the context, e.g. when a selected pair left the universe.
+			}
+		}
+	}()
+
+	return bookChan, errChan
+}
+
+// binanceExchangeInfo represents the part of the Binance exchangeInfo response with the symbol filters.
+type binanceExchangeInfo struct {
+	Symbols []struct {
+		Symbol     string `json:"symbol"`
+		Status     string `json:"status"`
+		BaseAsset  string `json:"baseAsset"`
+		QuoteAsset string `json:"quoteAsset"`
+		Filters    []struct {
+			FilterType  string `json:"filterType"`
+			MinQty      string `json:"minQty"`
+			MaxQty      string `json:"maxQty"`
+			StepSize    string `json:"stepSize"`
+			MinNotional string `json:"minNotional"`
+		} `json:"filters"`
+	} `json:"symbols"`
+}
+
+// FetchBinanceSymbols loads the assets and order filters of all trading pairs from the exchangeInfo
+// endpoint of the REST API at baseURL, e.g. BinanceAPIURL.
+func FetchBinanceSymbols(ctx context.Context, baseURL string) (map[string]domain.SymbolInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/api/v3/exchangeInfo", nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create exchange info request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch exchange info: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch exchange info: status %s", resp.Status)
+	}
+
+	var raw binanceExchangeInfo
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("could not decode exchange info: %w", err)
+	}
+	// Filters that are missing or do not parse are left at zero, which means they are not applied.
+	num := func(s string) float64 {
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	}
+	symbols := make(map[string]domain.SymbolInfo, len(raw.Symbols))
+	for _, s := range raw.Symbols {
+		if s.Status != "TRADING" {
+			continue
+		}
+		info := domain.SymbolInfo{Symbol: s.Symbol, BaseAsset: s.BaseAsset, QuoteAsset: s.QuoteAsset}
+		for _, f := range s.Filters {
+			switch f.FilterType {
+			case "LOT_SIZE":
+				info.StepSize, info.MinQuantity, info.MaxQuantity = num(f.StepSize), num(f.MinQty), num(f.MaxQty)
+			case "NOTIONAL", "MIN_NOTIONAL":
+				info.MinNotional = num(f.MinNotional)
+			}
+		}
+		symbols[s.Symbol] = info
+	}
+	return symbols, nil
+}
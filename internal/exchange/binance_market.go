@@ -0,0 +1,89 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/gorilla/websocket"
+)
+
+// BinanceMarketURL is the all-market ticker stream of Binance.
+const BinanceMarketURL = "wss://stream.binance.com:9443/ws/!ticker@arr"
+
+// BinanceMarketStreamer implements the MarketStreamer interface for the Binance all-market ticker stream.
+type BinanceMarketStreamer struct {
+	conn *websocket.Conn
+}
+
+// NewBinanceMarketStreamer creates a new all-market streamer connected to Binance.
+func NewBinanceMarketStreamer(ctx context.Context, url string) (*BinanceMarketStreamer, error) {
+	c, err := dialBinance(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return &BinanceMarketStreamer{conn: c}, nil
+}
+
+// Name returns the abbreviation of the exchange, as shown on the opportunity cards.
+func (s *BinanceMarketStreamer) Name() string {
+	return "BINANCE"
+}
+
+// StreamMarket starts listening to the websocket and sends each batch of tickers to a channel.
+func (s *BinanceMarketStreamer) StreamMarket(ctx context.Context) (<-chan []domain.Ticker, <-chan error) {
+	batchChan := make(chan []domain.Ticker, 10)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Recovered from panic in websocket read: %v", r)
+			}
+			close(batchChan)
+			close(errChan)
+			s.conn.Close()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Printf("Context cancelled, closing market websocket")
+				s.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			default:
+			}
+
+			s.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, message, err := s.conn.ReadMessage()
+			if err != nil {
+				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+					continue // It's a read timeout, just loop again to check context.
+				}
+				errChan <- err // Report other errors.
+				return
+			}
+
+			var rawTickers []binanceTicker
+			if err := json.Unmarshal(message, &rawTickers); err != nil {
+				log.Printf("Warning: could not unmarshal market message: %v", err)
+				continue
+			}
+
+			batch := make([]domain.Ticker, len(rawTickers))
+			for i, rt := range rawTickers {
+				batch[i] = rt.toDomain()
+			}
+			select {
+			case batchChan <- batch:
+			case <-ctx.Done():
+				// The consumer stopped with the context; the check above closes the websocket.
+			}
+		}
+	}()
+
+	return batchChan, errChan
+}
@@ -10,3 +10,15 @@ type Streamer interface {
 	Stream(ctx context.Context, symbol string) (<-chan domain.Ticker, <-chan error)
 }
 
+// MarketStreamer defines the interface for the all-market ticker feed of an exchange.
+// Every message is a batch with the tickers of all pairs that changed since the previous batch.
+type MarketStreamer interface {
+	Name() string
+	StreamMarket(ctx context.Context) (<-chan []domain.Ticker, <-chan error)
+}
+
+// DepthStreamer defines the interface for the order book stream of a pair.
+// Every message is a snapshot of the best levels of the book.
+type DepthStreamer interface {
+	StreamDepth(ctx context.Context, symbol string) (<-chan domain.OrderBook, <-chan error)
+}
@@ -0,0 +1,41 @@
+package indicator
+
+import (
+	"math"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// TrueRange returns the true range of a candle given the previous close.
+func TrueRange(c domain.Candle, prevClose float64) float64 {
+	tr := c.High - c.Low
+	if !math.IsNaN(prevClose) {
+		tr = math.Max(tr, math.Max(math.Abs(c.High-prevClose), math.Abs(c.Low-prevClose)))
+	}
+	return tr
+}
+
+// ATR calculates the Average True Range with Wilder's smoothing.
+func ATR(candles []domain.Candle, period int) []float64 {
+	out := nanSeries(len(candles))
+	if period <= 0 || len(candles) < period {
+		return out
+	}
+	prevClose := math.NaN()
+	var sum, atr float64
+	for i, c := range candles {
+		tr := TrueRange(c, prevClose)
+		prevClose = c.Close
+		switch {
+		case i < period-1:
+			sum += tr
+		case i == period-1:
+			atr = (sum + tr) / float64(period)
+			out[i] = atr
+		default:
+			atr = (atr*float64(period-1) + tr) / float64(period)
+			out[i] = atr
+		}
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+// Package indicator implements the technical indicators used by the strategies.
+//
+// Indicators work on whole series and return a slice aligned with the input.
+// Positions that are still inside the warm-up period are set to NaN.
+package indicator
+
+import (
+	"math"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// Closes extracts the closing prices of the candles.
+func Closes(candles []domain.Candle) []float64 {
+	out := make([]float64, len(candles))
+	for i, c := range candles {
+		out[i] = c.Close
+	}
+	return out
+}
+
+// nanSeries returns a series of length n filled with NaN.
+func nanSeries(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
+
+// SMA calculates the simple moving average over period values.
+// A window containing a NaN yields NaN, so SMAs can be chained on other indicators.
+func SMA(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if period <= 0 {
+		return out
+	}
+	var sum float64
+	nans := 0
+	for i, v := range values {
+		if math.IsNaN(v) {
+			nans++
+		} else {
+			sum += v
+		}
+		if i >= period {
+			old := values[i-period]
+			if math.IsNaN(old) {
+				nans--
+			} else {
+				sum -= old
+			}
+		}
+		if i >= period-1 && nans == 0 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+// EMA calculates the exponential moving average, seeded with the SMA of the first period values.
+func EMA(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if period <= 0 {
+		return out
+	}
+	k := 2 / float64(period+1)
+	prev := math.NaN()
+	seed := SMA(values, period)
+	for i, v := range values {
+		switch {
+		case math.IsNaN(prev):
+			prev = seed[i]
+		case math.IsNaN(v):
+			continue
+		default:
+			prev = v*k + prev*(1-k)
+		}
+		out[i] = prev
+	}
+	return out
+}
+
+// StdDev calculates the population standard deviation over period values.
+func StdDev(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	mean := SMA(values, period)
+	for i := range values {
+		if math.IsNaN(mean[i]) {
+			continue
+		}
+		var sq float64
+		for _, v := range values[i-period+1 : i+1] {
+			sq += (v - mean[i]) * (v - mean[i])
+		}
+		out[i] = math.Sqrt(sq / float64(period))
+	}
+	return out
+}
+
+// Last returns the most recent value of a series, or NaN for an empty series.
+func Last(series []float64) float64 {
+	if len(series) == 0 {
+		return math.NaN()
+	}
+	return series[len(series)-1]
+}
@@ -0,0 +1,35 @@
+package indicator
+
+import "math"
+
+// BollingerBands holds the three band series and the band width.
+type BollingerBands struct {
+	Upper  []float64
+	Middle []float64
+	Lower  []float64
+	// Width is (upper - lower) / middle, expressed as a percentage.
+	Width []float64
+}
+
+// Bollinger calculates Bollinger Bands: an SMA of period values with bands k standard deviations away.
+func Bollinger(values []float64, period int, k float64) BollingerBands {
+	middle := SMA(values, period)
+	dev := StdDev(values, period)
+	bb := BollingerBands{
+		Upper:  nanSeries(len(values)),
+		Middle: middle,
+		Lower:  nanSeries(len(values)),
+		Width:  nanSeries(len(values)),
+	}
+	for i := range values {
+		if math.IsNaN(middle[i]) {
+			continue
+		}
+		bb.Upper[i] = middle[i] + k*dev[i]
+		bb.Lower[i] = middle[i] - k*dev[i]
+		if middle[i] != 0 {
+			bb.Width[i] = (bb.Upper[i] - bb.Lower[i]) / middle[i] * 100
+		}
+	}
+	return bb
+}
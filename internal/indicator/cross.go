@@ -0,0 +1,37 @@
+package indicator
+
+import "math"
+
+// CrossesAbove reports whether series a crossed above series b on the last value:
+// a was at or below b on the previous value and is above it now.
+func CrossesAbove(a, b []float64) bool {
+	n := len(a)
+	if n < 2 || len(b) != n {
+		return false
+	}
+	if anyNaN(a[n-2], a[n-1], b[n-2], b[n-1]) {
+		return false
+	}
+	return a[n-2] <= b[n-2] && a[n-1] > b[n-1]
+}
+
+// CrossesBelow reports whether series a crossed below series b on the last value.
+func CrossesBelow(a, b []float64) bool {
+	n := len(a)
+	if n < 2 || len(b) != n {
+		return false
+	}
+	if anyNaN(a[n-2], a[n-1], b[n-2], b[n-1]) {
+		return false
+	}
+	return a[n-2] >= b[n-2] && a[n-1] < b[n-1]
+}
+
+func anyNaN(values ...float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) {
+			return true
+		}
+	}
+	return false
+}
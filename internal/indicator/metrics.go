@@ -0,0 +1,47 @@
+package indicator
+
+import (
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// MetricsConfig holds the indicator settings used for a signal's metrics snapshot.
+type MetricsConfig struct {
+	BBPeriod    int
+	BBStdDev    float64
+	StochPeriod int
+	StochSmooth int
+	StochD      int
+}
+
+// DefaultMetricsConfig returns the settings shown on the opportunity cards: BB(20, 2) and Stoch(14, 1, 3).
+func DefaultMetricsConfig() MetricsConfig {
+	return MetricsConfig{
+		BBPeriod:    20,
+		BBStdDev:    2,
+		StochPeriod: 14,
+		StochSmooth: 1,
+		StochD:      3,
+	}
+}
+
+// CaptureMetrics takes the metrics snapshot for a signal that fired on the last of the given candles.
+// The 24h volume and change come from the most recent ticker; pass nil when none is available.
+func CaptureMetrics(candles []domain.Candle, ticker *domain.Ticker, cfg MetricsConfig) domain.SignalMetrics {
+	var m domain.SignalMetrics
+	if len(candles) == 0 {
+		return m
+	}
+	m.CandleTime = candles[len(candles)-1].CloseTime
+
+	bb := Bollinger(Closes(candles), cfg.BBPeriod, cfg.BBStdDev)
+	m.BBWidth = Last(bb.Width)
+	k, d := Stochastic(candles, cfg.StochPeriod, cfg.StochSmooth, cfg.StochD)
+	m.StochK = Last(k)
+	m.StochD = Last(d)
+
+	if ticker != nil {
+		m.Volume24h = ticker.QuoteVolume.Float64Value()
+		m.Change24h = ticker.PriceChangePercent.Float64Value()
+	}
+	return m
+}
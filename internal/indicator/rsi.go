@@ -0,0 +1,37 @@
+package indicator
+
+import "math"
+
+// RSI calculates the Relative Strength Index with Wilder's smoothing.
+func RSI(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if period <= 0 || len(values) <= period {
+		return out
+	}
+	var gain, loss float64
+	for i := 1; i <= period; i++ {
+		change := values[i] - values[i-1]
+		gain += math.Max(change, 0)
+		loss += math.Max(-change, 0)
+	}
+	gain /= float64(period)
+	loss /= float64(period)
+	out[period] = rsiValue(gain, loss)
+	for i := period + 1; i < len(values); i++ {
+		change := values[i] - values[i-1]
+		gain = (gain*float64(period-1) + math.Max(change, 0)) / float64(period)
+		loss = (loss*float64(period-1) + math.Max(-change, 0)) / float64(period)
+		out[i] = rsiValue(gain, loss)
+	}
+	return out
+}
+
+func rsiValue(gain, loss float64) float64 {
+	if loss == 0 {
+		if gain == 0 {
+			return 50
+		}
+		return 100
+	}
+	return 100 - 100/(1+gain/loss)
+}
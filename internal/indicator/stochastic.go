@@ -0,0 +1,34 @@
+package indicator
+
+import (
+	"math"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// Stochastic calculates the Stochastic Oscillator.
+// %K is the position of the close within the high/low range of the last kPeriod candles,
+// smoothed over smooth candles; %D is the SMA of %K over dPeriod candles.
+// A candle range without movement yields a neutral 50.
+func Stochastic(candles []domain.Candle, kPeriod, smooth, dPeriod int) (k, d []float64) {
+	raw := nanSeries(len(candles))
+	if kPeriod > 0 {
+		for i := kPeriod - 1; i < len(candles); i++ {
+			low, high := math.Inf(1), math.Inf(-1)
+			for _, c := range candles[i-kPeriod+1 : i+1] {
+				low = math.Min(low, c.Low)
+				high = math.Max(high, c.High)
+			}
+			if high == low {
+				raw[i] = 50
+				continue
+			}
+			raw[i] = (candles[i].Close - low) / (high - low) * 100
+		}
+	}
+	k = raw
+	if smooth > 1 {
+		k = SMA(raw, smooth)
+	}
+	return k, SMA(k, dPeriod)
+}
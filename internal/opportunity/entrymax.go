@@ -0,0 +1,128 @@
+package opportunity
+
+import (
+	"math"
+	"sync"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// Balances provides the free balance of an asset on the exchange account.
+type Balances interface {
+	Free(asset string) float64
+}
+
+// FixedBalances are balances that do not change, e.g. configured on the command line.
+type FixedBalances map[string]float64
+
+// Free implements Balances.
+func (b FixedBalances) Free(asset string) float64 {
+	return b[asset]
+}
+
+// SizerConfig holds the settings of the entry maximum calculation.
+type SizerConfig struct {
+	// Budget is the risk budget of one entry, applied to the free balance.
+	Budget domain.SizeRule
+	// DefaultStop is the stop distance, in percent of the price, a risk percent budget assumes for
+	// signals without a trade plan.
+	DefaultStop float64
+	// Slippage is how far, in percent of the best price, an entry may walk into the order book.
+	Slippage float64
+}
+
+// DefaultSizerConfig risks 1% of the balance per entry, assumes a 2% stop and allows 0.5% slippage.
+func DefaultSizerConfig() SizerConfig {
+	return SizerConfig{
+		Budget:      domain.SizeRule{Kind: domain.SizeRiskPercent, Value: 1},
+		DefaultStop: 2,
+		Slippage:    0.5,
+	}
+}
+
+// Sizer calculates the entry maximum of a signal: the largest position the risk budget and the free
+// balance allow, within the lot and min-notional filters of the exchange and the liquidity of the
+// local order book. It keeps the latest order book of every pair it is given.
+type Sizer struct {
+	mu       sync.Mutex
+	cfg      SizerConfig
+	balances Balances
+	symbols  map[string]domain.SymbolInfo
+	books    map[string]domain.OrderBook
+}
+
+// NewSizer creates a Sizer on the free balances of an account.
+func NewSizer(cfg SizerConfig, balances Balances) *Sizer {
+	return &Sizer{
+		cfg:      cfg,
+		balances: balances,
+		symbols:  make(map[string]domain.SymbolInfo),
+		books:    make(map[string]domain.OrderBook),
+	}
+}
+
+// SetSymbols sets the assets and order filters of pairs.
+func (s *Sizer) SetSymbols(symbols map[string]domain.SymbolInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, info := range symbols {
+		s.symbols[name] = info
+	}
+}
+
+// OnOrderBook replaces the local order book of a pair.
+func (s *Sizer) OnOrderBook(book domain.OrderBook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.books[book.Symbol] = book
+}
+
+// EntryMax returns the entry maximum of a signal, or zero when the pair is unknown or the position
+// would not pass the filters of the exchange. Without an order book of the pair the liquidity is
+// not limited; with one the quote amount is what the fill would cost, not the signal price.
+func (s *Sizer) EntryMax(sig domain.Signal) domain.EntryMax {
+	s.mu.Lock()
+	info, known := s.symbols[sig.Symbol]
+	book, hasBook := s.books[sig.Symbol]
+	s.mu.Unlock()
+	if !known || sig.Price <= 0 {
+		return domain.EntryMax{}
+	}
+
+	plan := domain.TradePlan{Entry: sig.Price, Size: s.cfg.Budget}
+	if sig.Plan != nil && sig.Plan.StopLoss > 0 {
+		plan.StopLoss = sig.Plan.StopLoss
+	} else if sig.Direction == domain.Sell {
+		plan.StopLoss = sig.Price * (1 + s.cfg.DefaultStop/100)
+	} else {
+		plan.StopLoss = sig.Price * (1 - s.cfg.DefaultStop/100)
+	}
+
+	// A buy spends the quote asset; a sell can only sell the base asset that is held.
+	var quantity, freeQuote float64
+	if sig.Direction == domain.Sell {
+		free := s.balances.Free(info.BaseAsset)
+		quantity = math.Min(plan.Quantity(free*sig.Price), free)
+	} else {
+		freeQuote = s.balances.Free(info.QuoteAsset)
+		quantity = plan.Quantity(freeQuote)
+	}
+
+	if quantity <= 0 {
+		return domain.EntryMax{}
+	}
+	price := sig.Price
+	if hasBook {
+		base, quote := book.Fill(sig.Direction, quantity, s.cfg.Slippage)
+		if base <= 0 {
+			return domain.EntryMax{}
+		}
+		quantity, price = base, quote/base
+		if sig.Direction != domain.Sell {
+			// Walking the book costs more than the signal price the budget was sized at.
+			quantity = math.Min(quantity, freeQuote/price)
+		}
+	}
+	quantity = info.Quantity(quantity, price)
+	return domain.EntryMax{Base: quantity, Quote: quantity * price}
+}
@@ -0,0 +1,133 @@
+package opportunity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// card is an opportunity as the list shows it. Metrics that are not available are null.
+type card struct {
+	ID             int64     `json:"id"`
+	Strategy       string    `json:"strategy"`
+	Exchange       string    `json:"exchange"`
+	Symbol         string    `json:"symbol"`
+	Timeframe      string    `json:"timeframe"`
+	Direction      string    `json:"direction"`
+	Moment         string    `json:"moment"`
+	CandleTime     time.Time `json:"candle_time"`
+	Price          float64   `json:"price"`
+	EntryMaxBase   float64   `json:"entry_max_base"`
+	EntryMaxQuote  float64   `json:"entry_max_quote"`
+	Volume24h      *float64  `json:"volume_24h"`
+	Change24h      *float64  `json:"change_24h"`
+	BBWidth        *float64  `json:"bb_width"`
+	StochK         *float64  `json:"stoch_k"`
+	StochD         *float64  `json:"stoch_d"`
+	ChartTrend     string    `json:"chart_trend"`
+	MarketExchange string    `json:"market_exchange"`
+	MarketTrend    string    `json:"market_trend"`
+	MarketBreadth  *float64  `json:"market_breadth"`
+	WGHM           string    `json:"wghm"`
+	Status         string    `json:"status"`
+	Age            int       `json:"age"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func newCard(o domain.Opportunity) card {
+	sig := o.Signal
+	return card{
+		ID:             o.ID,
+		Strategy:       sig.Strategy,
+		Exchange:       sig.Exchange,
+		Symbol:         sig.Symbol,
+		Timeframe:      string(sig.Timeframe),
+		Direction:      string(sig.Direction),
+		Moment:         sig.Direction.Moment(),
+		CandleTime:     sig.Metrics.CandleTime,
+		Price:          sig.Price,
+		EntryMaxBase:   o.EntryMax.Base,
+		EntryMaxQuote:  o.EntryMax.Quote,
+		Volume24h:      number(sig.Metrics.Volume24h),
+		Change24h:      number(sig.Metrics.Change24h),
+		BBWidth:        number(sig.Metrics.BBWidth),
+		StochK:         number(sig.Metrics.StochK),
+		StochD:         number(sig.Metrics.StochD),
+		ChartTrend:     string(o.ChartTrend),
+		MarketExchange: o.MarketExchange,
+		MarketTrend:    string(o.MarketTrend),
+		MarketBreadth:  number(o.MarketBreadth),
+		WGHM:           o.WGHM,
+		Status:         string(o.Status),
+		Age:            o.Age,
+		CreatedAt:      o.CreatedAt,
+	}
+}
+
+// number returns nil for a metric that is not available, which JSON cannot encode as NaN.
+func number(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+// NewHandler serves the Trading Opportunities list of a Service over HTTP:
+//
+//	GET  /opportunities               the open opportunities as JSON, oldest first
+//	POST /opportunities/{id}/seen     the user opened an opportunity
+//	POST /opportunities/{id}/acted-on the user traded on an opportunity
+func NewHandler(s *Service) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/opportunities", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		cards := []card{}
+		for _, o := range s.Open() {
+			cards = append(cards, newCard(o))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(cards); err != nil {
+			log.Printf("Error writing opportunities: %v", err)
+		}
+	})
+	mux.HandleFunc("/opportunities/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/opportunities/"), "/")
+		id, err := strconv.ParseInt(parts[0], 10, 64)
+		if len(parts) != 2 || err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		mark := map[string]func(ctx context.Context, id int64) error{
+			"seen":     s.MarkSeen,
+			"acted-on": s.MarkActedOn,
+		}[parts[1]]
+		if mark == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		switch err := mark(r.Context(), id); {
+		case errors.Is(err, ErrNotOpen):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	return mux
+}
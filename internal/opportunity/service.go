@@ -0,0 +1,227 @@
+// Package opportunity turns strategy signals into the entries of the Trading Opportunities list
+// and manages their lifecycle: new, seen, acted on or expired.
+package opportunity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+)
+
+// ChartTrends provides the current chart trend of a pair, e.g. the trend.ChartTrendService.
+type ChartTrends interface {
+	Trend(symbol string, tf domain.Timeframe) (domain.ChartTrend, bool)
+}
+
+// MarketTrends provides the latest market trend, e.g. the trend.MarketTrendService.
+type MarketTrends interface {
+	Latest(tf domain.Timeframe) (domain.MarketTrend, bool)
+}
+
+// Config holds the settings of the opportunity lifecycle.
+type Config struct {
+	// ExpireAfter is the number of candles of the signal timeframe after which an opportunity
+	// that was not acted on expires; zero means never.
+	ExpireAfter int
+	// MarketTimeframe is the market trend shown when there is none for the signal timeframe.
+	MarketTimeframe domain.Timeframe
+	// Clock returns the current time; nil means time.Now.
+	Clock func() time.Time
+}
+
+// DefaultConfig expires opportunities after 10 candles and falls back to the 1h market trend.
+func DefaultConfig() Config {
+	return Config{ExpireAfter: 10, MarketTimeframe: domain.Hour1}
+}
+
+// ErrNotOpen is returned for a status change of an opportunity that is not in the list.
+var ErrNotOpen = errors.New("no open opportunity")
+
+// Service creates an opportunity for every signal, with the chart and market trend at that moment,
+// and ages the open opportunities on every closed candle of their timeframe until they expire.
+type Service struct {
+	mu     sync.Mutex
+	cfg    Config
+	repo   storage.OpportunityRepository
+	chart  ChartTrends
+	market MarketTrends
+	sizer  *Sizer
+	open   []*domain.Opportunity // Oldest first, as the list shows them.
+	nextID int64                 // Used for the IDs when there is no repository.
+}
+
+// NewService creates a Service. repo, chart and market may be nil.
+func NewService(cfg Config, repo storage.OpportunityRepository, chart ChartTrends, market MarketTrends) *Service {
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	return &Service{cfg: cfg, repo: repo, chart: chart, market: market}
+}
+
+// SetSizer sets the Sizer that calculates the entry maximum of new opportunities.
+// Without one the entry maximum is left at zero.
+func (s *Service) SetSizer(sizer *Sizer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sizer = sizer
+}
+
+// Load restores the open opportunities from the repository, e.g. after a restart.
+func (s *Service) Load(ctx context.Context) error {
+	if s.repo == nil {
+		return nil
+	}
+	stored, err := s.repo.ListOpportunities(ctx, domain.OpportunityNew, domain.OpportunitySeen)
+	if err != nil {
+		return fmt.Errorf("could not load opportunities: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.open = s.open[:0]
+	for i := range stored {
+		s.open = append(s.open, &stored[i])
+	}
+	return nil
+}
+
+// OnSignal implements app.SignalHandler: it adds a new opportunity at the bottom of the list.
+func (s *Service) OnSignal(ctx context.Context, sig domain.Signal) error {
+	now := s.cfg.Clock()
+	// There is no WGHM indicator yet, so every card shows the state without a WGHM signal.
+	o := domain.Opportunity{
+		Signal:      sig,
+		Status:      domain.OpportunityNew,
+		WGHM:        domain.WGHMNone,
+		ExpireAfter: s.cfg.ExpireAfter,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if s.chart != nil {
+		if t, ok := s.chart.Trend(sig.Symbol, sig.Timeframe); ok {
+			o.ChartTrend = t.Trend
+		}
+	}
+	if s.market != nil {
+		m, ok := s.market.Latest(sig.Timeframe)
+		if !ok {
+			m, ok = s.market.Latest(s.cfg.MarketTimeframe)
+		}
+		if ok {
+			o.MarketExchange, o.MarketTrend, o.MarketBreadth = m.Exchange, m.Trend, m.Breadth
+		}
+	}
+
+	s.mu.Lock()
+	sizer := s.sizer
+	s.mu.Unlock()
+	if sizer != nil {
+		o.EntryMax = sizer.EntryMax(sig)
+	}
+
+	if s.repo != nil {
+		id, err := s.repo.SaveOpportunity(ctx, o)
+		if err != nil {
+			return fmt.Errorf("could not save opportunity: %w", err)
+		}
+		o.ID = id
+	}
+	s.mu.Lock()
+	if s.repo == nil {
+		s.nextID++
+		o.ID = s.nextID
+	}
+	s.open = append(s.open, &o)
+	s.mu.Unlock()
+	log.Printf("Opportunity %d: %s %s %s on %s, chart trend %s, market trend %s",
+		o.ID, sig.Direction.Moment(), sig.Symbol, sig.Timeframe, sig.Strategy, o.ChartTrend, o.MarketTrend)
+	return nil
+}
+
+// OnCandle implements app.CandleHandler: it ages the open opportunities of the candle's pair and
+// timeframe and expires those that reached their age limit.
+func (s *Service) OnCandle(ctx context.Context, c domain.Candle) error {
+	now := s.cfg.Clock()
+	s.mu.Lock()
+	var changed []domain.Opportunity
+	open := s.open[:0]
+	for _, o := range s.open {
+		if o.Signal.Symbol == c.Symbol && o.Signal.Timeframe == c.Timeframe && c.CloseTime.After(o.Signal.Time) {
+			if o.Tick(now) {
+				log.Printf("Opportunity %d expired after %d candles", o.ID, o.Age)
+			}
+			changed = append(changed, *o)
+		}
+		if o.Open() {
+			open = append(open, o)
+		}
+	}
+	s.open = open
+	s.mu.Unlock()
+	return s.save(ctx, changed...)
+}
+
+// MarkSeen records that the user opened an opportunity.
+func (s *Service) MarkSeen(ctx context.Context, id int64) error {
+	return s.transition(ctx, id, domain.OpportunitySeen)
+}
+
+// MarkActedOn records that the user traded on an opportunity; it leaves the list.
+func (s *Service) MarkActedOn(ctx context.Context, id int64) error {
+	return s.transition(ctx, id, domain.OpportunityActedOn)
+}
+
+func (s *Service) transition(ctx context.Context, id int64, to domain.OpportunityStatus) error {
+	s.mu.Lock()
+	var updated *domain.Opportunity
+	for i, o := range s.open {
+		if o.ID != id {
+			continue
+		}
+		if err := o.Transition(to, s.cfg.Clock()); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		// OnCandle may age the opportunity as soon as the lock is released; save it as it is now.
+		snapshot := *o
+		updated = &snapshot
+		if !o.Open() {
+			s.open = append(s.open[:i], s.open[i+1:]...)
+		}
+		break
+	}
+	s.mu.Unlock()
+	if updated == nil {
+		return fmt.Errorf("%w with id %d", ErrNotOpen, id)
+	}
+	return s.save(ctx, *updated)
+}
+
+// save persists the lifecycle state of opportunities.
+func (s *Service) save(ctx context.Context, opportunities ...domain.Opportunity) error {
+	if s.repo == nil {
+		return nil
+	}
+	for _, o := range opportunities {
+		if err := s.repo.UpdateOpportunity(ctx, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open returns the open opportunities, oldest first: new ones appear at the bottom of the list.
+func (s *Service) Open() []domain.Opportunity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]domain.Opportunity, len(s.open))
+	for i, o := range s.open {
+		out[i] = *o
+	}
+	return out
+}
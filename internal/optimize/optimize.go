@@ -0,0 +1,230 @@
+// Package optimize searches the parameters of a strategy for the best backtest results, over a grid
+// or random samples of a parameter space, running the backtests in parallel.
+package optimize
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+)
+
+// Param is a parameter to search, from Min to Max in steps of Step.
+type Param struct {
+	Name           string
+	Min, Max, Step float64
+}
+
+// ParseParam parses a parameter written as "name=min:max:step", or "name=value" for a fixed value.
+func ParseParam(s string) (Param, error) {
+	name, spec, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return Param{}, fmt.Errorf("parameter %q: expected name=min:max:step", s)
+	}
+	parts := strings.Split(spec, ":")
+	var nums []float64
+	for _, part := range parts {
+		f, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return Param{}, fmt.Errorf("parameter %q: %q is not a number", s, part)
+		}
+		nums = append(nums, f)
+	}
+	p := Param{Name: name}
+	switch len(nums) {
+	case 1:
+		p.Min, p.Max, p.Step = nums[0], nums[0], 1
+	case 3:
+		p.Min, p.Max, p.Step = nums[0], nums[1], nums[2]
+	default:
+		return Param{}, fmt.Errorf("parameter %q: expected name=min:max:step", s)
+	}
+	if p.Step <= 0 || p.Max < p.Min {
+		return Param{}, fmt.Errorf("parameter %q: the step must be positive and max at least min", s)
+	}
+	return p, nil
+}
+
+// Values returns the values of the parameter, from Min up to and including Max.
+func (p Param) Values() []float64 {
+	var values []float64
+	// Counting steps instead of adding them up keeps the values free of accumulated rounding.
+	for i := 0; ; i++ {
+		v := p.Min + float64(i)*p.Step
+		if v > p.Max+p.Step*1e-9 {
+			return values
+		}
+		values = append(values, math.Round(v*1e9)/1e9)
+	}
+}
+
+// Params is one point of a parameter space.
+type Params map[string]float64
+
+// String returns the parameters as "name=value" pairs, sorted by name.
+func (p Params) String() string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + strconv.FormatFloat(p[name], 'g', -1, 64)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Grid returns every combination of the parameter values.
+func Grid(space []Param) []Params {
+	points := []Params{{}}
+	for _, p := range space {
+		var next []Params
+		for _, point := range points {
+			for _, v := range p.Values() {
+				q := Params{p.Name: v}
+				for name, value := range point {
+					q[name] = value
+				}
+				next = append(next, q)
+			}
+		}
+		points = next
+	}
+	return points
+}
+
+// Random returns n samples of the grid, drawn with replacement, from a seeded generator so a
+// search can be repeated.
+func Random(space []Param, n int, seed int64) []Params {
+	rng := rand.New(rand.NewSource(seed))
+	points := make([]Params, n)
+	for i := range points {
+		points[i] = Params{}
+		for _, p := range space {
+			values := p.Values()
+			points[i][p.Name] = values[rng.Intn(len(values))]
+		}
+	}
+	return points
+}
+
+// Objective is the metric to maximize, under constraints.
+type Objective struct {
+	// Metric is one of Metrics.
+	Metric string
+	// MaxDrawdown is the largest max equity drawdown, in percent, a result may have; zero for no limit.
+	MaxDrawdown float64
+	// MinTrades is the fewest trades a result needs.
+	MinTrades int
+}
+
+// Metrics are the metrics an Objective can maximize. Max drawdown is maximized as its negative.
+var Metrics = []string{"net_profit", "profit_factor", "sharpe", "sortino", "profitable_trades", "max_drawdown"}
+
+// Score returns the value of the objective for the metrics of a backtest, and whether they meet its
+// constraints and the metric is defined.
+func (o Objective) Score(m backtest.Metrics) (float64, bool) {
+	var v float64
+	switch o.Metric {
+	case "net_profit":
+		v = m.NetProfit
+	case "profit_factor":
+		v = float64(m.ProfitFactor)
+	case "sharpe":
+		v = float64(m.Sharpe)
+	case "sortino":
+		v = float64(m.Sortino)
+	case "profitable_trades":
+		v = float64(m.ProfitableTrades)
+	case "max_drawdown":
+		v = -m.MaxDrawdownPercent
+	default:
+		return math.NaN(), false
+	}
+	if math.IsNaN(v) || m.TotalTrades < o.MinTrades || (o.MaxDrawdown > 0 && m.MaxDrawdownPercent > o.MaxDrawdown) {
+		return v, false
+	}
+	return v, true
+}
+
+// Validate checks that the metric of the objective is known.
+func (o Objective) Validate() error {
+	for _, m := range Metrics {
+		if o.Metric == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown objective %q, expected one of %s", o.Metric, strings.Join(Metrics, ", "))
+}
+
+// Backtest runs a backtest with a set of parameters.
+type Backtest func(ctx context.Context, params Params) (*backtest.Result, error)
+
+// Trial is the outcome of the backtest of one set of parameters.
+type Trial struct {
+	Params   Params
+	Metrics  backtest.Metrics
+	Score    float64
+	Feasible bool // Whether the result meets the constraints of the objective.
+	Err      error
+}
+
+// Run backtests every set of parameters on up to workers goroutines, zero meaning one per CPU, and
+// returns the trials ranked by the objective: feasible ones first, best first, then the others.
+// It stops early, with an error, when the context is cancelled.
+func Run(ctx context.Context, points []Params, objective Objective, workers int, run Backtest) ([]Trial, error) {
+	if err := objective.Validate(); err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	trials := make([]Trial, len(points))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				t := Trial{Params: points[i]}
+				res, err := run(ctx, points[i])
+				if err != nil {
+					t.Err = err
+				} else {
+					t.Metrics = backtest.Analyze(res)
+					t.Score, t.Feasible = objective.Score(t.Metrics)
+				}
+				trials[i] = t
+			}
+		}()
+	}
+	for i := range points {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(trials, func(i, j int) bool {
+		a, b := trials[i], trials[j]
+		if a.Feasible != b.Feasible {
+			return a.Feasible
+		}
+		return a.Score > b.Score || (!math.IsNaN(a.Score) && math.IsNaN(b.Score))
+	})
+	return trials, nil
+}
@@ -0,0 +1,237 @@
+package optimize
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+)
+
+// stochBBParams sets the parameters of the "stoch-bb" strategy.
+var stochBBParams = map[string]func(c *strategy.StochBBConfig, v float64){
+	"bb_period":    func(c *strategy.StochBBConfig, v float64) { c.BBPeriod = int(v) },
+	"bb_stddev":    func(c *strategy.StochBBConfig, v float64) { c.BBStdDev = v },
+	"stoch_period": func(c *strategy.StochBBConfig, v float64) { c.StochPeriod = int(v) },
+	"stoch_smooth": func(c *strategy.StochBBConfig, v float64) { c.StochSmooth = int(v) },
+	"stoch_d":      func(c *strategy.StochBBConfig, v float64) { c.StochD = int(v) },
+	"oversold":     func(c *strategy.StochBBConfig, v float64) { c.Oversold = v },
+	"overbought":   func(c *strategy.StochBBConfig, v float64) { c.Overbought = v },
+	"min_bb_width": func(c *strategy.StochBBConfig, v float64) { c.MinBBWidth = v },
+	"risk_percent": func(c *strategy.StochBBConfig, v float64) { c.RiskPercent = v },
+}
+
+// rsiDivergenceParams sets the parameters of the "rsi-divergence" strategy.
+var rsiDivergenceParams = map[string]func(c *strategy.RSIDivergenceConfig, v float64){
+	"rsi_period":   func(c *strategy.RSIDivergenceConfig, v float64) { c.RSIPeriod = int(v) },
+	"pivot_left":   func(c *strategy.RSIDivergenceConfig, v float64) { c.PivotLeft = int(v) },
+	"pivot_right":  func(c *strategy.RSIDivergenceConfig, v float64) { c.PivotRight = int(v) },
+	"min_distance": func(c *strategy.RSIDivergenceConfig, v float64) { c.MinDistance = int(v) },
+	"max_lookback": func(c *strategy.RSIDivergenceConfig, v float64) { c.MaxLookback = int(v) },
+	"oversold":     func(c *strategy.RSIDivergenceConfig, v float64) { c.Oversold = v },
+	"overbought":   func(c *strategy.RSIDivergenceConfig, v float64) { c.Overbought = v },
+	"atr_period":   func(c *strategy.RSIDivergenceConfig, v float64) { c.ATRPeriod = int(v) },
+	"stop_atr":     func(c *strategy.RSIDivergenceConfig, v float64) { c.StopATR = v },
+	"reward_risk":  func(c *strategy.RSIDivergenceConfig, v float64) { c.RewardRisk = v },
+	"risk_percent": func(c *strategy.RSIDivergenceConfig, v float64) { c.RiskPercent = v },
+}
+
+// Strategies are the built-in strategies that can be optimized. A strategy definition file can be
+// optimized too, on the parameters of its indicators.
+var Strategies = []string{"stoch-bb", "rsi-divergence"}
+
+// ParamNames returns the names of the parameters of a built-in strategy, or of the indicators of a
+// strategy definition file as indicator.param, e.g. "bb.period", sorted.
+func ParamNames(name string) ([]string, error) {
+	var names []string
+	switch name {
+	case "stoch-bb":
+		for n := range stochBBParams {
+			names = append(names, n)
+		}
+	case "rsi-divergence":
+		for n := range rsiDivergenceParams {
+			names = append(names, n)
+		}
+	default:
+		def, err := strategy.LoadDefinition(name)
+		if err != nil {
+			return nil, fmt.Errorf("unknown strategy %q, expected one of %s or a definition file: %w", name, strings.Join(Strategies, ", "), err)
+		}
+		names = definitionParams(def)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// checkParams rejects the parameters that are not among the known ones.
+func checkParams(name string, known []string, params Params) error {
+	sort.Strings(known)
+	for p := range params {
+		if i := sort.SearchStrings(known, p); i == len(known) || known[i] != p {
+			return fmt.Errorf("unknown parameter %q of %s, expected one of %s", p, name, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// StrategyFactory returns the factory of a built-in strategy with parameters applied over its
+// default configuration, e.g. "bb_period" for the BBPeriod of "stoch-bb". Definitions are applied
+// with WithParams.
+func StrategyFactory(name string, params Params) (strategy.Factory, error) {
+	known, err := ParamNames(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkParams(name, known, params); err != nil {
+		return nil, err
+	}
+	switch name {
+	case "stoch-bb":
+		cfg := strategy.DefaultStochBBConfig()
+		for p, v := range params {
+			stochBBParams[p](&cfg, v)
+		}
+		return func() strategy.Strategy { return strategy.NewStochBB(cfg) }, nil
+	case "rsi-divergence":
+		cfg := strategy.DefaultRSIDivergenceConfig()
+		for p, v := range params {
+			rsiDivergenceParams[p](&cfg, v)
+		}
+		return func() strategy.Strategy { return strategy.NewRSIDivergence(cfg) }, nil
+	}
+	return nil, fmt.Errorf("%s is not a built-in strategy", name)
+}
+
+// definitionParams returns the parameters of the indicators of a definition as indicator.param.
+// Only the parameters set in the definition can be searched; its conditions are not parameters.
+func definitionParams(def *strategy.Definition) []string {
+	var names []string
+	for ind, spec := range def.Indicators {
+		for p := range spec.Params {
+			names = append(names, ind+"."+p)
+		}
+	}
+	return names
+}
+
+// WithParams returns a copy of a definition with parameters of its indicators replaced, e.g.
+// "bb.period" for the period of its indicator "bb". The copy is validated.
+func WithParams(def *strategy.Definition, params Params) (*strategy.Definition, error) {
+	if err := checkParams(def.Name, definitionParams(def), params); err != nil {
+		return nil, err
+	}
+	out := *def
+	out.Indicators = make(map[string]strategy.IndicatorDef, len(def.Indicators))
+	for ind, spec := range def.Indicators {
+		values := make(map[string]float64, len(spec.Params))
+		for p, v := range spec.Params {
+			if set, ok := params[ind+"."+p]; ok {
+				v = set
+			}
+			values[p] = v
+		}
+		out.Indicators[ind] = strategy.IndicatorDef{Type: spec.Type, Params: values}
+	}
+	if err := out.Validate(); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EngineBacktest returns a Backtest that runs a strategy over the history: a built-in strategy on
+// the timeframe of the configuration, or a strategy definition file on its own timeframes. Load the
+// history into a backtest.MemoryHistory first when the parameter space is large, from the start of
+// the period less its WarmUpPeriod.
+func EngineBacktest(cfg backtest.Config, history storage.TickerHistory, name string) Backtest {
+	def, defErr := loadDefinition(name)
+	return func(ctx context.Context, params Params) (*backtest.Result, error) {
+		if defErr != nil {
+			return nil, defErr
+		}
+		strategies, err := strategiesWith(def, name, cfg.Timeframe, params)
+		if err != nil {
+			return nil, err
+		}
+		engine := backtest.NewEngine(cfg, history)
+		for _, s := range strategies {
+			engine.Add(s.timeframe, s.factory)
+		}
+		return engine.Run(ctx)
+	}
+}
+
+// WarmUpPeriod returns the history before the period that the backtests of a strategy over the
+// points replay: the warm-up of the configuration, or the longest warm-up the strategy needs with
+// the parameters of a point. The points the strategy rejects are skipped, as their backtests fail.
+func WarmUpPeriod(cfg backtest.Config, name string, points []Params) (time.Duration, error) {
+	def, err := loadDefinition(name)
+	if err != nil {
+		return 0, err
+	}
+	warmUp := cfg.WarmUp
+	for _, params := range points {
+		strategies, err := strategiesWith(def, name, cfg.Timeframe, params)
+		if err != nil {
+			continue
+		}
+		for _, s := range strategies {
+			warmUp = max(warmUp, strategy.WarmUpPeriod(s.factory(), s.timeframe))
+		}
+	}
+	return warmUp, nil
+}
+
+// timeframed is a strategy and the timeframe it runs on.
+type timeframed struct {
+	timeframe domain.Timeframe
+	factory   strategy.Factory
+}
+
+// loadDefinition loads the definition file of a strategy; built-in strategies have none.
+func loadDefinition(name string) (*strategy.Definition, error) {
+	if builtIn(name) {
+		return nil, nil
+	}
+	return strategy.LoadDefinition(name)
+}
+
+// strategiesWith returns a strategy with parameters on the timeframes it runs on: a definition on its
+// own timeframes, or the built-in strategy name on tf.
+func strategiesWith(def *strategy.Definition, name string, tf domain.Timeframe, params Params) ([]timeframed, error) {
+	if def == nil {
+		factory, err := StrategyFactory(name, params)
+		if err != nil {
+			return nil, err
+		}
+		return []timeframed{{tf, factory}}, nil
+	}
+	d, err := WithParams(def, params)
+	if err != nil {
+		return nil, err
+	}
+	factory, err := d.Factory()
+	if err != nil {
+		return nil, err
+	}
+	var out []timeframed
+	for _, tf := range d.ParsedTimeframes() {
+		out = append(out, timeframed{tf, factory})
+	}
+	return out, nil
+}
+
+// builtIn reports whether a name is that of a built-in strategy.
+func builtIn(name string) bool {
+	for _, s := range Strategies {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
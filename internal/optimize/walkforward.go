@@ -0,0 +1,193 @@
+package optimize
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+)
+
+// Window is one step of a walk-forward analysis: the parameters are optimized on the in-sample
+// period and then evaluated on the out-of-sample period that directly follows it.
+type Window struct {
+	InFrom, InTo   time.Time
+	OutFrom, OutTo time.Time
+}
+
+// Windows splits a period into rolling windows of an in-sample and an out-of-sample duration. The
+// windows move forward by the out-of-sample duration, so the out-of-sample periods follow each other
+// without overlap. Anchored windows keep their in-sample period starting at from, growing with every
+// step. The last out-of-sample period is cut off at to.
+func Windows(from, to time.Time, inSample, outOfSample time.Duration, anchored bool) ([]Window, error) {
+	if inSample <= 0 || outOfSample <= 0 {
+		return nil, fmt.Errorf("the in-sample and out-of-sample durations must be positive")
+	}
+	var windows []Window
+	for start := from; ; start = start.Add(outOfSample) {
+		w := Window{InFrom: start, InTo: start.Add(inSample)}
+		if anchored {
+			w.InFrom = from
+		}
+		w.OutFrom, w.OutTo = w.InTo, w.InTo.Add(outOfSample)
+		if !w.OutFrom.Before(to) {
+			break
+		}
+		if w.OutTo.After(to) {
+			w.OutTo = to
+		}
+		windows = append(windows, w)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("the period is shorter than one in-sample window")
+	}
+	return windows, nil
+}
+
+// WindowResult is the outcome of one walk-forward window.
+type WindowResult struct {
+	Window
+	// Params are the best in-sample parameters, nil when no trial met the objective.
+	Params Params
+	// InSample is the best in-sample trial; Trials counts the in-sample backtests.
+	InSample Trial
+	Trials   int
+	// OutOfSample is the backtest of Params on the out-of-sample period, nil without Params.
+	OutOfSample *backtest.Result
+	Metrics     backtest.Metrics
+	Err         error
+}
+
+// ParamStability describes how a parameter chosen in-sample varied over the windows.
+type ParamStability struct {
+	Name     string
+	Min, Max float64
+	Mean     float64
+	StdDev   float64
+	// Mode is the most chosen value, and ModeShare the percentage of the windows with parameters
+	// that chose it.
+	Mode      float64
+	ModeShare float64
+}
+
+// WalkForwardResult is the outcome of a walk-forward analysis.
+type WalkForwardResult struct {
+	Windows []WindowResult
+	// OutOfSample stitches the out-of-sample backtests together: every window starts with the final
+	// equity of the window before it, so its equity curve is what trading the re-optimized
+	// parameters would have made.
+	OutOfSample *backtest.Result
+	Metrics     backtest.Metrics
+	Stability   []ParamStability
+	// Efficiency is the out-of-sample net profit per day divided by the in-sample net profit per day,
+	// averaged over the windows; values near or above 1 suggest the parameters are not overfit.
+	Efficiency float64
+}
+
+// WalkForward optimizes the parameters on the in-sample period of each window and backtests the best
+// ones on its out-of-sample period. newBacktest returns the backtest of the configuration, which is
+// cfg with the period of the window and, out-of-sample, the carried balance.
+func WalkForward(ctx context.Context, cfg backtest.Config, windows []Window, points []Params, objective Objective, workers int,
+	newBacktest func(cfg backtest.Config) Backtest) (*WalkForwardResult, error) {
+	if err := objective.Validate(); err != nil {
+		return nil, err
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no walk-forward windows")
+	}
+	wf := &WalkForwardResult{}
+	stitched := &backtest.Result{
+		Symbol:         cfg.Symbol,
+		Timeframe:      cfg.Timeframe,
+		From:           windows[0].OutFrom,
+		To:             windows[len(windows)-1].OutTo,
+		InitialBalance: cfg.InitialBalance,
+		FinalEquity:    cfg.InitialBalance,
+	}
+	var efficiency float64
+	var efficiencies int
+	for _, w := range windows {
+		wr := WindowResult{Window: w, Trials: len(points)}
+		inCfg := cfg
+		inCfg.From, inCfg.To = w.InFrom, w.InTo
+		trials, err := Run(ctx, points, objective, workers, newBacktest(inCfg))
+		if err != nil {
+			return nil, err
+		}
+		if len(trials) > 0 && trials[0].Feasible {
+			wr.InSample, wr.Params = trials[0], trials[0].Params
+		}
+
+		if wr.Params != nil {
+			outCfg := cfg
+			outCfg.From, outCfg.To, outCfg.InitialBalance = w.OutFrom, w.OutTo, stitched.FinalEquity
+			res, err := newBacktest(outCfg)(ctx, wr.Params)
+			if err != nil {
+				wr.Err = fmt.Errorf("could not backtest out-of-sample: %w", err)
+			} else {
+				wr.OutOfSample, wr.Metrics = res, backtest.Analyze(res)
+				stitch(stitched, res)
+				inDays, outDays := w.InTo.Sub(w.InFrom).Hours()/24, w.OutTo.Sub(w.OutFrom).Hours()/24
+				if in := wr.InSample.Metrics.NetProfitPercent / inDays; in > 0 {
+					efficiency += wr.Metrics.NetProfitPercent / outDays / in
+					efficiencies++
+				}
+			}
+		}
+		wf.Windows = append(wf.Windows, wr)
+	}
+	wf.OutOfSample = stitched
+	wf.Metrics = backtest.Analyze(stitched)
+	wf.Stability = stability(wf.Windows)
+	wf.Efficiency = math.NaN()
+	if efficiencies > 0 {
+		wf.Efficiency = efficiency / float64(efficiencies)
+	}
+	return wf, nil
+}
+
+// stitch appends an out-of-sample result to the stitched one.
+func stitch(stitched, res *backtest.Result) {
+	stitched.FinalEquity = res.FinalEquity
+	stitched.Trades = append(stitched.Trades, res.Trades...)
+	stitched.Equity = append(stitched.Equity, res.Equity...)
+	stitched.Signals = append(stitched.Signals, res.Signals...)
+	stitched.Candles = append(stitched.Candles, res.Candles...)
+}
+
+// stability summarizes the parameters chosen in the windows that had any.
+func stability(windows []WindowResult) []ParamStability {
+	values := map[string][]float64{}
+	for _, w := range windows {
+		for name, v := range w.Params {
+			values[name] = append(values[name], v)
+		}
+	}
+	var out []ParamStability
+	for name, vs := range values {
+		s := ParamStability{Name: name, Min: vs[0], Max: vs[0]}
+		counts := map[float64]int{}
+		for _, v := range vs {
+			s.Min, s.Max = math.Min(s.Min, v), math.Max(s.Max, v)
+			s.Mean += v
+			counts[v]++
+		}
+		s.Mean /= float64(len(vs))
+		for _, v := range vs {
+			s.StdDev += (v - s.Mean) * (v - s.Mean)
+		}
+		s.StdDev = math.Sqrt(s.StdDev / float64(len(vs)))
+		best := 0
+		for v, n := range counts {
+			if n > best || (n == best && v < s.Mode) {
+				s.Mode, best = v, n
+			}
+		}
+		s.ModeShare = float64(best) / float64(len(vs)) * 100
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
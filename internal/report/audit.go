@@ -0,0 +1,36 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/audit"
+)
+
+// WriteAudit writes the audit of the history of a pair: what was checked, the number of issues of
+// every kind, and the first limit issues in time order; a limit of 0 writes all of them.
+func WriteAudit(w io.Writer, r *audit.Report, limit int) error {
+	fmt.Fprintf(w, "%s %s → %s: %d tickers, %d candles, %s\n", r.Symbol,
+		r.From.Format("2006-01-02 15:04"), r.To.Format("2006-01-02 15:04"), r.Tickers, r.Candles, r.Summary())
+	if r.OK() {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tFROM\tTO\tDETAIL")
+	issues := r.Issues
+	if limit > 0 && len(issues) > limit {
+		issues = issues[:limit]
+	}
+	for _, issue := range issues {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", issue.Kind,
+			issue.From.UTC().Format("2006-01-02 15:04:05"), issue.To.UTC().Format("2006-01-02 15:04:05"), issue.Detail)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if hidden := len(r.Issues) - len(issues); hidden > 0 {
+		fmt.Fprintf(w, "... and %d more\n", hidden)
+	}
+	return nil
+}
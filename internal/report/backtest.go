@@ -0,0 +1,354 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// BacktestReport is the JSON document of a backtest run: what was tested, its metrics and its trades.
+type BacktestReport struct {
+	Symbol    string           `json:"symbol"`
+	Timeframe string           `json:"timeframe"`
+	From      time.Time        `json:"from"`
+	To        time.Time        `json:"to"`
+	Metrics   backtest.Metrics `json:"metrics"`
+	// Pairs break the trades of a portfolio down by pair.
+	Pairs  []PairSummary    `json:"pairs,omitempty"`
+	Trades []backtest.Trade `json:"trades"`
+	// RejectedSignals counts the entries refused for the position limit or the lack of free cash.
+	RejectedSignals int `json:"rejected_signals"`
+	// MonteCarlo are the Monte Carlo simulations of the trades, when any were run.
+	MonteCarlo []backtest.MonteCarloResult `json:"monte_carlo,omitempty"`
+}
+
+// NewBacktestReport analyzes a backtest run.
+func NewBacktestReport(res *backtest.Result) BacktestReport {
+	trades := res.Trades
+	if trades == nil {
+		trades = []backtest.Trade{}
+	}
+	r := BacktestReport{
+		Symbol:          res.Symbol,
+		Timeframe:       string(res.Timeframe),
+		From:            res.From,
+		To:              res.To,
+		Metrics:         backtest.Analyze(res),
+		Trades:          trades,
+		RejectedSignals: len(res.Rejected),
+	}
+	if strings.Contains(res.Symbol, ",") {
+		r.Pairs = pairSummaries(strings.Split(res.Symbol, ","), trades)
+	}
+	return r
+}
+
+// PairSummary is the result of the trades of one pair of a portfolio.
+type PairSummary struct {
+	Symbol        string  `json:"symbol"`
+	TotalTrades   int     `json:"total_trades"`
+	WinningTrades int     `json:"winning_trades"`
+	NetProfit     float64 `json:"net_profit"`
+	Fees          float64 `json:"fees"`
+}
+
+func pairSummaries(symbols []string, trades []backtest.Trade) []PairSummary {
+	pairs := make([]PairSummary, len(symbols))
+	index := map[string]int{}
+	for i, symbol := range symbols {
+		pairs[i].Symbol, index[symbol] = symbol, i
+	}
+	for _, t := range trades {
+		i, ok := index[t.Symbol]
+		if !ok {
+			continue
+		}
+		p := &pairs[i]
+		p.TotalTrades++
+		if t.PnL > 0 {
+			p.WinningTrades++
+		}
+		p.NetProfit += t.PnL
+		p.Fees += t.Fees
+	}
+	return pairs
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r BacktestReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteTable writes the metrics and the trades as human-readable tables.
+func (r BacktestReport) WriteTable(w io.Writer) error {
+	m := r.Metrics
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Backtest %s %s, %s to %s\n\n", r.Symbol, r.Timeframe, r.From.Format(time.RFC3339), r.To.Format(time.RFC3339))
+	rows := []struct{ name, value string }{
+		{"Total P&L", fmt.Sprintf("%s (%s)", amount(m.NetProfit), percent(m.NetProfitPercent))},
+		{"Final equity", amount(m.FinalEquity)},
+		{"Max equity drawdown", fmt.Sprintf("%s (%s)", amount(m.MaxDrawdown), percent(m.MaxDrawdownPercent))},
+		{"Total trades", fmt.Sprint(m.TotalTrades)},
+		{"Profitable trades", fmt.Sprintf("%s (%d/%d)", percent(float64(m.ProfitableTrades)), m.WinningTrades, m.TotalTrades)},
+		{"Profit factor", ratio(m.ProfitFactor)},
+		{"Gross profit / loss", fmt.Sprintf("%s / %s", amount(m.GrossProfit), amount(m.GrossLoss))},
+		{"Fees", amount(m.Fees)},
+		{"Average trade", amount(float64(m.AverageTrade))},
+		{"Average win / loss", fmt.Sprintf("%s / %s", amount(float64(m.AverageWin)), amount(float64(m.AverageLoss)))},
+		{"Largest win / loss", fmt.Sprintf("%s / %s", amount(m.LargestWin), amount(m.LargestLoss))},
+		{"Average run-up / drawdown", fmt.Sprintf("%s / %s", amount(float64(m.AverageRunUp)), amount(float64(m.AverageTradeDrawdown)))},
+		{"Max run-up / drawdown", fmt.Sprintf("%s / %s", amount(m.MaxRunUp), amount(m.MaxTradeDrawdown))},
+		{"Buy & hold", fmt.Sprintf("%s (%s)", amount(m.BuyHold), percent(m.BuyHoldPercent))},
+		{"Sharpe ratio", ratio(m.Sharpe)},
+		{"Sortino ratio", ratio(m.Sortino)},
+		{"Exposure", percent(m.Exposure)},
+		{"Average trade duration", time.Duration(m.AverageTradeDuration).String()},
+	}
+	if r.RejectedSignals > 0 {
+		rows = append(rows, struct{ name, value string }{"Rejected signals", fmt.Sprint(r.RejectedSignals)})
+	}
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\n", row.name, row.value)
+	}
+	if len(r.Pairs) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "PAIR\tTRADES\tWINNING\tP&L\tFEES")
+		for _, p := range r.Pairs {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\n", p.Symbol, p.TotalTrades, p.WinningTrades, amount(p.NetProfit), amount(p.Fees))
+		}
+	}
+	if len(r.Trades) > 0 {
+		fmt.Fprintln(tw)
+		// The trades of a portfolio also show their pair.
+		pair := func(t backtest.Trade) string { return "" }
+		header := "#\tSIDE\tENTRY\tEXIT\tQUANTITY\tENTRY PRICE\tEXIT PRICE\tP&L\tP&L %\tRUN-UP\tDRAWDOWN\tREASON"
+		if len(r.Pairs) > 0 {
+			pair = func(t backtest.Trade) string { return t.Symbol + "\t" }
+			header = "#\tPAIR\tSIDE\tENTRY\tEXIT\tQUANTITY\tENTRY PRICE\tEXIT PRICE\tP&L\tP&L %\tRUN-UP\tDRAWDOWN\tREASON"
+		}
+		fmt.Fprintln(tw, header)
+		for i, t := range r.Trades {
+			fmt.Fprintf(tw, "%d\t%s%s\t%s\t%s\t%.6f\t%.4f\t%.4f\t%s\t%s\t%s\t%s\t%s\n", i+1, pair(t), t.Direction,
+				t.EntryTime.Format(time.RFC3339), t.ExitTime.Format(time.RFC3339), t.Quantity, t.EntryPrice, t.ExitPrice,
+				amount(t.PnL), percent(t.PnLPercent), amount(t.RunUp), amount(t.Drawdown), t.ExitReason)
+		}
+	}
+	for _, mc := range r.MonteCarlo {
+		fmt.Fprintln(tw)
+		fmt.Fprintf(tw, "Monte Carlo, %d %s runs: %s lost money, %s had a lower P&L, %s a larger drawdown\n",
+			mc.Runs, mc.Method, percent(mc.LossPercent), percent(mc.NetProfitRank), percent(mc.DrawdownRank))
+		fmt.Fprintln(tw, "\tMEAN\tMIN\t5%\t25%\t50%\t75%\t95%\tMAX")
+		for _, row := range []struct {
+			name  string
+			d     backtest.Distribution
+			value func(float64) string
+		}{{"Total P&L", mc.NetProfit, amount}, {"Max drawdown", mc.MaxDrawdownPercent, percent}} {
+			d := row.d
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", row.name, row.value(d.Mean), row.value(d.Min),
+				row.value(d.P5), row.value(d.P25), row.value(d.P50), row.value(d.P75), row.value(d.P95), row.value(d.Max))
+		}
+	}
+	return tw.Flush()
+}
+
+func amount(v float64) string {
+	if math.IsNaN(v) {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+func percent(v float64) string {
+	if math.IsNaN(v) {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f%%", v)
+}
+
+func ratio(r backtest.Ratio) string {
+	switch f := float64(r); {
+	case math.IsNaN(f):
+		return "-"
+	case math.IsInf(f, 0):
+		return "∞"
+	default:
+		return fmt.Sprintf("%.2f", f)
+	}
+}
+
+// BacktestChart builds a chart of a backtest run: the price with the trade entries and exits, the
+// equity next to buy & hold, and the drawdown of the equity from its peak in percent.
+func BacktestChart(title string, res *backtest.Result) Chart {
+	n := len(res.Equity)
+	times := make([]time.Time, n)
+	prices := make([]float64, n)
+	equity := make([]float64, n)
+	buyHold := make([]float64, n)
+	drawdown := make([]float64, n)
+	shading := make([]string, n)
+	first := math.NaN()
+	if len(res.Candles) > 0 {
+		first = res.Candles[0].Open
+	}
+	peak := res.InitialBalance
+	for i, p := range res.Equity {
+		times[i], prices[i], equity[i] = p.Time, p.Price, p.Equity
+		buyHold[i] = res.InitialBalance * p.Price / first
+		peak = math.Max(peak, p.Equity)
+		drawdown[i] = (p.Equity/peak - 1) * 100
+		if p.InPosition {
+			shading[i] = "#1f77b4"
+		}
+	}
+
+	// A marker goes on the first candle that closed at or after the fill.
+	index := func(t time.Time) int {
+		return sort.Search(n, func(i int) bool { return !times[i].Before(t) })
+	}
+	var markers []Marker
+	for i, t := range res.Trades {
+		long := t.Direction != domain.Sell
+		if e := index(t.EntryTime); e < n {
+			markers = append(markers, Marker{Index: e, Value: t.EntryPrice, Color: "#2ca02c", Up: long,
+				Label: fmt.Sprintf("#%d %s entry at %.4f", i+1, t.Direction, t.EntryPrice)})
+		}
+		if x := index(t.ExitTime); x < n {
+			markers = append(markers, Marker{Index: x, Value: t.ExitPrice, Color: "#d62728", Up: !long,
+				Label: fmt.Sprintf("#%d exit at %.4f (%s), P&L %.2f", i+1, t.ExitPrice, t.ExitReason, t.PnL)})
+		}
+	}
+
+	return Chart{
+		Title: title,
+		Times: times,
+		Panes: []Pane{
+			{Height: 300, Series: []Series{{Name: "Price", Color: "#333", Values: prices}}, Shading: shading, Markers: markers},
+			{Height: 200, Series: []Series{
+				{Name: "Equity", Color: "#1f77b4", Values: equity},
+				{Name: "Buy & hold", Color: "#ff7f0e", Values: buyHold},
+			}},
+			{Height: 120, Series: []Series{{Name: "Drawdown %", Color: "#d62728", Values: drawdown}}, ZeroLine: true},
+		},
+	}
+}
+
+// WriteHTML writes the report as a self-contained HTML page: the chart of the run, the metrics and
+// a trades table that sorts on a click on its headers. It needs nothing but a browser.
+func (r BacktestReport) WriteHTML(w io.Writer, res *backtest.Result) error {
+	title := fmt.Sprintf("Backtest %s %s, %s to %s", r.Symbol, r.Timeframe, r.From.Format("2006-01-02 15:04"), r.To.Format("2006-01-02 15:04"))
+	var metrics strings.Builder
+	if err := r.writeMetrics(&metrics); err != nil {
+		return err
+	}
+	type row struct {
+		Cells []cell
+		Win   bool
+	}
+	var rows []row
+	for i, t := range r.Trades {
+		rows = append(rows, row{Win: t.PnL > 0, Cells: []cell{
+			numberCell(float64(i+1), fmt.Sprint(i+1)),
+			textCell(string(t.Direction)),
+			numberCell(float64(t.EntryTime.Unix()), t.EntryTime.UTC().Format("2006-01-02 15:04:05")),
+			numberCell(float64(t.ExitTime.Unix()), t.ExitTime.UTC().Format("2006-01-02 15:04:05")),
+			numberCell(t.Quantity, fmt.Sprintf("%.6f", t.Quantity)),
+			numberCell(t.EntryPrice, fmt.Sprintf("%.4f", t.EntryPrice)),
+			numberCell(t.ExitPrice, fmt.Sprintf("%.4f", t.ExitPrice)),
+			numberCell(t.PnL, amount(t.PnL)),
+			numberCell(t.PnLPercent, percent(t.PnLPercent)),
+			numberCell(t.RunUp, amount(t.RunUp)),
+			numberCell(t.Drawdown, amount(t.Drawdown)),
+			numberCell(t.Duration().Seconds(), t.Duration().String()),
+			textCell(string(t.ExitReason)),
+		}})
+	}
+	return backtestPage.Execute(w, map[string]any{
+		"Title":   title,
+		"Chart":   template.HTML(BacktestChart(title, res).SVG()),
+		"Metrics": metrics.String(),
+		"Headers": []string{"#", "Side", "Entry", "Exit", "Quantity", "Entry price", "Exit price", "P&L", "P&L %", "Run-up", "Drawdown", "Duration", "Reason"},
+		"Rows":    rows,
+	})
+}
+
+// cell is a cell of the trades table; Sort is the value it sorts on.
+type cell struct {
+	Text string
+	Sort string
+}
+
+func numberCell(v float64, s string) cell {
+	return cell{Text: s, Sort: strconv.FormatFloat(v, 'g', -1, 64)}
+}
+
+func textCell(s string) cell {
+	return cell{Text: s, Sort: s}
+}
+
+// writeMetrics writes the metrics part of the table report.
+func (r BacktestReport) writeMetrics(w io.Writer) error {
+	return BacktestReport{Symbol: r.Symbol, Timeframe: r.Timeframe, From: r.From, To: r.To, Metrics: r.Metrics}.WriteTable(w)
+}
+
+var backtestPage = template.Must(template.New("backtest").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 20px; color: #222; }
+pre { background: #f6f6f6; padding: 10px; display: inline-block; }
+table { border-collapse: collapse; font-size: 13px; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: right; }
+th { background: #f0f0f0; cursor: pointer; user-select: none; }
+th.asc::after { content: " ▲"; }
+th.desc::after { content: " ▼"; }
+tr.win td:nth-child(8) { color: #2ca02c; }
+tr.loss td:nth-child(8) { color: #d62728; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{.Chart}}
+<h2>Performance</h2>
+<pre>{{.Metrics}}</pre>
+<h2>Trades</h2>
+<table id="trades">
+<thead><tr>{{range .Headers}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Rows}}<tr class="{{if .Win}}win{{else}}loss{{end}}">{{range .Cells}}<td data-sort="{{.Sort}}">{{.Text}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+<script>
+document.querySelectorAll("#trades th").forEach(function (th, col) {
+  th.addEventListener("click", function () {
+    var asc = !th.classList.contains("asc");
+    document.querySelectorAll("#trades th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+    th.classList.add(asc ? "asc" : "desc");
+    var body = document.querySelector("#trades tbody");
+    var rows = Array.from(body.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[col].dataset.sort, y = b.cells[col].dataset.sort;
+      var nx = parseFloat(x), ny = parseFloat(y);
+      var cmp = isNaN(nx) || isNaN(ny) ? x.localeCompare(y) : nx - ny;
+      return asc ? cmp : -cmp;
+    });
+    rows.forEach(function (r) { body.appendChild(r); });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,228 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/optimize"
+)
+
+// WriteTrialsTable writes the ranked trials of an optimization as a human-readable table, with a
+// column per parameter. At most limit trials are written; zero writes all of them.
+func WriteTrialsTable(w io.Writer, trials []optimize.Trial, names []string, limit int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := append([]string{"RANK"}, upper(names)...)
+	header = append(header, "SCORE", "NET P&L %", "MAX DD %", "TRADES", "WIN %", "PROFIT FACTOR", "SHARPE", "NOTE")
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for i, t := range trials {
+		if limit > 0 && i >= limit {
+			break
+		}
+		row := []string{fmt.Sprint(i + 1)}
+		for _, name := range names {
+			row = append(row, strconv.FormatFloat(t.Params[name], 'g', -1, 64))
+		}
+		note := ""
+		switch {
+		case t.Err != nil:
+			note = t.Err.Error()
+		case !t.Feasible:
+			note = "constraints not met"
+		}
+		m := t.Metrics
+		row = append(row, amount(t.Score), percent(m.NetProfitPercent), percent(m.MaxDrawdownPercent), fmt.Sprint(m.TotalTrades),
+			percent(float64(m.ProfitableTrades)), ratio(m.ProfitFactor), ratio(m.Sharpe), note)
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// WriteTrialsCSV writes the ranked trials of an optimization as CSV with a header row.
+func WriteTrialsCSV(w io.Writer, trials []optimize.Trial, names []string) error {
+	cw := csv.NewWriter(w)
+	header := append(append([]string{}, names...), "score", "feasible", "net_profit", "net_profit_percent",
+		"max_drawdown_percent", "total_trades", "profitable_trades_percent", "profit_factor", "sharpe", "sortino", "error")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	num := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+	for _, t := range trials {
+		var record []string
+		for _, name := range names {
+			record = append(record, num(t.Params[name]))
+		}
+		errText := ""
+		if t.Err != nil {
+			errText = t.Err.Error()
+		}
+		m := t.Metrics
+		record = append(record, num(t.Score), strconv.FormatBool(t.Feasible), num(m.NetProfit), num(m.NetProfitPercent),
+			num(m.MaxDrawdownPercent), strconv.Itoa(m.TotalTrades), num(float64(m.ProfitableTrades)), num(float64(m.ProfitFactor)),
+			num(float64(m.Sharpe)), num(float64(m.Sortino)), errText)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func upper(names []string) []string {
+	out := make([]string, len(names))
+	for i, n := range names {
+		out[i] = strings.ToUpper(n)
+	}
+	return out
+}
+
+// Heatmap is a grid of scores of a two-parameter sweep.
+type Heatmap struct {
+	Title        string
+	XName, YName string
+	X, Y         []float64
+	// Values holds the score per cell, indexed [y][x]; NaN marks a cell without a feasible result.
+	Values [][]float64
+}
+
+// TrialsHeatmap builds the heatmap of the trials of a sweep over the parameters x and y. A cell
+// holds the best feasible score of the trials with its x and y values, so other parameters of the
+// sweep are maximized over.
+func TrialsHeatmap(title string, trials []optimize.Trial, x, y string) Heatmap {
+	h := Heatmap{Title: title, XName: x, YName: y}
+	xs, ys := map[float64]bool{}, map[float64]bool{}
+	for _, t := range trials {
+		xs[t.Params[x]], ys[t.Params[y]] = true, true
+	}
+	h.X, h.Y = sortedKeys(xs), sortedKeys(ys)
+	xi, yi := indexOf(h.X), indexOf(h.Y)
+	h.Values = make([][]float64, len(h.Y))
+	for i := range h.Values {
+		h.Values[i] = make([]float64, len(h.X))
+		for j := range h.Values[i] {
+			h.Values[i][j] = math.NaN()
+		}
+	}
+	for _, t := range trials {
+		if !t.Feasible || t.Err != nil {
+			continue
+		}
+		cell := &h.Values[yi[t.Params[y]]][xi[t.Params[x]]]
+		if math.IsNaN(*cell) || t.Score > *cell {
+			*cell = t.Score
+		}
+	}
+	return h
+}
+
+func sortedKeys(m map[float64]bool) []float64 {
+	keys := make([]float64, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Float64s(keys)
+	return keys
+}
+
+func indexOf(values []float64) map[float64]int {
+	index := make(map[float64]int, len(values))
+	for i, v := range values {
+		index[v] = i
+	}
+	return index
+}
+
+const (
+	heatmapCell   = 40
+	heatmapMargin = 70
+)
+
+// WriteSVG renders the heatmap as a standalone SVG document.
+func (h Heatmap) WriteSVG(w io.Writer) error {
+	_, err := io.WriteString(w, h.SVG())
+	return err
+}
+
+// SVG renders the heatmap from red for the lowest score to green for the highest; cells without a
+// feasible result are grey. Every cell shows its score, and an unbounded score shows as ∞.
+func (h Heatmap) SVG() string {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, row := range h.Values {
+		for _, v := range row {
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+		}
+	}
+	width := heatmapMargin + len(h.X)*heatmapCell + 20
+	height := chartTitleHeight + len(h.Y)*heatmapCell + heatmapMargin
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="10">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+	fmt.Fprintf(&b, `<text x="%d" y="20" font-size="14" font-weight="bold">%s</text>`+"\n", heatmapMargin, html.EscapeString(h.Title))
+
+	// Rows are drawn from the highest y value at the top.
+	for i := range h.Y {
+		row := len(h.Y) - 1 - i
+		top := chartTitleHeight + row*heatmapCell
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", heatmapMargin-5, top+heatmapCell/2+4, formatAxisValue(h.Y[i]))
+		for j := range h.X {
+			v := h.Values[i][j]
+			left := heatmapMargin + j*heatmapCell
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="white"><title>%s=%s %s=%s: %s</title></rect>`+"\n",
+				left, top, heatmapCell, heatmapCell, heatColor(v, lo, hi),
+				html.EscapeString(h.XName), formatAxisValue(h.X[j]), html.EscapeString(h.YName), formatAxisValue(h.Y[i]), heatLabel(v))
+			fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n", left+heatmapCell/2, top+heatmapCell/2+4, heatLabel(v))
+		}
+	}
+	bottom := chartTitleHeight + len(h.Y)*heatmapCell
+	for j, x := range h.X {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n", heatmapMargin+j*heatmapCell+heatmapCell/2, bottom+15, formatAxisValue(x))
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" font-size="12">%s</text>`+"\n",
+		heatmapMargin+len(h.X)*heatmapCell/2, bottom+35, html.EscapeString(h.XName))
+	fmt.Fprintf(&b, `<text x="15" y="%d" text-anchor="middle" font-size="12" transform="rotate(-90 15 %d)">%s</text>`+"\n",
+		chartTitleHeight+len(h.Y)*heatmapCell/2, chartTitleHeight+len(h.Y)*heatmapCell/2, html.EscapeString(h.YName))
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// heatColor interpolates from red at lo over yellow to green at hi.
+func heatColor(v, lo, hi float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "#ddd"
+	case math.IsInf(v, 1):
+		return "rgb(40,170,60)"
+	case math.IsInf(v, -1):
+		return "rgb(215,50,40)"
+	}
+	f := 0.5
+	if hi > lo {
+		f = (v - lo) / (hi - lo)
+	}
+	r, g := 215.0, 50+f*2*(200-50)
+	if f > 0.5 {
+		r, g = 215-(f-0.5)*2*(215-40), 200-(f-0.5)*2*(200-170)
+	}
+	return fmt.Sprintf("rgb(%.0f,%.0f,%.0f)", r, g, 40+f*20)
+}
+
+func heatLabel(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "-"
+	case math.IsInf(v, 0):
+		return "∞"
+	default:
+		return formatAxisValue(v)
+	}
+}
@@ -0,0 +1,256 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/optimize"
+)
+
+// NewBacktestRun returns the record of the report to store: the strategy with the hash of its
+// definition, the settings of the run by name, and the metrics and trades as in the JSON report.
+func (r BacktestReport) NewBacktestRun(strategyName, definitionHash string, params map[string]string) (domain.BacktestRun, error) {
+	metrics, err := json.Marshal(r.Metrics)
+	if err != nil {
+		return domain.BacktestRun{}, fmt.Errorf("could not encode metrics: %w", err)
+	}
+	trades, err := json.Marshal(r.Trades)
+	if err != nil {
+		return domain.BacktestRun{}, fmt.Errorf("could not encode trades: %w", err)
+	}
+	return domain.BacktestRun{
+		CreatedAt:      time.Now().UTC(),
+		Strategy:       strategyName,
+		DefinitionHash: definitionHash,
+		Params:         params,
+		Symbol:         r.Symbol,
+		Timeframe:      domain.Timeframe(r.Timeframe),
+		From:           r.From,
+		To:             r.To,
+		EngineVersion:  backtest.EngineVersion,
+		Metrics:        metrics,
+		Trades:         trades,
+	}, nil
+}
+
+// NewTrialRun returns the record of a backtest of an optimization: the settings of the run with the
+// values of the parameters the strategy ran with added by name.
+func NewTrialRun(res *backtest.Result, strategyName, definitionHash string, settings map[string]string, params optimize.Params) (domain.BacktestRun, error) {
+	all := make(map[string]string, len(settings)+len(params))
+	for name, v := range settings {
+		all[name] = v
+	}
+	for name, v := range params {
+		all[name] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return NewBacktestReport(res).NewBacktestRun(strategyName, definitionHash, all)
+}
+
+// NewWalkForwardRuns returns the records of a walk-forward analysis: the out-of-sample backtest of
+// every window that found parameters, with the setting walk_forward "window N", and the stitched
+// out-of-sample backtest, with walk_forward "stitched".
+func NewWalkForwardRuns(wf *optimize.WalkForwardResult, strategyName, definitionHash string, settings map[string]string) ([]domain.BacktestRun, error) {
+	var runs []domain.BacktestRun
+	add := func(res *backtest.Result, label string, params optimize.Params) error {
+		labelled := map[string]string{"walk_forward": label}
+		for name, v := range settings {
+			labelled[name] = v
+		}
+		run, err := NewTrialRun(res, strategyName, definitionHash, labelled, params)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+		return nil
+	}
+	for i, w := range wf.Windows {
+		if w.OutOfSample == nil {
+			continue
+		}
+		if err := add(w.OutOfSample, fmt.Sprintf("window %d", i+1), w.Params); err != nil {
+			return nil, err
+		}
+	}
+	if err := add(wf.OutOfSample, "stitched", nil); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// WriteRuns writes stored backtest runs as a table with their headline metrics.
+func WriteRuns(w io.Writer, runs []domain.BacktestRun) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCREATED\tSTRATEGY\tHASH\tPAIR\tTIMEFRAME\tFROM\tTO\tNET P&L %\tMAX DD %\tTRADES\tPROFIT FACTOR")
+	for _, run := range runs {
+		m := runMetrics(run)
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", run.ID, run.CreatedAt.Format("2006-01-02 15:04"),
+			run.Strategy, shortHash(run.DefinitionHash), run.Symbol, run.Timeframe, run.From.Format("2006-01-02"), run.To.Format("2006-01-02"),
+			m.text("net_profit_percent"), m.text("max_drawdown_percent"), m.text("total_trades"), m.text("profit_factor"))
+	}
+	return tw.Flush()
+}
+
+// WriteRunComparison writes stored backtest runs side by side: what they tested, their settings and
+// their metrics, marking the rows where the runs differ with a "*". Two runs also get the change of
+// every metric from the first to the second. With onlyDiff, rows where all runs agree are left out.
+func WriteRunComparison(w io.Writer, runs []domain.BacktestRun, onlyDiff bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{""}
+	for _, run := range runs {
+		header = append(header, fmt.Sprintf("RUN %d", run.ID))
+	}
+	if len(runs) == 2 {
+		header = append(header, "CHANGE")
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	row := func(name string, values []string, change string) {
+		differ := false
+		for _, v := range values[1:] {
+			differ = differ || v != values[0]
+		}
+		if onlyDiff && !differ {
+			return
+		}
+		if differ {
+			name = "* " + name
+		}
+		cells := append([]string{name}, values...)
+		if len(runs) == 2 {
+			cells = append(cells, change)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	text := func(value func(run domain.BacktestRun) string) []string {
+		values := make([]string, len(runs))
+		for i, run := range runs {
+			values[i] = value(run)
+		}
+		return values
+	}
+	row("strategy", text(func(r domain.BacktestRun) string { return r.Strategy }), "")
+	row("definition", text(func(r domain.BacktestRun) string { return shortHash(r.DefinitionHash) }), "")
+	row("pair", text(func(r domain.BacktestRun) string { return r.Symbol }), "")
+	row("timeframe", text(func(r domain.BacktestRun) string { return string(r.Timeframe) }), "")
+	row("from", text(func(r domain.BacktestRun) string { return r.From.Format(time.RFC3339) }), "")
+	row("to", text(func(r domain.BacktestRun) string { return r.To.Format(time.RFC3339) }), "")
+	row("engine", text(func(r domain.BacktestRun) string { return r.EngineVersion }), "")
+
+	names := map[string]bool{}
+	for _, run := range runs {
+		for name := range run.Params {
+			names[name] = true
+		}
+	}
+	var params []string
+	for name := range names {
+		params = append(params, name)
+	}
+	sort.Strings(params)
+	for _, name := range params {
+		row(name, text(func(r domain.BacktestRun) string {
+			if v, ok := r.Params[name]; ok {
+				return v
+			}
+			return "-"
+		}), "")
+	}
+
+	metrics := make([]runMetricValues, len(runs))
+	for i, run := range runs {
+		metrics[i] = runMetrics(run)
+	}
+	for _, name := range metricNames() {
+		values := make([]string, len(runs))
+		for i, m := range metrics {
+			values[i] = m.text(name)
+		}
+		change := ""
+		if len(runs) == 2 {
+			change = metricChange(metrics[0][name], metrics[1][name])
+		}
+		row(name, values, change)
+	}
+	return tw.Flush()
+}
+
+// runMetricValues are the metrics of a stored run by their JSON name.
+type runMetricValues map[string]json.RawMessage
+
+func runMetrics(run domain.BacktestRun) runMetricValues {
+	var m runMetricValues
+	if err := json.Unmarshal(run.Metrics, &m); err != nil {
+		return runMetricValues{}
+	}
+	return m
+}
+
+// text formats a metric: fractional numbers with two decimals, undefined and missing ones as "-".
+func (m runMetricValues) text(name string) string {
+	raw, ok := m[name]
+	if !ok || string(raw) == "null" {
+		return "-"
+	}
+	var f float64
+	if err := json.Unmarshal(raw, &f); err == nil {
+		// Counts are written without decimals, amounts and ratios with two.
+		if !strings.ContainsAny(string(raw), ".eE") {
+			return string(raw)
+		}
+		return fmt.Sprintf("%.2f", f)
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// metricChange returns the change of a numeric metric, or of a duration, from a to b.
+func metricChange(a, b json.RawMessage) string {
+	var x, y float64
+	if json.Unmarshal(a, &x) == nil && json.Unmarshal(b, &y) == nil && string(a) != "null" && string(b) != "null" {
+		return fmt.Sprintf("%+.2f", y-x)
+	}
+	var s, t string
+	if json.Unmarshal(a, &s) == nil && json.Unmarshal(b, &t) == nil {
+		d, errD := time.ParseDuration(s)
+		e, errE := time.ParseDuration(t)
+		if errD == nil && errE == nil {
+			change := e - d
+			if change >= 0 {
+				return "+" + change.String()
+			}
+			return change.String()
+		}
+	}
+	return ""
+}
+
+// metricNames returns the JSON names of backtest.Metrics in the order of the report.
+func metricNames() []string {
+	t := reflect.TypeOf(backtest.Metrics{})
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -0,0 +1,230 @@
+// Package report renders analysis and backtest results as CSV, SVG and HTML.
+package report
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Series is a named line of values, aligned with the times of the chart.
+// NaN values leave a gap in the line.
+type Series struct {
+	Name   string
+	Color  string
+	Values []float64
+}
+
+// Marker is a point annotation on a pane, e.g. a trade entry or exit.
+type Marker struct {
+	Index int
+	Value float64
+	Color string
+	Label string
+	Up    bool // Draw an upward triangle instead of a downward one.
+}
+
+// Pane is one stacked plot area of a chart. All panes share the x axis.
+type Pane struct {
+	Height int
+	Series []Series
+	// Shading optionally colors the background behind each point; an empty color leaves it blank.
+	Shading []string
+	Markers []Marker
+	// ZeroLine draws a horizontal line at 0 when it is within range.
+	ZeroLine bool
+}
+
+// Chart is a time-aligned line chart made of one or more panes.
+type Chart struct {
+	Title string
+	Width int
+	Times []time.Time
+	Panes []Pane
+}
+
+const (
+	chartMarginLeft   = 70
+	chartMarginRight  = 20
+	chartTitleHeight  = 30
+	chartPaneGap      = 25
+	chartAxisHeight   = 30
+	defaultChartWidth = 1000
+	defaultPaneHeight = 200
+)
+
+// WriteSVG renders the chart as a standalone SVG document.
+func (c Chart) WriteSVG(w io.Writer) error {
+	_, err := io.WriteString(w, c.SVG())
+	return err
+}
+
+// SVG renders the chart as an SVG element that can be written to a file or embedded in HTML.
+func (c Chart) SVG() string {
+	width := c.Width
+	if width == 0 {
+		width = defaultChartWidth
+	}
+	height := chartTitleHeight + chartAxisHeight
+	for _, p := range c.Panes {
+		height += paneHeight(p) + chartPaneGap
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+	fmt.Fprintf(&b, `<text x="%d" y="20" font-size="14" font-weight="bold">%s</text>`+"\n", chartMarginLeft, html.EscapeString(c.Title))
+
+	plotWidth := float64(width - chartMarginLeft - chartMarginRight)
+	x := func(i int) float64 {
+		if len(c.Times) <= 1 {
+			return chartMarginLeft
+		}
+		return chartMarginLeft + plotWidth*float64(i)/float64(len(c.Times)-1)
+	}
+
+	top := chartTitleHeight
+	for _, p := range c.Panes {
+		h := paneHeight(p)
+		c.writePane(&b, p, top, h, x, plotWidth)
+		top += h + chartPaneGap
+	}
+	c.writeTimeAxis(&b, top-chartPaneGap, x)
+
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+func paneHeight(p Pane) int {
+	if p.Height == 0 {
+		return defaultPaneHeight
+	}
+	return p.Height
+}
+
+func (c Chart) writePane(b *strings.Builder, p Pane, top, h int, x func(int) float64, plotWidth float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, s := range p.Series {
+		for _, v := range s.Values {
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+		}
+	}
+	for _, m := range p.Markers {
+		lo, hi = math.Min(lo, m.Value), math.Max(hi, m.Value)
+	}
+	if p.ZeroLine {
+		lo, hi = math.Min(lo, 0), math.Max(hi, 0)
+	}
+	if math.IsInf(lo, 0) {
+		lo, hi = 0, 1
+	}
+	if hi == lo {
+		lo, hi = lo-1, hi+1
+	}
+	y := func(v float64) float64 {
+		return float64(top) + float64(h)*(hi-v)/(hi-lo)
+	}
+
+	fmt.Fprintf(b, `<rect x="%d" y="%d" width="%.0f" height="%d" fill="none" stroke="#ccc"/>`+"\n", chartMarginLeft, top, plotWidth, h)
+
+	// Background shading, one slice per point.
+	if len(c.Times) > 1 {
+		step := plotWidth / float64(len(c.Times)-1)
+		for i, color := range p.Shading {
+			if color == "" || i >= len(c.Times) {
+				continue
+			}
+			fmt.Fprintf(b, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" fill-opacity="0.15"/>`+"\n",
+				x(i)-step/2, top, step, h, color)
+		}
+	}
+
+	for _, v := range []float64{hi, (hi + lo) / 2, lo} {
+		fmt.Fprintf(b, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`+"\n", chartMarginLeft-5, y(v)+4, formatAxisValue(v))
+	}
+	if p.ZeroLine {
+		fmt.Fprintf(b, `<line x1="%d" y1="%.1f" x2="%.0f" y2="%.1f" stroke="#999" stroke-dasharray="4 3"/>`+"\n",
+			chartMarginLeft, y(0), chartMarginLeft+plotWidth, y(0))
+	}
+
+	for si, s := range p.Series {
+		color := s.Color
+		if color == "" {
+			color = "#1f77b4"
+		}
+		for _, segment := range lineSegments(s.Values) {
+			var points []string
+			for _, i := range segment {
+				points = append(points, fmt.Sprintf("%.1f,%.1f", x(i), y(s.Values[i])))
+			}
+			fmt.Fprintf(b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`+"\n", color, strings.Join(points, " "))
+		}
+		fmt.Fprintf(b, `<text x="%d" y="%d" fill="%s">%s</text>`+"\n", chartMarginLeft+5+si*150, top+14, color, html.EscapeString(s.Name))
+	}
+
+	for _, m := range p.Markers {
+		mx, my := x(m.Index), y(m.Value)
+		if m.Up {
+			fmt.Fprintf(b, `<polygon points="%.1f,%.1f %.1f,%.1f %.1f,%.1f" fill="%s"><title>%s</title></polygon>`+"\n",
+				mx, my, mx-5, my+9, mx+5, my+9, m.Color, html.EscapeString(m.Label))
+		} else {
+			fmt.Fprintf(b, `<polygon points="%.1f,%.1f %.1f,%.1f %.1f,%.1f" fill="%s"><title>%s</title></polygon>`+"\n",
+				mx, my, mx-5, my-9, mx+5, my-9, m.Color, html.EscapeString(m.Label))
+		}
+	}
+}
+
+func (c Chart) writeTimeAxis(b *strings.Builder, top int, x func(int) float64) {
+	if len(c.Times) == 0 {
+		return
+	}
+	ticks := 5
+	if len(c.Times) < ticks {
+		ticks = len(c.Times)
+	}
+	for t := 0; t < ticks; t++ {
+		i := 0
+		if ticks > 1 {
+			i = t * (len(c.Times) - 1) / (ticks - 1)
+		}
+		fmt.Fprintf(b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`+"\n",
+			x(i), top+15, c.Times[i].UTC().Format("2006-01-02 15:04"))
+	}
+}
+
+// lineSegments splits the indexes of a series into runs without NaN values.
+func lineSegments(values []float64) [][]int {
+	var segments [][]int
+	var current []int
+	for i, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			if len(current) > 0 {
+				segments = append(segments, current)
+			}
+			current = nil
+			continue
+		}
+		current = append(current, i)
+	}
+	if len(current) > 0 {
+		segments = append(segments, current)
+	}
+	return segments
+}
+
+func formatAxisValue(v float64) string {
+	switch {
+	case math.Abs(v) >= 1000:
+		return fmt.Sprintf("%.0f", v)
+	case math.Abs(v) >= 1:
+		return fmt.Sprintf("%.2f", v)
+	default:
+		return fmt.Sprintf("%.4g", v)
+	}
+}
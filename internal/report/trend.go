@@ -0,0 +1,78 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// TrendPoint is one point of a recomputed chart or market trend, aligned with the price at that time.
+type TrendPoint struct {
+	Time  time.Time
+	Price float64 // NaN when no price is available for the point.
+	Trend domain.Trend
+	Value float64 // Chart trend strength or market breadth, in percent.
+}
+
+// WriteTrendCSV writes the trend points as CSV with a header row.
+func WriteTrendCSV(w io.Writer, valueName string, points []TrendPoint) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "price", "trend", valueName}); err != nil {
+		return err
+	}
+	for _, p := range points {
+		price := ""
+		if !math.IsNaN(p.Price) {
+			price = strconv.FormatFloat(p.Price, 'f', -1, 64)
+		}
+		record := []string{
+			p.Time.UTC().Format(time.RFC3339),
+			price,
+			string(p.Trend),
+			strconv.FormatFloat(p.Value, 'f', 4, 64),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// TrendChart builds a chart with the price, shaded by trend, above a pane with the trend value.
+func TrendChart(title, valueName string, points []TrendPoint) Chart {
+	times := make([]time.Time, len(points))
+	prices := make([]float64, len(points))
+	values := make([]float64, len(points))
+	shading := make([]string, len(points))
+	for i, p := range points {
+		times[i] = p.Time
+		prices[i] = p.Price
+		values[i] = p.Value
+		shading[i] = TrendColor(p.Trend)
+	}
+	return Chart{
+		Title: title,
+		Times: times,
+		Panes: []Pane{
+			{Height: 300, Series: []Series{{Name: "Price", Color: "#333", Values: prices}}, Shading: shading},
+			{Height: 150, Series: []Series{{Name: valueName, Color: "#1f77b4", Values: values}}, ZeroLine: true},
+		},
+	}
+}
+
+// TrendColor returns the shading color of a trend: green for bullish, red for bearish.
+func TrendColor(t domain.Trend) string {
+	switch t {
+	case domain.Bullish:
+		return "#2ca02c"
+	case domain.Bearish:
+		return "#d62728"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,71 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/optimize"
+)
+
+// WriteWalkForward writes a walk-forward analysis as human-readable tables: per window the chosen
+// parameters with their in-sample and out-of-sample results, the stability of every parameter, and
+// the metrics of the stitched out-of-sample backtest.
+func WriteWalkForward(w io.Writer, wf *optimize.WalkForwardResult, names []string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := append([]string{"#", "IN-SAMPLE", "OUT-OF-SAMPLE"}, upper(names)...)
+	header = append(header, "IS SCORE", "IS P&L %", "OOS P&L %", "OOS MAX DD %", "OOS TRADES", "NOTE")
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	day := func(t time.Time) string { return t.Format("2006-01-02 15:04") }
+	for i, wr := range wf.Windows {
+		row := []string{fmt.Sprint(i + 1), day(wr.InFrom) + " → " + day(wr.InTo), day(wr.OutFrom) + " → " + day(wr.OutTo)}
+		for _, name := range names {
+			if wr.Params == nil {
+				row = append(row, "-")
+				continue
+			}
+			row = append(row, strconv.FormatFloat(wr.Params[name], 'g', -1, 64))
+		}
+		switch {
+		case wr.Params == nil:
+			row = append(row, "-", "-", "-", "-", "-", fmt.Sprintf("no feasible parameters in %d trials", wr.Trials))
+		case wr.Err != nil:
+			row = append(row, amount(wr.InSample.Score), percent(wr.InSample.Metrics.NetProfitPercent), "-", "-", "-", wr.Err.Error())
+		default:
+			m := wr.Metrics
+			row = append(row, amount(wr.InSample.Score), percent(wr.InSample.Metrics.NetProfitPercent),
+				percent(m.NetProfitPercent), percent(m.MaxDrawdownPercent), fmt.Sprint(m.TotalTrades), "")
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	if len(wf.Stability) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "PARAMETER\tMIN\tMAX\tMEAN\tSTD DEV\tMODE\tMODE SHARE")
+		for _, s := range wf.Stability {
+			fmt.Fprintf(tw, "%s\t%g\t%g\t%.4g\t%.4g\t%g\t%s\n", s.Name, s.Min, s.Max, s.Mean, s.StdDev, s.Mode, percent(s.ModeShare))
+		}
+	}
+
+	m := wf.Metrics
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "Out-of-sample %s to %s\n", wf.OutOfSample.From.Format(time.RFC3339), wf.OutOfSample.To.Format(time.RFC3339))
+	rows := []struct{ name, value string }{
+		{"Total P&L", fmt.Sprintf("%s (%s)", amount(m.NetProfit), percent(m.NetProfitPercent))},
+		{"Final equity", amount(m.FinalEquity)},
+		{"Max equity drawdown", fmt.Sprintf("%s (%s)", amount(m.MaxDrawdown), percent(m.MaxDrawdownPercent))},
+		{"Total trades", fmt.Sprint(m.TotalTrades)},
+		{"Profitable trades", percent(float64(m.ProfitableTrades))},
+		{"Profit factor", ratio(m.ProfitFactor)},
+		{"Sharpe ratio", ratio(m.Sharpe)},
+		{"Buy & hold", fmt.Sprintf("%s (%s)", amount(m.BuyHold), percent(m.BuyHoldPercent))},
+		{"Walk-forward efficiency", amount(wf.Efficiency)},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\n", row.name, row.value)
+	}
+	return tw.Flush()
+}
@@ -30,22 +30,77 @@ func NewSqliteRepository(ctx context.Context, dbPath string) (*SqliteRepository,
 
 	repo := &SqliteRepository{db: db}
 
-	if err := repo.createTable(ctx); err != nil {
+	if err := repo.createTables(ctx); err != nil {
 		return nil, err
 	}
 
 	return repo, nil
 }
 
-// createTable creates the 'ticks' table for storing ticker data.
-func (s *SqliteRepository) createTable(ctx context.Context) error {
-	query := `
+// createTables creates all tables used by the repository and adds the columns that are missing from
+// the tables of an older database.
+func (s *SqliteRepository) createTables(ctx context.Context) error {
+	for _, query := range []string{ticksTable, signalsTable, chartTrendsTable, marketTrendsTable, opportunitiesTable, orderBooksTable, backtestRunsTable} {
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	for _, c := range addedColumns {
+		if err := s.addColumn(ctx, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addedColumns are the columns added to a table after it was first created. CREATE TABLE IF NOT
+// EXISTS leaves the table of an existing database as it is, so they are added by ALTER TABLE.
+var addedColumns = []struct{ table, column, definition string }{
+	{"ticks", "quote_volume", "TEXT"},
+	{"ticks", "price_change_percent", "TEXT"},
+	{"signals", "plan_entry", "REAL"},
+	{"signals", "plan_stop_loss", "REAL"},
+	{"signals", "plan_take_profit", "REAL"},
+	{"signals", "plan_size_kind", "TEXT"},
+	{"signals", "plan_size_value", "REAL"},
+}
+
+// addColumn adds a column to a table unless the table already has it.
+func (s *SqliteRepository) addColumn(ctx context.Context, table, column, definition string) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return fmt.Errorf("could not read the columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("could not scan column of %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("could not read the columns of %s: %w", table, err)
+	}
+	rows.Close()
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("could not add column %s to %s: %w", column, table, err)
+	}
+	return nil
+}
+
+// ticksTable stores the raw ticker data.
+const ticksTable = `
 	CREATE TABLE IF NOT EXISTS ticks (
 		event_type TEXT NOT NULL,
 		event_time INTEGER NOT NULL,
 		symbol TEXT NOT NULL,
 		last_price TEXT NOT NULL,
 		volume TEXT NOT NULL,
+		quote_volume TEXT,
+		price_change_percent TEXT,
 		open_time INTEGER NOT NULL,
 		close_time INTEGER NOT NULL,
 		count INTEGER NOT NULL,
@@ -53,26 +108,31 @@ func (s *SqliteRepository) createTable(ctx context.Context) error {
 		PRIMARY KEY (symbol, event_time)
 	);`
 
-	_, err := s.db.ExecContext(ctx, query)
-	return err
-}
-
 // SaveTicker saves a domain.Ticker object to the database. Note the change in the table schema.
 func (s *SqliteRepository) SaveTicker(ctx context.Context, ticker domain.Ticker) error {
 	query := `
-	INSERT INTO ticks (event_type, event_time, symbol, last_price, volume, open_time, close_time, count)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+	INSERT INTO ticks (event_type, event_time, symbol, last_price, volume, quote_volume, price_change_percent,
+		open_time, close_time, count)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
 
 	// Use full precision for price, as it's critical.
 	lastPriceStr := ticker.LastPrice.Float.Text('f', -1)
 	// For volume, 8 decimal places is a standard and sufficient precision.
 	volumeStr := ticker.Volume.Float.Text('f', 8)
+	// The 24h statistics are NULL when the ticker has none.
+	var quoteVolume, priceChange sql.NullString
+	if ticker.QuoteVolume.Float != nil {
+		quoteVolume = sql.NullString{String: ticker.QuoteVolume.Float.Text('f', 8), Valid: true}
+	}
+	if ticker.PriceChangePercent.Float != nil {
+		priceChange = sql.NullString{String: ticker.PriceChangePercent.Float.Text('f', -1), Valid: true}
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := s.db.ExecContext(ctx, query,
-		ticker.EventType, ticker.EventTime, ticker.Symbol, lastPriceStr, volumeStr,
+		ticker.EventType, ticker.EventTime, ticker.Symbol, lastPriceStr, volumeStr, quoteVolume, priceChange,
 		ticker.OpenTime, ticker.CloseTime, ticker.Count,
 	)
 
@@ -81,11 +141,12 @@ func (s *SqliteRepository) SaveTicker(ctx context.Context, ticker domain.Ticker)
 
 // GetTickerByEventTime retrieves a ticker from the database by its event time.
 func (s *SqliteRepository) GetTickerByEventTime(ctx context.Context, eventTime int64) (*domain.Ticker, error) {
-	query := `SELECT event_type, event_time, symbol, last_price, volume, open_time, close_time, count FROM ticks WHERE event_time = ?`
+	query := `SELECT event_type, event_time, symbol, last_price, volume, quote_volume, price_change_percent, open_time, close_time, count FROM ticks WHERE event_time = ?`
 	row := s.db.QueryRowContext(ctx, query, eventTime)
 
 	var ticker domain.Ticker
 	var lastPriceStr, volumeStr string
+	var quoteVolume, priceChange sql.NullString
 
 	err := row.Scan(
 		&ticker.EventType,
@@ -93,6 +154,8 @@ func (s *SqliteRepository) GetTickerByEventTime(ctx context.Context, eventTime i
 		&ticker.Symbol,
 		&lastPriceStr,
 		&volumeStr,
+		&quoteVolume,
+		&priceChange,
 		&ticker.OpenTime,
 		&ticker.CloseTime,
 		&ticker.Count,
@@ -105,17 +168,98 @@ func (s *SqliteRepository) GetTickerByEventTime(ctx context.Context, eventTime i
 		return nil, fmt.Errorf("could not scan ticker row: %w", err)
 	}
 
-	// Convert string representations back to big.Float
+	if err := parseTickerNumbers(&ticker, lastPriceStr, volumeStr, quoteVolume, priceChange); err != nil {
+		return nil, err
+	}
+
+	return &ticker, nil
+}
+
+// ListTickers retrieves the tickers of a symbol with an event time in [from, to), ordered by event time.
+// An empty symbol retrieves the tickers of all symbols.
+func (s *SqliteRepository) ListTickers(ctx context.Context, symbol string, from, to time.Time) ([]domain.Ticker, error) {
+	return s.listTickers(ctx, "event_time, symbol", symbol, from, to)
+}
+
+// ListTickersAsStored retrieves the tickers of a symbol with an event time in [from, to) in the order
+// they were inserted, so tickers stored out of order can be found.
+func (s *SqliteRepository) ListTickersAsStored(ctx context.Context, symbol string, from, to time.Time) ([]domain.Ticker, error) {
+	return s.listTickers(ctx, "rowid", symbol, from, to)
+}
+
+// listTickers retrieves the tickers of a symbol with an event time in [from, to) in an order.
+func (s *SqliteRepository) listTickers(ctx context.Context, orderBy, symbol string, from, to time.Time) ([]domain.Ticker, error) {
+	// The symbol is only filtered on when it is given, so the query can use the
+	// (symbol, event_time) primary key.
+	where := "event_time >= ? AND event_time < ?"
+	args := []any{from.UnixMilli(), to.UnixMilli()}
+	if symbol != "" {
+		where = "symbol = ? AND " + where
+		args = append([]any{symbol}, args...)
+	}
+	query := `
+	SELECT event_type, event_time, symbol, last_price, volume, quote_volume, price_change_percent,
+		open_time, close_time, count
+	FROM ticks
+	WHERE ` + where + `
+	ORDER BY ` + orderBy
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query tickers: %w", err)
+	}
+	defer rows.Close()
+
+	var tickers []domain.Ticker
+	for rows.Next() {
+		var ticker domain.Ticker
+		var lastPriceStr, volumeStr string
+		var quoteVolume, priceChange sql.NullString
+		if err := rows.Scan(
+			&ticker.EventType,
+			&ticker.EventTime,
+			&ticker.Symbol,
+			&lastPriceStr,
+			&volumeStr,
+			&quoteVolume,
+			&priceChange,
+			&ticker.OpenTime,
+			&ticker.CloseTime,
+			&ticker.Count,
+		); err != nil {
+			return nil, fmt.Errorf("could not scan ticker row: %w", err)
+		}
+		if err := parseTickerNumbers(&ticker, lastPriceStr, volumeStr, quoteVolume, priceChange); err != nil {
+			return nil, err
+		}
+		tickers = append(tickers, ticker)
+	}
+	return tickers, rows.Err()
+}
+
+// parseTickerNumbers converts the stored string representations back to big.Float. The 24h
+// statistics of tickers stored before they were are left unset.
+func parseTickerNumbers(ticker *domain.Ticker, lastPriceStr, volumeStr string, quoteVolume, priceChange sql.NullString) error {
+	var err error
 	ticker.LastPrice.Float, _, err = big.ParseFloat(lastPriceStr, 10, 256, big.ToZero)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse last_price: %w", err)
+		return fmt.Errorf("could not parse last_price: %w", err)
 	}
 	ticker.Volume.Float, _, err = big.ParseFloat(volumeStr, 10, 256, big.ToZero)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse volume: %w", err)
+		return fmt.Errorf("could not parse volume: %w", err)
 	}
-
-	return &ticker, nil
+	if quoteVolume.Valid {
+		if ticker.QuoteVolume.Float, _, err = big.ParseFloat(quoteVolume.String, 10, 256, big.ToZero); err != nil {
+			return fmt.Errorf("could not parse quote_volume: %w", err)
+		}
+	}
+	if priceChange.Valid {
+		if ticker.PriceChangePercent.Float, _, err = big.ParseFloat(priceChange.String, 10, 256, big.ToZero); err != nil {
+			return fmt.Errorf("could not parse price_change_percent: %w", err)
+		}
+	}
+	return nil
 }
 
 // Close closes the database connection.
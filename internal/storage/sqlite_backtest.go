@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// backtestRunsTable stores backtest runs; the parameters, metrics and trades are stored as JSON.
+const backtestRunsTable = `
+	CREATE TABLE IF NOT EXISTS backtest_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		created_at INTEGER NOT NULL,
+		strategy TEXT NOT NULL,
+		definition_hash TEXT NOT NULL,
+		params TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		timeframe TEXT NOT NULL,
+		from_time INTEGER NOT NULL,
+		to_time INTEGER NOT NULL,
+		engine_version TEXT NOT NULL,
+		metrics TEXT NOT NULL,
+		trades TEXT NOT NULL
+	);`
+
+// SaveBacktestRun inserts a backtest run and returns its ID.
+func (s *SqliteRepository) SaveBacktestRun(ctx context.Context, run domain.BacktestRun) (int64, error) {
+	params, err := json.Marshal(run.Params)
+	if err != nil {
+		return 0, fmt.Errorf("could not encode parameters: %w", err)
+	}
+	query := `
+	INSERT INTO backtest_runs (created_at, strategy, definition_hash, params, symbol, timeframe,
+		from_time, to_time, engine_version, metrics, trades)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query,
+		run.CreatedAt.UnixMilli(), run.Strategy, run.DefinitionHash, string(params), run.Symbol, string(run.Timeframe),
+		run.From.UnixMilli(), run.To.UnixMilli(), run.EngineVersion, string(run.Metrics), string(run.Trades),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("could not insert backtest run: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+const backtestRunColumns = `id, created_at, strategy, definition_hash, params, symbol, timeframe,
+	from_time, to_time, engine_version, metrics, trades`
+
+// GetBacktestRun returns the backtest run with an ID, or nil when there is none.
+func (s *SqliteRepository) GetBacktestRun(ctx context.Context, id int64) (*domain.BacktestRun, error) {
+	query := `SELECT ` + backtestRunColumns + ` FROM backtest_runs WHERE id = ?`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	run, err := scanBacktestRun(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found is a valid outcome, not an error.
+	}
+	return run, err
+}
+
+// ListBacktestRuns returns the latest backtest runs, newest first, of a symbol or, when it is empty,
+// of all symbols. At most limit runs are returned; zero returns all of them.
+func (s *SqliteRepository) ListBacktestRuns(ctx context.Context, symbol string, limit int) ([]domain.BacktestRun, error) {
+	query := `SELECT ` + backtestRunColumns + ` FROM backtest_runs WHERE ? = '' OR symbol = ? ORDER BY id DESC`
+	args := []any{symbol, symbol}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query backtest runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []domain.BacktestRun
+	for rows.Next() {
+		run, err := scanBacktestRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, rows.Err()
+}
+
+// scanBacktestRun scans a row of backtestRunColumns. It returns sql.ErrNoRows unwrapped.
+func scanBacktestRun(row interface{ Scan(dest ...any) error }) (*domain.BacktestRun, error) {
+	var run domain.BacktestRun
+	var createdAt, from, to int64
+	var params, timeframe, metrics, trades string
+	err := row.Scan(&run.ID, &createdAt, &run.Strategy, &run.DefinitionHash, &params, &run.Symbol, &timeframe,
+		&from, &to, &run.EngineVersion, &metrics, &trades)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not scan backtest run row: %w", err)
+	}
+	if err := json.Unmarshal([]byte(params), &run.Params); err != nil {
+		return nil, fmt.Errorf("could not decode parameters of backtest run %d: %w", run.ID, err)
+	}
+	run.CreatedAt, run.From, run.To = time.UnixMilli(createdAt).UTC(), time.UnixMilli(from).UTC(), time.UnixMilli(to).UTC()
+	run.Timeframe = domain.Timeframe(timeframe)
+	run.Metrics, run.Trades = json.RawMessage(metrics), json.RawMessage(trades)
+	return &run, nil
+}
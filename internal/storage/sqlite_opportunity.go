@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// opportunitiesTable stores the trading opportunities and their lifecycle state.
+// The signal an opportunity was created from is stored in the signals table.
+const opportunitiesTable = `
+	CREATE TABLE IF NOT EXISTS opportunities (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		signal_id INTEGER NOT NULL REFERENCES signals (id),
+		entry_max_base REAL NOT NULL,
+		entry_max_quote REAL NOT NULL,
+		chart_trend TEXT NOT NULL,
+		market_exchange TEXT NOT NULL,
+		market_trend TEXT NOT NULL,
+		market_breadth REAL,
+		wghm TEXT NOT NULL,
+		status TEXT NOT NULL,
+		age INTEGER NOT NULL,
+		expire_after INTEGER NOT NULL,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);`
+
+// SaveOpportunity inserts a new opportunity and returns its ID. Its signal must have been saved first.
+func (s *SqliteRepository) SaveOpportunity(ctx context.Context, o domain.Opportunity) (int64, error) {
+	if o.Signal.ID == 0 {
+		return 0, fmt.Errorf("could not insert opportunity: its signal has not been saved")
+	}
+	query := `
+	INSERT INTO opportunities (signal_id, entry_max_base, entry_max_quote, chart_trend,
+		market_exchange, market_trend, market_breadth, wghm, status, age, expire_after, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query,
+		o.Signal.ID, o.EntryMax.Base, o.EntryMax.Quote, string(o.ChartTrend),
+		o.MarketExchange, string(o.MarketTrend), nullFloat(o.MarketBreadth), o.WGHM,
+		string(o.Status), o.Age, o.ExpireAfter, o.CreatedAt.UnixMilli(), o.UpdatedAt.UnixMilli(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("could not insert opportunity: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// UpdateOpportunity stores the lifecycle state of an opportunity: its status, age and entry maximum.
+func (s *SqliteRepository) UpdateOpportunity(ctx context.Context, o domain.Opportunity) error {
+	query := `
+	UPDATE opportunities
+	SET status = ?, age = ?, entry_max_base = ?, entry_max_quote = ?, updated_at = ?
+	WHERE id = ?;`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query,
+		string(o.Status), o.Age, o.EntryMax.Base, o.EntryMax.Quote, o.UpdatedAt.UnixMilli(), o.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("could not update opportunity: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("could not update opportunity: no opportunity with id %d", o.ID)
+	}
+	return nil
+}
+
+// ListOpportunities returns the opportunities with one of the given statuses, oldest first.
+// Without statuses all opportunities are returned.
+func (s *SqliteRepository) ListOpportunities(ctx context.Context, statuses ...domain.OpportunityStatus) ([]domain.Opportunity, error) {
+	query := `
+	SELECT o.id, o.entry_max_base, o.entry_max_quote, o.chart_trend, o.market_exchange, o.market_trend,
+		o.market_breadth, o.wghm, o.status, o.age, o.expire_after, o.created_at, o.updated_at,
+		` + signalColumns + `
+	FROM opportunities o JOIN signals s ON s.id = o.signal_id`
+	args := make([]any, len(statuses))
+	if len(statuses) > 0 {
+		query += ` WHERE o.status IN (?` + strings.Repeat(", ?", len(statuses)-1) + `)`
+		for i, st := range statuses {
+			args[i] = string(st)
+		}
+	}
+	query += ` ORDER BY o.created_at, o.id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query opportunities: %w", err)
+	}
+	defer rows.Close()
+
+	var opportunities []domain.Opportunity
+	for rows.Next() {
+		var o domain.Opportunity
+		var chartTrend, marketTrend, status string
+		var breadth sql.NullFloat64
+		var createdAt, updatedAt int64
+		var sig signalRow
+		dest := append([]any{
+			&o.ID, &o.EntryMax.Base, &o.EntryMax.Quote, &chartTrend, &o.MarketExchange, &marketTrend,
+			&breadth, &o.WGHM, &status, &o.Age, &o.ExpireAfter, &createdAt, &updatedAt,
+		}, sig.dest()...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("could not scan opportunity row: %w", err)
+		}
+		o.Signal = sig.signal()
+		o.ChartTrend = domain.Trend(chartTrend)
+		o.MarketTrend = domain.Trend(marketTrend)
+		o.MarketBreadth = floatOrNaN(breadth)
+		o.Status = domain.OpportunityStatus(status)
+		o.CreatedAt = time.UnixMilli(createdAt).UTC()
+		o.UpdatedAt = time.UnixMilli(updatedAt).UTC()
+		opportunities = append(opportunities, o)
+	}
+	return opportunities, rows.Err()
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// orderBooksTable stores order book snapshots; the levels of each side are stored as JSON arrays of
+// [price, quantity] pairs.
+const orderBooksTable = `
+	CREATE TABLE IF NOT EXISTS order_books (
+		symbol TEXT NOT NULL,
+		time INTEGER NOT NULL,
+		bids TEXT NOT NULL,
+		asks TEXT NOT NULL,
+		PRIMARY KEY (symbol, time)
+	);`
+
+// SaveOrderBook saves an order book snapshot, replacing an earlier snapshot of the same moment.
+func (s *SqliteRepository) SaveOrderBook(ctx context.Context, book domain.OrderBook) error {
+	bids, err := json.Marshal(levelPairs(book.Bids))
+	if err != nil {
+		return fmt.Errorf("could not encode bids: %w", err)
+	}
+	asks, err := json.Marshal(levelPairs(book.Asks))
+	if err != nil {
+		return fmt.Errorf("could not encode asks: %w", err)
+	}
+	query := `INSERT OR REPLACE INTO order_books (symbol, time, bids, asks) VALUES (?, ?, ?, ?);`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err = s.db.ExecContext(ctx, query, book.Symbol, book.Time.UnixMilli(), string(bids), string(asks))
+	return err
+}
+
+// ListOrderBooks returns the order book snapshots of a symbol with a time in [from, to), oldest first.
+func (s *SqliteRepository) ListOrderBooks(ctx context.Context, symbol string, from, to time.Time) ([]domain.OrderBook, error) {
+	query := `
+	SELECT symbol, time, bids, asks
+	FROM order_books
+	WHERE symbol = ? AND time >= ? AND time < ?
+	ORDER BY time`
+
+	rows, err := s.db.QueryContext(ctx, query, symbol, from.UnixMilli(), to.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("could not query order books: %w", err)
+	}
+	defer rows.Close()
+
+	var books []domain.OrderBook
+	for rows.Next() {
+		var book domain.OrderBook
+		var at int64
+		var bids, asks string
+		if err := rows.Scan(&book.Symbol, &at, &bids, &asks); err != nil {
+			return nil, fmt.Errorf("could not scan order book row: %w", err)
+		}
+		book.Time = time.UnixMilli(at).UTC()
+		if book.Bids, err = parseLevelPairs(bids); err != nil {
+			return nil, fmt.Errorf("could not decode bids: %w", err)
+		}
+		if book.Asks, err = parseLevelPairs(asks); err != nil {
+			return nil, fmt.Errorf("could not decode asks: %w", err)
+		}
+		books = append(books, book)
+	}
+	return books, rows.Err()
+}
+
+func levelPairs(levels []domain.PriceLevel) [][2]float64 {
+	pairs := make([][2]float64, len(levels))
+	for i, l := range levels {
+		pairs[i] = [2]float64{l.Price, l.Quantity}
+	}
+	return pairs
+}
+
+func parseLevelPairs(s string) ([]domain.PriceLevel, error) {
+	var pairs [][2]float64
+	if err := json.Unmarshal([]byte(s), &pairs); err != nil {
+		return nil, err
+	}
+	levels := make([]domain.PriceLevel, len(pairs))
+	for i, p := range pairs {
+		levels[i] = domain.PriceLevel{Price: p[0], Quantity: p[1]}
+	}
+	return levels, nil
+}
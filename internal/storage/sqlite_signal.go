@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// signalsTable stores strategy signals together with their metrics snapshot.
+const signalsTable = `
+	CREATE TABLE IF NOT EXISTS signals (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		strategy TEXT NOT NULL,
+		exchange TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		timeframe TEXT NOT NULL,
+		direction TEXT NOT NULL,
+		signal_time INTEGER NOT NULL,
+		price REAL NOT NULL,
+		candle_time INTEGER NOT NULL,
+		bb_width REAL,
+		stoch_k REAL,
+		stoch_d REAL,
+		volume_24h REAL,
+		change_24h REAL,
+		plan_entry REAL,
+		plan_stop_loss REAL,
+		plan_take_profit REAL,
+		plan_size_kind TEXT,
+		plan_size_value REAL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+
+// SaveSignal saves a signal with its metrics snapshot and trade plan and returns the new signal ID.
+func (s *SqliteRepository) SaveSignal(ctx context.Context, signal domain.Signal) (int64, error) {
+	query := `
+	INSERT INTO signals (strategy, exchange, symbol, timeframe, direction, signal_time, price,
+		candle_time, bb_width, stoch_k, stoch_d, volume_24h, change_24h,
+		plan_entry, plan_stop_loss, plan_take_profit, plan_size_kind, plan_size_value)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var entry, stopLoss, takeProfit, sizeValue sql.NullFloat64
+	var sizeKind sql.NullString
+	if p := signal.Plan; p != nil {
+		entry, stopLoss, takeProfit = nullFloat(p.Entry), nullFloat(p.StopLoss), nullFloat(p.TakeProfit)
+		sizeKind = sql.NullString{String: string(p.Size.Kind), Valid: true}
+		sizeValue = nullFloat(p.Size.Value)
+	}
+
+	m := signal.Metrics
+	res, err := s.db.ExecContext(ctx, query,
+		signal.Strategy, signal.Exchange, signal.Symbol, string(signal.Timeframe), string(signal.Direction),
+		signal.Time.UnixMilli(), signal.Price, m.CandleTime.UnixMilli(),
+		nullFloat(m.BBWidth), nullFloat(m.StochK), nullFloat(m.StochD), nullFloat(m.Volume24h), nullFloat(m.Change24h),
+		entry, stopLoss, takeProfit, sizeKind, sizeValue,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("could not insert signal: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// signalColumns are the columns of a signal row, in the order of signalRow.dest.
+const signalColumns = `s.id, s.strategy, s.exchange, s.symbol, s.timeframe, s.direction, s.signal_time, s.price,
+		s.candle_time, s.bb_width, s.stoch_k, s.stoch_d, s.volume_24h, s.change_24h,
+		s.plan_entry, s.plan_stop_loss, s.plan_take_profit, s.plan_size_kind, s.plan_size_value`
+
+// ListSignals returns the signals for a symbol that fired at or after since, oldest first.
+// An empty symbol returns the signals of all symbols.
+func (s *SqliteRepository) ListSignals(ctx context.Context, symbol string, since time.Time) ([]domain.Signal, error) {
+	query := `
+	SELECT ` + signalColumns + `
+	FROM signals s
+	WHERE (? = '' OR s.symbol = ?) AND s.signal_time >= ?
+	ORDER BY s.signal_time, s.id`
+
+	rows, err := s.db.QueryContext(ctx, query, symbol, symbol, since.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("could not query signals: %w", err)
+	}
+	defer rows.Close()
+
+	var signals []domain.Signal
+	for rows.Next() {
+		var row signalRow
+		if err := rows.Scan(row.dest()...); err != nil {
+			return nil, fmt.Errorf("could not scan signal row: %w", err)
+		}
+		signals = append(signals, row.signal())
+	}
+	return signals, rows.Err()
+}
+
+// signalRow holds the columns of a signal while it is scanned.
+type signalRow struct {
+	sig                                     domain.Signal
+	timeframe, direction                    string
+	signalTime, candleTime                  int64
+	bbWidth, stochK, stochD, volume, change sql.NullFloat64
+	entry, stopLoss, takeProfit, sizeValue  sql.NullFloat64
+	sizeKind                                sql.NullString
+}
+
+// dest returns the scan destinations for signalColumns.
+func (r *signalRow) dest() []any {
+	return []any{
+		&r.sig.ID, &r.sig.Strategy, &r.sig.Exchange, &r.sig.Symbol, &r.timeframe, &r.direction, &r.signalTime, &r.sig.Price,
+		&r.candleTime, &r.bbWidth, &r.stochK, &r.stochD, &r.volume, &r.change,
+		&r.entry, &r.stopLoss, &r.takeProfit, &r.sizeKind, &r.sizeValue,
+	}
+}
+
+// signal converts the scanned columns into a signal.
+func (r *signalRow) signal() domain.Signal {
+	sig := r.sig
+	sig.Timeframe = domain.Timeframe(r.timeframe)
+	sig.Direction = domain.Direction(r.direction)
+	sig.Time = time.UnixMilli(r.signalTime).UTC()
+	sig.Metrics = domain.SignalMetrics{
+		CandleTime: time.UnixMilli(r.candleTime).UTC(),
+		BBWidth:    floatOrNaN(r.bbWidth),
+		StochK:     floatOrNaN(r.stochK),
+		StochD:     floatOrNaN(r.stochD),
+		Volume24h:  floatOrNaN(r.volume),
+		Change24h:  floatOrNaN(r.change),
+	}
+	if r.sizeKind.Valid {
+		sig.Plan = &domain.TradePlan{
+			Entry:      floatOrNaN(r.entry),
+			StopLoss:   floatOrNaN(r.stopLoss),
+			TakeProfit: floatOrNaN(r.takeProfit),
+			Size:       domain.SizeRule{Kind: domain.SizeKind(r.sizeKind.String), Value: floatOrNaN(r.sizeValue)},
+		}
+	}
+	return sig
+}
+
+// nullFloat stores NaN (an indicator still warming up) as NULL.
+func nullFloat(v float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: v, Valid: !math.IsNaN(v)}
+}
+
+// floatOrNaN is the inverse of nullFloat.
+func floatOrNaN(v sql.NullFloat64) float64 {
+	if !v.Valid {
+		return math.NaN()
+	}
+	return v.Float64
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// chartTrendsTable stores the latest chart trend state per symbol and timeframe.
+const chartTrendsTable = `
+	CREATE TABLE IF NOT EXISTS chart_trends (
+		symbol TEXT NOT NULL,
+		timeframe TEXT NOT NULL,
+		trend TEXT NOT NULL,
+		strength REAL NOT NULL,
+		fast_ema REAL NOT NULL,
+		slow_ema REAL NOT NULL,
+		samples INTEGER NOT NULL,
+		candle_time INTEGER NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (symbol, timeframe)
+	);`
+
+// SaveChartTrend creates or replaces the chart trend state of a symbol and timeframe.
+func (s *SqliteRepository) SaveChartTrend(ctx context.Context, t domain.ChartTrend) error {
+	query := `
+	INSERT INTO chart_trends (symbol, timeframe, trend, strength, fast_ema, slow_ema, samples, candle_time)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (symbol, timeframe) DO UPDATE SET
+		trend = excluded.trend,
+		strength = excluded.strength,
+		fast_ema = excluded.fast_ema,
+		slow_ema = excluded.slow_ema,
+		samples = excluded.samples,
+		candle_time = excluded.candle_time,
+		updated_at = CURRENT_TIMESTAMP;`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query,
+		t.Symbol, string(t.Timeframe), string(t.Trend), t.Strength, t.FastEMA, t.SlowEMA, t.Samples, t.CandleTime.UnixMilli(),
+	)
+	return err
+}
+
+// LoadChartTrends returns the stored chart trend states of a symbol.
+func (s *SqliteRepository) LoadChartTrends(ctx context.Context, symbol string) ([]domain.ChartTrend, error) {
+	query := `
+	SELECT symbol, timeframe, trend, strength, fast_ema, slow_ema, samples, candle_time
+	FROM chart_trends WHERE symbol = ?`
+
+	rows, err := s.db.QueryContext(ctx, query, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("could not query chart trends: %w", err)
+	}
+	defer rows.Close()
+
+	var trends []domain.ChartTrend
+	for rows.Next() {
+		var t domain.ChartTrend
+		var timeframe, trend string
+		var candleTime int64
+		if err := rows.Scan(&t.Symbol, &timeframe, &trend, &t.Strength, &t.FastEMA, &t.SlowEMA, &t.Samples, &candleTime); err != nil {
+			return nil, fmt.Errorf("could not scan chart trend row: %w", err)
+		}
+		t.Timeframe = domain.Timeframe(timeframe)
+		t.Trend = domain.Trend(trend)
+		t.CandleTime = time.UnixMilli(candleTime).UTC()
+		trends = append(trends, t)
+	}
+	return trends, rows.Err()
+}
+
+// marketTrendsTable stores the market trend time series per exchange and timeframe.
+const marketTrendsTable = `
+	CREATE TABLE IF NOT EXISTS market_trends (
+		exchange TEXT NOT NULL,
+		timeframe TEXT NOT NULL,
+		period_end INTEGER NOT NULL,
+		pairs INTEGER NOT NULL,
+		advancing INTEGER NOT NULL,
+		declining INTEGER NOT NULL,
+		breadth REAL NOT NULL,
+		volume_weighted_change REAL NOT NULL,
+		trend TEXT NOT NULL,
+		PRIMARY KEY (exchange, timeframe, period_end)
+	);`
+
+// SaveMarketTrend saves the market trend of one period, replacing an earlier result for the same period.
+func (s *SqliteRepository) SaveMarketTrend(ctx context.Context, m domain.MarketTrend) error {
+	query := `
+	INSERT OR REPLACE INTO market_trends (exchange, timeframe, period_end, pairs, advancing, declining,
+		breadth, volume_weighted_change, trend)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query,
+		m.Exchange, string(m.Timeframe), m.Time.UnixMilli(), m.Pairs, m.Advancing, m.Declining,
+		m.Breadth, m.VolumeWeightedChange, string(m.Trend),
+	)
+	return err
+}
+
+// ListMarketTrends returns the market trends of an exchange and timeframe with a period end in [from, to).
+func (s *SqliteRepository) ListMarketTrends(ctx context.Context, exchange string, tf domain.Timeframe, from, to time.Time) ([]domain.MarketTrend, error) {
+	query := `
+	SELECT exchange, timeframe, period_end, pairs, advancing, declining, breadth, volume_weighted_change, trend
+	FROM market_trends
+	WHERE exchange = ? AND timeframe = ? AND period_end >= ? AND period_end < ?
+	ORDER BY period_end`
+
+	rows, err := s.db.QueryContext(ctx, query, exchange, string(tf), from.UnixMilli(), to.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("could not query market trends: %w", err)
+	}
+	defer rows.Close()
+
+	var trends []domain.MarketTrend
+	for rows.Next() {
+		var m domain.MarketTrend
+		var timeframe, trend string
+		var periodEnd int64
+		if err := rows.Scan(&m.Exchange, &timeframe, &periodEnd, &m.Pairs, &m.Advancing, &m.Declining,
+			&m.Breadth, &m.VolumeWeightedChange, &trend); err != nil {
+			return nil, fmt.Errorf("could not scan market trend row: %w", err)
+		}
+		m.Timeframe = domain.Timeframe(timeframe)
+		m.Time = time.UnixMilli(periodEnd).UTC()
+		m.Trend = domain.Trend(trend)
+		trends = append(trends, m)
+	}
+	return trends, rows.Err()
+}
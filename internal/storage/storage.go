@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
 )
@@ -12,3 +13,52 @@ type Repository interface {
 	GetTickerByEventTime(ctx context.Context, eventTime int64) (*domain.Ticker, error)
 	Close() error
 }
+
+// SignalRepository defines the interface for persisting strategy signals and their metrics.
+type SignalRepository interface {
+	SaveSignal(ctx context.Context, signal domain.Signal) (int64, error)
+	ListSignals(ctx context.Context, symbol string, since time.Time) ([]domain.Signal, error)
+}
+
+// TickerHistory defines the interface for reading stored tickers back, e.g. to rebuild candles.
+type TickerHistory interface {
+	ListTickers(ctx context.Context, symbol string, from, to time.Time) ([]domain.Ticker, error)
+}
+
+// StoredTickerHistory defines the interface for reading stored tickers back in the order they were
+// stored, e.g. to audit the data quality.
+type StoredTickerHistory interface {
+	ListTickersAsStored(ctx context.Context, symbol string, from, to time.Time) ([]domain.Ticker, error)
+}
+
+// ChartTrendRepository defines the interface for persisting the chart trend state.
+type ChartTrendRepository interface {
+	SaveChartTrend(ctx context.Context, trend domain.ChartTrend) error
+	LoadChartTrends(ctx context.Context, symbol string) ([]domain.ChartTrend, error)
+}
+
+// MarketTrendRepository defines the interface for persisting the market trend time series.
+type MarketTrendRepository interface {
+	SaveMarketTrend(ctx context.Context, trend domain.MarketTrend) error
+	ListMarketTrends(ctx context.Context, exchange string, tf domain.Timeframe, from, to time.Time) ([]domain.MarketTrend, error)
+}
+
+// OpportunityRepository defines the interface for persisting trading opportunities and their lifecycle.
+type OpportunityRepository interface {
+	SaveOpportunity(ctx context.Context, o domain.Opportunity) (int64, error)
+	UpdateOpportunity(ctx context.Context, o domain.Opportunity) error
+	ListOpportunities(ctx context.Context, statuses ...domain.OpportunityStatus) ([]domain.Opportunity, error)
+}
+
+// OrderBookHistory defines the interface for persisting order book snapshots, e.g. for simulated fills.
+type OrderBookHistory interface {
+	SaveOrderBook(ctx context.Context, book domain.OrderBook) error
+	ListOrderBooks(ctx context.Context, symbol string, from, to time.Time) ([]domain.OrderBook, error)
+}
+
+// BacktestRunRepository defines the interface for persisting backtest runs to list and compare them.
+type BacktestRunRepository interface {
+	SaveBacktestRun(ctx context.Context, run domain.BacktestRun) (int64, error)
+	GetBacktestRun(ctx context.Context, id int64) (*domain.BacktestRun, error)
+	ListBacktestRuns(ctx context.Context, symbol string, limit int) ([]domain.BacktestRun, error)
+}
@@ -0,0 +1,165 @@
+package strategy
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy/expr"
+)
+
+// comparisons maps the comparison operators of a definition to their implementation.
+// Comparisons with a NaN operand (an indicator still warming up) are false.
+var comparisons = map[string]func(a, b operand) condition{
+	"<":  compare(func(a, b float64) bool { return a < b }),
+	"<=": compare(func(a, b float64) bool { return a <= b }),
+	">":  compare(func(a, b float64) bool { return a > b }),
+	">=": compare(func(a, b float64) bool { return a >= b }),
+	"==": compare(func(a, b float64) bool { return a == b }),
+	"!=": compare(func(a, b float64) bool { return a != b }),
+	"crosses_above": func(a, b operand) condition {
+		return func(e *evalContext) bool {
+			return crossed(a(e, 1), b(e, 1), a(e, 0), b(e, 0))
+		}
+	},
+	"crosses_below": func(a, b operand) condition {
+		return func(e *evalContext) bool {
+			return crossed(-a(e, 1), -b(e, 1), -a(e, 0), -b(e, 0))
+		}
+	},
+}
+
+func compare(cmp func(a, b float64) bool) func(a, b operand) condition {
+	return func(a, b operand) condition {
+		return func(e *evalContext) bool {
+			x, y := a(e, 0), b(e, 0)
+			return !math.IsNaN(x) && !math.IsNaN(y) && cmp(x, y)
+		}
+	}
+}
+
+// crossed reports whether a moved from at or below b to above b.
+func crossed(prevA, prevB, a, b float64) bool {
+	if math.IsNaN(prevA) || math.IsNaN(prevB) || math.IsNaN(a) || math.IsNaN(b) {
+		return false
+	}
+	return prevA <= prevB && a > b
+}
+
+// compileCondition compiles a condition tree, reporting every problem with its path.
+func compileCondition(env *compileEnv, path string, c Condition) (condition, ValidationErrors) {
+	set := 0
+	for _, ok := range []bool{c.All != nil, c.Any != nil, c.Not != nil, c.Op != "" || c.Left != nil || c.Right != nil, c.Expr != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		if set == 0 {
+			return nil, ValidationErrors{{Path: path, Message: "condition needs one of all, any, not, left/op/right or expr"}}
+		}
+		return nil, ValidationErrors{{Path: path, Message: "condition must set only one of all, any, not, left/op/right or expr"}}
+	}
+
+	switch {
+	case c.Expr != "":
+		prog, err := expr.CompileCondition(c.Expr, env.exprResolver)
+		if err != nil {
+			return nil, ValidationErrors{{Path: path, Message: err.Error()}}
+		}
+		env.require(prog.WarmUp())
+		return func(e *evalContext) bool { return prog.Bool(e.expr) }, nil
+	case c.All != nil, c.Any != nil:
+		list, name := c.All, "all"
+		if c.Any != nil {
+			list, name = c.Any, "any"
+		}
+		if len(list) == 0 {
+			return nil, ValidationErrors{{Path: path + "." + name, Message: "needs at least one condition"}}
+		}
+		var errs ValidationErrors
+		conds := make([]condition, 0, len(list))
+		for i, sub := range list {
+			cond, err := compileCondition(env, fmt.Sprintf("%s.%s[%d]", path, name, i), sub)
+			errs = append(errs, err...)
+			conds = append(conds, cond)
+		}
+		if len(errs) > 0 {
+			return nil, errs
+		}
+		if name == "all" {
+			return allOf(conds...), nil
+		}
+		return anyOf(conds...), nil
+	case c.Not != nil:
+		cond, errs := compileCondition(env, path+".not", *c.Not)
+		if len(errs) > 0 {
+			return nil, errs
+		}
+		return func(e *evalContext) bool { return !cond(e) }, nil
+	}
+
+	var errs ValidationErrors
+	cmp, ok := comparisons[c.Op]
+	if !ok {
+		errs = append(errs, ValidationError{Path: path + ".op", Message: fmt.Sprintf("unknown operator %q, expected one of %s", c.Op, strings.Join(sortedKeys(comparisons), ", "))})
+	}
+	left, err := compileOperand(env, c.Left)
+	if err != nil {
+		errs = append(errs, ValidationError{Path: path + ".left", Message: err.Error()})
+	}
+	right, err := compileOperand(env, c.Right)
+	if err != nil {
+		errs = append(errs, ValidationError{Path: path + ".right", Message: err.Error()})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return cmp(left, right), nil
+}
+
+// compileOperand compiles a number, a series name or a numeric expression.
+func compileOperand(env *compileEnv, raw json.RawMessage) (operand, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("is required")
+	}
+	var number float64
+	if err := json.Unmarshal(raw, &number); err == nil {
+		return func(*evalContext, int) float64 { return number }, nil
+	}
+	var src string
+	if err := json.Unmarshal(raw, &src); err != nil {
+		return nil, fmt.Errorf("must be a number, a series name or an expression, got %s", raw)
+	}
+	prog, err := expr.CompileNumber(src, env.exprResolver)
+	if err != nil {
+		return nil, err
+	}
+	env.require(prog.WarmUp())
+	return func(e *evalContext, back int) float64 { return prog.NumberAt(e.expr, back) }, nil
+}
+
+// allOf combines conditions that must all hold.
+func allOf(conds ...condition) condition {
+	return func(e *evalContext) bool {
+		for _, c := range conds {
+			if !c(e) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// anyOf combines conditions of which at least one must hold.
+func anyOf(conds ...condition) condition {
+	return func(e *evalContext) bool {
+		for _, c := range conds {
+			if c(e) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,130 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/trend"
+)
+
+// Confirmation is a condition on a higher timeframe, or on the market trend, that must hold for an
+// entry, for example
+//
+//	{"timeframe": "1h", "trend": "bullish"}
+//	{"timeframe": "4h", "condition": "close > ema(50)"}
+//	{"timeframe": "1h", "market": "bullish"}
+//
+// It is evaluated on the last candle of that timeframe that closed at or before the entry candle,
+// never on the candle that is still open. Until the higher timeframe has enough closed candles
+// the confirmation does not hold. A trend confirmation holds on the chart trend of the input's
+// Trends, the trend on the opportunity cards; a strategy run without them recomputes it from the
+// candles. A market confirmation holds on the market trend of the last completed period of its
+// timeframe; it does not hold while the market trend is not tracked.
+type Confirmation struct {
+	Timeframe string `json:"timeframe"`
+	// Trend is the required chart trend: bullish, bearish or neutral.
+	Trend string `json:"trend,omitempty"`
+	// Market is the required market trend: bullish, bearish or neutral.
+	Market    string     `json:"market,omitempty"`
+	Condition *Condition `json:"condition,omitempty"`
+}
+
+// compiledConfirmation is a validated Confirmation.
+type compiledConfirmation struct {
+	timeframe domain.Timeframe
+	trend     domain.Trend // Empty when the confirmation is not on the chart trend.
+	market    domain.Trend // Empty when the confirmation is not on the market trend.
+	cond      condition
+	warmUp    int
+}
+
+// compileConfirmation validates a confirmation against the timeframes the strategy runs on.
+func compileConfirmation(path string, c Confirmation, timeframes []domain.Timeframe, indicators []indicatorSpec) (compiledConfirmation, ValidationErrors) {
+	var errs ValidationErrors
+	add := func(path, format string, args ...any) {
+		errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var out compiledConfirmation
+	tf, err := domain.ParseTimeframe(c.Timeframe)
+	if err != nil {
+		add(path+".timeframe", "%v", err)
+	}
+	out.timeframe = tf
+
+	kinds := 0
+	for _, set := range []bool{c.Trend != "", c.Market != "", c.Condition != nil} {
+		if set {
+			kinds++
+		}
+	}
+	switch {
+	case kinds != 1:
+		add(path, "needs exactly one of a trend, a market trend or a condition")
+	case c.Market != "":
+		if err == nil && !marketTimeframe(tf) {
+			add(path+".timeframe", "the market trend is not tracked on %s", tf)
+		}
+		switch t := domain.Trend(c.Market); t {
+		case domain.Bullish, domain.Bearish, domain.Neutral:
+			out.market = t
+		default:
+			add(path+".market", "must be bullish, bearish or neutral, got %q", c.Market)
+		}
+	case c.Trend != "":
+		switch t := domain.Trend(c.Trend); t {
+		case domain.Bullish, domain.Bearish, domain.Neutral:
+			out.trend = t
+			out.warmUp = trend.DefaultChartConfig().SlowPeriod
+		default:
+			add(path+".trend", "must be bullish, bearish or neutral, got %q", c.Trend)
+		}
+	default:
+		env := newCompileEnv(indicators)
+		cond, cerrs := compileCondition(env, path+".condition", *c.Condition)
+		errs = append(errs, cerrs...)
+		out.cond = cond
+		out.warmUp = env.warmUp + 1
+	}
+	// The market trend is not built from the candles of the pair, any tracked timeframe will do.
+	for _, base := range timeframes {
+		if err == nil && c.Market == "" && tf.Duration() <= base.Duration() {
+			add(path+".timeframe", "must be higher than the strategy timeframe %s, got %s", base, tf)
+		}
+	}
+	return out, errs
+}
+
+// marketTimeframe reports whether the scanner tracks the market trend on a timeframe.
+func marketTimeframe(tf domain.Timeframe) bool {
+	for _, mtf := range trend.DefaultMarketConfig().Timeframes {
+		if mtf == tf {
+			return true
+		}
+	}
+	return false
+}
+
+// holds evaluates the confirmation on the aligned candles of its timeframe, or on the market trend.
+func (c compiledConfirmation) holds(in Input, indicators []indicatorSpec) bool {
+	if c.market != "" {
+		if in.Trends == nil {
+			return false
+		}
+		m, ok := in.Trends.MarketTrend(c.timeframe)
+		return ok && !m.Time.After(in.Last().CloseTime) && m.Trend == c.market
+	}
+	if c.trend != "" && in.Trends != nil {
+		t, ok := in.Trends.ChartTrend(in.Symbol, c.timeframe)
+		return ok && t.Samples >= c.warmUp && !t.CandleTime.After(in.Last().CloseTime) && t.Trend == c.trend
+	}
+	candles := in.Higher[c.timeframe]
+	if len(candles) < c.warmUp {
+		return false
+	}
+	if c.trend != "" {
+		history := trend.ChartHistory(trend.DefaultChartConfig(), candles)
+		return history[len(history)-1].Trend == c.trend
+	}
+	return c.cond(newEvalContext(candles, in.Ticker, indicators))
+}
@@ -0,0 +1,104 @@
+package strategy
+
+import (
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// compiledDefinition is a validated definition, shared by all strategy instances created from it.
+type compiledDefinition struct {
+	name       string
+	short      bool
+	indicators []indicatorSpec
+	filters    []condition
+	entry      condition
+	exit       condition
+	confirms   []compiledConfirmation
+	trigger    *Trigger // Nil to use the trigger rules of the runner.
+	warmUp     int
+}
+
+// definedStrategy runs a compiled definition. It remembers whether it is in a position, so the
+// exit condition only signals after an entry and the entry does not repeat while in a position.
+type definedStrategy struct {
+	compiled *compiledDefinition
+	inTrade  bool
+}
+
+// Name implements Strategy.
+func (s *definedStrategy) Name() string {
+	return s.compiled.name
+}
+
+// WarmUp implements Strategy.
+func (s *definedStrategy) WarmUp() int {
+	return s.compiled.warmUp
+}
+
+// HigherTimeframes implements MultiTimeframe.
+func (s *definedStrategy) HigherTimeframes() map[domain.Timeframe]int {
+	if len(s.compiled.confirms) == 0 {
+		return nil
+	}
+	higher := make(map[domain.Timeframe]int)
+	for _, c := range s.compiled.confirms {
+		if c.market == "" {
+			higher[c.timeframe] = max(higher[c.timeframe], c.warmUp)
+		}
+	}
+	if len(higher) == 0 {
+		return nil
+	}
+	return higher
+}
+
+// triggeredStrategy is a definedStrategy with trigger rules of its own; a definition without
+// them gets the rules of the runner.
+type triggeredStrategy struct {
+	*definedStrategy
+}
+
+// Trigger implements Triggered.
+func (s triggeredStrategy) Trigger() Trigger {
+	return *s.compiled.trigger
+}
+
+// OnCandle implements Strategy.
+func (s *definedStrategy) OnCandle(in Input) []domain.Signal {
+	c := s.compiled
+	e := newEvalContext(in.Candles, in.Ticker, c.indicators)
+
+	entry, exit := domain.Buy, domain.Sell
+	if c.short {
+		entry, exit = domain.Sell, domain.Buy
+	}
+
+	if s.inTrade {
+		if c.exit != nil && c.exit(e) {
+			s.inTrade = false
+			return []domain.Signal{NewSignal(s, in, exit)}
+		}
+		return nil
+	}
+	for _, f := range c.filters {
+		if !f(e) {
+			return nil
+		}
+	}
+	if !c.entry(e) {
+		return nil
+	}
+	// The higher timeframes are only checked for an entry, they are the most expensive part.
+	for _, conf := range c.confirms {
+		if !conf.holds(in, c.indicators) {
+			return nil
+		}
+	}
+	// Without an exit condition every entry is a separate signal.
+	s.inTrade = c.exit != nil
+	return []domain.Signal{NewSignal(s, in, entry)}
+}
+
+// OnTicker implements Strategy. Defined strategies only signal on closed candles.
+func (s *definedStrategy) OnTicker(in Input, ticker domain.Ticker) []domain.Signal {
+	return nil
+}
@@ -0,0 +1,296 @@
+package strategy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// Definition is a strategy described in a JSON file instead of in Go code.
+//
+//	{
+//	  "name": "Stoch & Bollinger Bands",
+//	  "timeframes": ["1m"],
+//	  "side": "long",
+//	  "indicators": {
+//	    "bb":    {"type": "bollinger", "params": {"period": 20, "stddev": 2}},
+//	    "stoch": {"type": "stochastic", "params": {"period": 14, "smooth": 1, "d": 3}}
+//	  },
+//	  "filters": [{"left": "bb.width", "op": ">=", "right": 1}],
+//	  "entry": {"all": [
+//	    {"left": "low", "op": "<=", "right": "bb.lower"},
+//	    {"left": "stoch.k", "op": "crosses_above", "right": "stoch.d"}
+//	  ]},
+//	  "exit": {"left": "high", "op": ">=", "right": "bb.upper"},
+//	  "confirm": [{"timeframe": "1h", "trend": "bullish"}],
+//	  "trigger": {"mode": "edge", "cooldown": "15m", "rearm_after": 3}
+//	}
+//
+// A long strategy signals a buy when the entry condition is met and a sell when the exit condition
+// is met after an entry; a short strategy does the opposite. Filters and the confirmations on
+// higher timeframes must all hold for an entry.
+type Definition struct {
+	Name       string                  `json:"name"`
+	Timeframes []string                `json:"timeframes"`
+	Side       string                  `json:"side"`
+	Indicators map[string]IndicatorDef `json:"indicators"`
+	Filters    []Condition             `json:"filters"`
+	Entry      *Condition              `json:"entry"`
+	Exit       *Condition              `json:"exit"`
+	Confirm    []Confirmation          `json:"confirm"`
+	Trigger    *TriggerDef             `json:"trigger"`
+}
+
+// IndicatorDef declares a named indicator and its parameters.
+type IndicatorDef struct {
+	Type   string             `json:"type"`
+	Params map[string]float64 `json:"params"`
+}
+
+// TriggerDef sets the trigger rules of a defined strategy; see Trigger. Omitted fields keep the
+// defaults of DefaultTrigger.
+type TriggerDef struct {
+	Mode       string `json:"mode"`
+	Cooldown   string `json:"cooldown"`
+	ReArmAfter *int   `json:"rearm_after"`
+}
+
+// Condition is a node of a condition tree: exactly one of All, Any, Not, a comparison or an
+// expression. Operands of a comparison are a number, the name of a series or a numeric expression:
+// a price field (open, high, low, close, volume), a ticker field (volume_24h, change_24h), an
+// indicator output such as "rsi" or "bb.lower", or something like "close[1]" or "ema(200)".
+//
+// In JSON a condition can also be written as an expression string, for example
+//
+//	"entry": "rsi < 30 and close > ema(200) and crosses_above(stoch.k, stoch.d)"
+//
+// See package expr for the expression syntax.
+type Condition struct {
+	All   []Condition     `json:"all,omitempty"`
+	Any   []Condition     `json:"any,omitempty"`
+	Not   *Condition      `json:"not,omitempty"`
+	Left  json.RawMessage `json:"left,omitempty"`
+	Op    string          `json:"op,omitempty"`
+	Right json.RawMessage `json:"right,omitempty"`
+	Expr  string          `json:"expr,omitempty"`
+}
+
+// UnmarshalJSON accepts a condition object or an expression string.
+func (c *Condition) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '"' {
+		*c = Condition{}
+		return json.Unmarshal(trimmed, &c.Expr)
+	}
+	type plain Condition
+	var p plain
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return err
+	}
+	*c = Condition(p)
+	return nil
+}
+
+// ValidationError describes one problem in a strategy definition.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors collects all problems found in a strategy definition.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "invalid strategy definition:\n  " + strings.Join(msgs, "\n  ")
+}
+
+// LoadDefinition reads and validates a strategy definition file.
+func LoadDefinition(path string) (*Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read strategy definition: %w", err)
+	}
+	return ParseDefinition(data)
+}
+
+// ParseDefinition parses and validates a strategy definition.
+func ParseDefinition(data []byte) (*Definition, error) {
+	var def Definition
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&def); err != nil {
+		return nil, fmt.Errorf("could not parse strategy definition: %w", err)
+	}
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// LoadDefinitions loads all *.json strategy definitions in a directory, sorted by file name.
+func LoadDefinitions(dir string) ([]*Definition, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var defs []*Definition
+	for _, path := range paths {
+		def, err := LoadDefinition(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+// ParsedTimeframes returns the timeframes of the definition. It must only be called on a valid definition.
+func (d *Definition) ParsedTimeframes() []domain.Timeframe {
+	tfs := make([]domain.Timeframe, 0, len(d.Timeframes))
+	for _, s := range d.Timeframes {
+		tf, _ := domain.ParseTimeframe(s)
+		tfs = append(tfs, tf)
+	}
+	return tfs
+}
+
+// Validate checks the definition and reports every problem it finds.
+func (d *Definition) Validate() error {
+	_, err := d.compile()
+	return err
+}
+
+// Factory compiles the definition into a strategy factory.
+func (d *Definition) Factory() (Factory, error) {
+	c, err := d.compile()
+	if err != nil {
+		return nil, err
+	}
+	if c.trigger != nil {
+		return func() Strategy { return triggeredStrategy{&definedStrategy{compiled: c}} }, nil
+	}
+	return func() Strategy { return &definedStrategy{compiled: c} }, nil
+}
+
+// compile validates the definition and compiles its indicators and conditions.
+func (d *Definition) compile() (*compiledDefinition, error) {
+	var errs ValidationErrors
+	add := func(path, format string, args ...any) {
+		errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(d.Name) == "" {
+		add("name", "is required")
+	}
+	if len(d.Timeframes) == 0 {
+		add("timeframes", "at least one timeframe is required")
+	}
+	var timeframes []domain.Timeframe
+	for i, s := range d.Timeframes {
+		tf, err := domain.ParseTimeframe(s)
+		if err != nil {
+			add(fmt.Sprintf("timeframes[%d]", i), "%v", err)
+			continue
+		}
+		timeframes = append(timeframes, tf)
+	}
+
+	c := &compiledDefinition{name: d.Name}
+	switch d.Side {
+	case "", "long":
+	case "short":
+		c.short = true
+	default:
+		add("side", "must be \"long\" or \"short\", got %q", d.Side)
+	}
+
+	names := make([]string, 0, len(d.Indicators))
+	for name := range d.Indicators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ind, err := newIndicatorSpec(name, d.Indicators[name])
+		if err != nil {
+			add("indicators."+name, "%v", err)
+			continue
+		}
+		c.indicators = append(c.indicators, ind)
+	}
+
+	env := newCompileEnv(c.indicators)
+	if d.Entry == nil {
+		add("entry", "is required")
+	} else if cond, err := compileCondition(env, "entry", *d.Entry); err != nil {
+		errs = append(errs, err...)
+	} else {
+		c.entry = cond
+	}
+	if d.Exit != nil {
+		if cond, err := compileCondition(env, "exit", *d.Exit); err != nil {
+			errs = append(errs, err...)
+		} else {
+			c.exit = cond
+		}
+	}
+	for i, f := range d.Filters {
+		if cond, err := compileCondition(env, fmt.Sprintf("filters[%d]", i), f); err != nil {
+			errs = append(errs, err...)
+		} else {
+			c.filters = append(c.filters, cond)
+		}
+	}
+
+	if d.Trigger != nil {
+		t := DefaultTrigger()
+		switch mode := TriggerMode(d.Trigger.Mode); mode {
+		case "":
+		case EdgeTriggered, LevelTriggered:
+			t.Mode = mode
+		default:
+			add("trigger.mode", "must be \"edge\" or \"level\", got %q", d.Trigger.Mode)
+		}
+		if d.Trigger.Cooldown != "" {
+			if cooldown, err := time.ParseDuration(d.Trigger.Cooldown); err != nil || cooldown < 0 {
+				add("trigger.cooldown", "must be a duration like \"15m\", got %q", d.Trigger.Cooldown)
+			} else {
+				t.Cooldown = cooldown
+			}
+		}
+		if n := d.Trigger.ReArmAfter; n != nil {
+			if *n < 1 {
+				add("trigger.rearm_after", "must be at least 1, got %d", *n)
+			}
+			t.ReArmAfter = *n
+		}
+		c.trigger = &t
+	}
+	for i, conf := range d.Confirm {
+		cc, err := compileConfirmation(fmt.Sprintf("confirm[%d]", i), conf, timeframes, c.indicators)
+		errs = append(errs, err...)
+		c.confirms = append(c.confirms, cc)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	c.warmUp = env.warmUp + 1 // One extra candle for cross-over conditions.
+	return c, nil
+}
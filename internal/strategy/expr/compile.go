@@ -0,0 +1,228 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// value is a type checked, compiled expression node.
+type value struct {
+	typ      Type
+	num      Series
+	cond     func(c *Context, back int) bool
+	constant bool
+	warmUp   int
+	key      string // Canonical text of the expression, used to cache inline indicators.
+}
+
+func constNumber(x float64) *value {
+	return &value{
+		typ:      Number,
+		num:      func(*Context, int) float64 { return x },
+		constant: true,
+		key:      strconv.FormatFloat(x, 'g', -1, 64),
+	}
+}
+
+func constBool(b bool) *value {
+	return &value{
+		typ:      Bool,
+		cond:     func(*Context, int) bool { return b },
+		constant: true,
+		key:      strconv.FormatBool(b),
+	}
+}
+
+// at returns the value of a series back candles before the last one, or NaN when out of range.
+func at(series []float64, back int) float64 {
+	i := len(series) - 1 - back
+	if i < 0 || i >= len(series) {
+		return math.NaN()
+	}
+	return series[i]
+}
+
+type compiler struct {
+	resolve Resolver
+}
+
+func (cp *compiler) compile(n node) (*value, error) {
+	switch n := n.(type) {
+	case *numberNode:
+		return constNumber(n.value), nil
+	case *boolNode:
+		return constBool(n.value), nil
+	case *identNode:
+		return cp.ident(n)
+	case *indexNode:
+		return cp.index(n)
+	case *unaryNode:
+		return cp.unary(n)
+	case *binaryNode:
+		return cp.binary(n)
+	case *callNode:
+		return cp.call(n)
+	}
+	return nil, &SyntaxError{Pos: n.position(), Msg: "unsupported expression"}
+}
+
+func (cp *compiler) ident(n *identNode) (*value, error) {
+	if cp.resolve == nil {
+		return nil, &NameError{Pos: n.pos, Name: n.name, Msg: fmt.Sprintf("unknown series %q", n.name)}
+	}
+	s, warmUp, err := cp.resolve(n.name)
+	if err != nil {
+		return nil, &NameError{Pos: n.pos, Name: n.name, Msg: err.Error()}
+	}
+	return &value{typ: Number, num: s, warmUp: warmUp, key: n.name}, nil
+}
+
+// index shifts an expression back in time; constants are the same on every candle.
+func (cp *compiler) index(n *indexNode) (*value, error) {
+	x, err := cp.compile(n.x)
+	if err != nil {
+		return nil, err
+	}
+	if x.constant || n.back == 0 {
+		return x, nil
+	}
+	v := &value{typ: x.typ, warmUp: x.warmUp + n.back, key: fmt.Sprintf("%s[%d]", x.key, n.back)}
+	if x.typ == Bool {
+		v.cond = func(c *Context, back int) bool { return x.cond(c, back+n.back) }
+	} else {
+		v.num = func(c *Context, back int) float64 { return x.num(c, back+n.back) }
+	}
+	return v, nil
+}
+
+func (cp *compiler) unary(n *unaryNode) (*value, error) {
+	x, err := cp.compile(n.x)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "not" {
+		if err := expectType(n.x, x, Bool, "not"); err != nil {
+			return nil, err
+		}
+		if x.constant {
+			return constBool(!x.cond(nil, 0)), nil
+		}
+		return &value{
+			typ:    Bool,
+			cond:   func(c *Context, back int) bool { return !x.cond(c, back) },
+			warmUp: x.warmUp,
+			key:    "not " + x.key,
+		}, nil
+	}
+	if err := expectType(n.x, x, Number, "-"); err != nil {
+		return nil, err
+	}
+	if x.constant {
+		return constNumber(-x.num(nil, 0)), nil
+	}
+	return &value{
+		typ:    Number,
+		num:    func(c *Context, back int) float64 { return -x.num(c, back) },
+		warmUp: x.warmUp,
+		key:    "-" + x.key,
+	}, nil
+}
+
+// arithmetic implements the arithmetic operators. Division by zero has no value.
+var arithmetic = map[string]func(a, b float64) float64{
+	"+": func(a, b float64) float64 { return a + b },
+	"-": func(a, b float64) float64 { return a - b },
+	"*": func(a, b float64) float64 { return a * b },
+	"/": func(a, b float64) float64 {
+		if b == 0 {
+			return math.NaN()
+		}
+		return a / b
+	},
+}
+
+// comparisons implements the comparison operators. Comparisons with a missing value are false.
+var comparisons = map[string]func(a, b float64) bool{
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+func (cp *compiler) binary(n *binaryNode) (*value, error) {
+	l, err := cp.compile(n.l)
+	if err != nil {
+		return nil, err
+	}
+	r, err := cp.compile(n.r)
+	if err != nil {
+		return nil, err
+	}
+	key := "(" + l.key + " " + n.op + " " + r.key + ")"
+	warmUp := max(l.warmUp, r.warmUp)
+
+	if n.op == "and" || n.op == "or" {
+		if err := expectType(n.l, l, Bool, n.op); err != nil {
+			return nil, err
+		}
+		if err := expectType(n.r, r, Bool, n.op); err != nil {
+			return nil, err
+		}
+		return logical(n.op, l, r, key, warmUp), nil
+	}
+
+	if err := expectType(n.l, l, Number, n.op); err != nil {
+		return nil, err
+	}
+	if err := expectType(n.r, r, Number, n.op); err != nil {
+		return nil, err
+	}
+	if cmp, ok := comparisons[n.op]; ok {
+		v := &value{typ: Bool, warmUp: warmUp, key: key, cond: func(c *Context, back int) bool {
+			a, b := l.num(c, back), r.num(c, back)
+			return !math.IsNaN(a) && !math.IsNaN(b) && cmp(a, b)
+		}}
+		if l.constant && r.constant {
+			return constBool(v.cond(nil, 0)), nil
+		}
+		return v, nil
+	}
+	op := arithmetic[n.op]
+	if l.constant && r.constant {
+		return constNumber(op(l.num(nil, 0), r.num(nil, 0))), nil
+	}
+	return &value{typ: Number, warmUp: warmUp, key: key, num: func(c *Context, back int) float64 {
+		return op(l.num(c, back), r.num(c, back))
+	}}, nil
+}
+
+// logical combines two conditions, folding a constant side: "true and x" is x, "false and x" is false.
+func logical(op string, l, r *value, key string, warmUp int) *value {
+	and := op == "and"
+	for _, pair := range [][2]*value{{l, r}, {r, l}} {
+		if c, other := pair[0], pair[1]; c.constant {
+			if c.cond(nil, 0) == and {
+				return other
+			}
+			return constBool(!and)
+		}
+	}
+	if and {
+		return &value{typ: Bool, warmUp: warmUp, key: key, cond: func(c *Context, back int) bool {
+			return l.cond(c, back) && r.cond(c, back)
+		}}
+	}
+	return &value{typ: Bool, warmUp: warmUp, key: key, cond: func(c *Context, back int) bool {
+		return l.cond(c, back) || r.cond(c, back)
+	}}
+}
+
+func expectType(n node, v *value, want Type, op string) error {
+	if v.typ == want {
+		return nil
+	}
+	return &TypeError{Pos: n.position(), Msg: fmt.Sprintf("%q needs a %s, got a %s", op, want, v.typ)}
+}
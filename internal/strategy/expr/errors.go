@@ -0,0 +1,35 @@
+package expr
+
+import "fmt"
+
+// SyntaxError reports an expression that cannot be parsed.
+type SyntaxError struct {
+	Pos int // Byte offset in the source.
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos+1, e.Msg)
+}
+
+// TypeError reports an operation on operands of the wrong type, e.g. "close and 3",
+// or a function argument that must be a constant.
+type TypeError struct {
+	Pos int
+	Msg string
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("type error at position %d: %s", e.Pos+1, e.Msg)
+}
+
+// NameError reports an unknown series, function or member.
+type NameError struct {
+	Pos  int
+	Name string
+	Msg  string
+}
+
+func (e *NameError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos+1)
+}
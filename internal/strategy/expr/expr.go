@@ -0,0 +1,134 @@
+// Package expr parses and evaluates the condition expressions of strategy definitions, such as
+//
+//	rsi(14) < 30 and close > ema(200) and crosses_above(stoch.k, stoch.d)
+//
+// Expressions are evaluated on the last closed candle. A series can be indexed with the number of
+// candles to look back: close[1] is the close of the candle before the last one. Numbers, the
+// arithmetic operators + - * /, the comparisons < <= > >= == != and the logical operators and, or,
+// not are supported, together with the functions in functions.go. Parts of an expression that do
+// not depend on the candles are folded into constants when it is compiled.
+package expr
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// Type is the type of an expression.
+type Type int
+
+const (
+	Number Type = iota
+	Bool
+)
+
+func (t Type) String() string {
+	if t == Bool {
+		return "condition"
+	}
+	return "number"
+}
+
+// Context is the state an expression is evaluated against for one candle. Indicators computed
+// inline, like ema(200), are cached in the context, so create a new one for every candle.
+type Context struct {
+	Candles []domain.Candle
+	// Data is passed through to the series returned by the Resolver.
+	Data  any
+	cache map[string][]float64
+}
+
+// NewContext creates a context for evaluating expressions on the last of the candles.
+func NewContext(candles []domain.Candle, data any) *Context {
+	return &Context{Candles: candles, Data: data, cache: make(map[string][]float64)}
+}
+
+// Series returns the value of a series back candles before the last one, or NaN when it has none.
+type Series func(c *Context, back int) float64
+
+// Resolver resolves a series name, like "close" or "stoch.k", to its accessor and the number of
+// candles the series needs before it has a value.
+type Resolver func(name string) (Series, int, error)
+
+// Program is a compiled expression.
+type Program struct {
+	v *value
+}
+
+// Compile parses and type checks an expression of any type.
+func Compile(src string, resolve Resolver) (*Program, error) {
+	n, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	v, err := (&compiler{resolve: resolve}).compile(n)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{v: v}, nil
+}
+
+// CompileCondition compiles an expression that must evaluate to true or false.
+func CompileCondition(src string, resolve Resolver) (*Program, error) {
+	return compileTyped(src, resolve, Bool)
+}
+
+// CompileNumber compiles an expression that must evaluate to a number.
+func CompileNumber(src string, resolve Resolver) (*Program, error) {
+	return compileTyped(src, resolve, Number)
+}
+
+func compileTyped(src string, resolve Resolver, want Type) (*Program, error) {
+	p, err := Compile(src, resolve)
+	if err != nil {
+		return nil, err
+	}
+	if p.Type() != want {
+		return nil, &TypeError{Pos: 0, Msg: fmt.Sprintf("expression is a %s, expected a %s", p.Type(), want)}
+	}
+	return p, nil
+}
+
+// Type returns the type of the expression.
+func (p *Program) Type() Type {
+	return p.v.typ
+}
+
+// WarmUp returns the number of candles needed before the expression has a value.
+func (p *Program) WarmUp() int {
+	return p.v.warmUp
+}
+
+// Constant reports whether the expression was folded into a constant.
+func (p *Program) Constant() bool {
+	return p.v.constant
+}
+
+// Bool evaluates a condition on the last candle of the context. Comparisons with a missing value,
+// such as an indicator that is still warming up, are false.
+func (p *Program) Bool(c *Context) bool {
+	return p.BoolAt(c, 0)
+}
+
+// BoolAt evaluates a condition back candles before the last one.
+func (p *Program) BoolAt(c *Context, back int) bool {
+	if p.v.typ != Bool {
+		return false
+	}
+	return p.v.cond(c, back)
+}
+
+// Number evaluates a numeric expression on the last candle of the context; NaN means no value.
+func (p *Program) Number(c *Context) float64 {
+	return p.NumberAt(c, 0)
+}
+
+// NumberAt evaluates a numeric expression back candles before the last one.
+func (p *Program) NumberAt(c *Context, back int) float64 {
+	if p.v.typ != Number {
+		return math.NaN()
+	}
+	return p.v.num(c, back)
+}
@@ -0,0 +1,296 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/indicator"
+)
+
+// function compiles a call with its already compiled arguments.
+type function struct {
+	usage   string
+	members []string // Outputs selected with ".member"; empty for a single output.
+	compile func(cp *compiler, n *callNode, args []*value) (*value, error)
+}
+
+// functions lists the functions available in expressions. Indicators take their period as a
+// constant; sma, ema and rsi work on the close unless a series is passed as first argument.
+var functions = map[string]function{
+	"sma": {usage: "sma([series,] period)", compile: sourceIndicator("sma", 0, indicator.SMA)},
+	"ema": {usage: "ema([series,] period)", compile: sourceIndicator("ema", 0, indicator.EMA)},
+	"rsi": {usage: "rsi([series,] period)", compile: sourceIndicator("rsi", 1, indicator.RSI)},
+	"atr": {usage: "atr(period)", compile: func(cp *compiler, n *callNode, args []*value) (*value, error) {
+		if err := argCount(n, args, 1); err != nil {
+			return nil, err
+		}
+		p, err := intParam(n, args[0], "period")
+		if err != nil {
+			return nil, err
+		}
+		return cached(fmt.Sprintf("atr(%d)", p), p, func(c *Context) []float64 {
+			return indicator.ATR(c.Candles, p)
+		}), nil
+	}},
+	"bb": {usage: "bb(period, stddev).upper|middle|lower|width", members: []string{"upper", "middle", "lower", "width"}, compile: func(cp *compiler, n *callNode, args []*value) (*value, error) {
+		if err := argCount(n, args, 2); err != nil {
+			return nil, err
+		}
+		p, err := intParam(n, args[0], "period")
+		if err != nil {
+			return nil, err
+		}
+		k, err := numberParam(n, args[1], "stddev")
+		if err != nil {
+			return nil, err
+		}
+		return cached(fmt.Sprintf("bb(%d, %g).%s", p, k, n.member), p, func(c *Context) []float64 {
+			bb := indicator.Bollinger(indicator.Closes(c.Candles), p, k)
+			return map[string][]float64{"upper": bb.Upper, "middle": bb.Middle, "lower": bb.Lower, "width": bb.Width}[n.member]
+		}), nil
+	}},
+	"stoch": {usage: "stoch(period, smooth, d).k|d", members: []string{"k", "d"}, compile: func(cp *compiler, n *callNode, args []*value) (*value, error) {
+		if err := argCount(n, args, 3); err != nil {
+			return nil, err
+		}
+		var params [3]int
+		for i, name := range []string{"period", "smooth", "d"} {
+			v, err := intParam(n, args[i], name)
+			if err != nil {
+				return nil, err
+			}
+			params[i] = v
+		}
+		p, smooth, d := params[0], params[1], params[2]
+		return cached(fmt.Sprintf("stoch(%d, %d, %d).%s", p, smooth, d, n.member), p+smooth+d-1, func(c *Context) []float64 {
+			k, dLine := indicator.Stochastic(c.Candles, p, smooth, d)
+			if n.member == "d" {
+				return dLine
+			}
+			return k
+		}), nil
+	}},
+	"highest": {usage: "highest(series, length)", compile: window("highest", math.Max)},
+	"lowest":  {usage: "lowest(series, length)", compile: window("lowest", math.Min)},
+	"abs": {usage: "abs(x)", compile: func(cp *compiler, n *callNode, args []*value) (*value, error) {
+		if err := argCount(n, args, 1); err != nil {
+			return nil, err
+		}
+		return mapNumbers(n, args, func(x []float64) float64 { return math.Abs(x[0]) })
+	}},
+	"min": {usage: "min(a, b)", compile: func(cp *compiler, n *callNode, args []*value) (*value, error) {
+		if err := argCount(n, args, 2); err != nil {
+			return nil, err
+		}
+		return mapNumbers(n, args, func(x []float64) float64 { return math.Min(x[0], x[1]) })
+	}},
+	"max": {usage: "max(a, b)", compile: func(cp *compiler, n *callNode, args []*value) (*value, error) {
+		if err := argCount(n, args, 2); err != nil {
+			return nil, err
+		}
+		return mapNumbers(n, args, func(x []float64) float64 { return math.Max(x[0], x[1]) })
+	}},
+	"crosses_above": {usage: "crosses_above(a, b)", compile: cross(1)},
+	"crosses_below": {usage: "crosses_below(a, b)", compile: cross(-1)},
+}
+
+func (cp *compiler) call(n *callNode) (*value, error) {
+	f, ok := functions[n.name]
+	if !ok {
+		return nil, &NameError{Pos: n.pos, Name: n.name, Msg: fmt.Sprintf("unknown function %q, expected one of %s", n.name, strings.Join(functionNames(), ", "))}
+	}
+	switch {
+	case len(f.members) == 0 && n.member != "":
+		return nil, &NameError{Pos: n.pos, Name: n.name + "." + n.member, Msg: fmt.Sprintf("%s has no output %q", n.name, n.member)}
+	case len(f.members) > 0 && !contains(f.members, n.member):
+		return nil, &NameError{Pos: n.pos, Name: n.name + "." + n.member, Msg: fmt.Sprintf("%s needs one of the outputs .%s", n.name, strings.Join(f.members, ", ."))}
+	}
+	args := make([]*value, len(n.args))
+	for i, a := range n.args {
+		v, err := cp.compile(a)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := f.compile(cp, n, args)
+	if te, ok := err.(*TypeError); ok && te.Pos == n.pos {
+		te.Msg += "; usage: " + f.usage
+	}
+	return v, err
+}
+
+// cached returns a value that computes an indicator over all candles once per context.
+func cached(key string, warmUp int, compute func(c *Context) []float64) *value {
+	return &value{typ: Number, warmUp: warmUp, key: key, num: func(c *Context, back int) float64 {
+		s, ok := c.cache[key]
+		if !ok {
+			s = compute(c)
+			c.cache[key] = s
+		}
+		return at(s, back)
+	}}
+}
+
+// sourceIndicator compiles an indicator on the close, or on a series passed as first argument.
+func sourceIndicator(name string, extraWarmUp int, compute func(values []float64, period int) []float64) func(*compiler, *callNode, []*value) (*value, error) {
+	return func(cp *compiler, n *callNode, args []*value) (*value, error) {
+		if len(args) != 1 && len(args) != 2 {
+			return nil, &TypeError{Pos: n.pos, Msg: fmt.Sprintf("%s takes 1 or 2 arguments, got %d", name, len(args))}
+		}
+		p, err := intParam(n, args[len(args)-1], "period")
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 1 {
+			return cached(fmt.Sprintf("%s(%d)", name, p), p+extraWarmUp, func(c *Context) []float64 {
+				return compute(indicator.Closes(c.Candles), p)
+			}), nil
+		}
+		src := args[0]
+		if err := expectType(n.args[0], src, Number, name); err != nil {
+			return nil, err
+		}
+		return cached(fmt.Sprintf("%s(%s, %d)", name, src.key, p), src.warmUp+p-1+extraWarmUp, func(c *Context) []float64 {
+			return compute(materialize(c, src), p)
+		}), nil
+	}
+}
+
+// materialize evaluates a numeric value on every candle of the context.
+func materialize(c *Context, v *value) []float64 {
+	out := make([]float64, len(c.Candles))
+	for i := range out {
+		out[i] = v.num(c, len(out)-1-i)
+	}
+	return out
+}
+
+// window compiles a function over the last length values of a series, the current one included.
+func window(name string, pick func(a, b float64) float64) func(*compiler, *callNode, []*value) (*value, error) {
+	return func(cp *compiler, n *callNode, args []*value) (*value, error) {
+		if err := argCount(n, args, 2); err != nil {
+			return nil, err
+		}
+		src := args[0]
+		if err := expectType(n.args[0], src, Number, name); err != nil {
+			return nil, err
+		}
+		length, err := intParam(n, args[1], "length")
+		if err != nil {
+			return nil, err
+		}
+		if src.constant {
+			return src, nil
+		}
+		return &value{typ: Number, warmUp: src.warmUp + length - 1, key: fmt.Sprintf("%s(%s, %d)", name, src.key, length), num: func(c *Context, back int) float64 {
+			result := src.num(c, back)
+			for i := 1; i < length; i++ {
+				result = pick(result, src.num(c, back+i))
+			}
+			return result
+		}}, nil
+	}
+}
+
+// mapNumbers applies a function to numeric arguments, folding it when they are all constant.
+func mapNumbers(n *callNode, args []*value, f func(x []float64) float64) (*value, error) {
+	constant, warmUp, keys := true, 0, make([]string, len(args))
+	for i, a := range args {
+		if err := expectType(n.args[i], a, Number, n.name); err != nil {
+			return nil, err
+		}
+		constant = constant && a.constant
+		warmUp = max(warmUp, a.warmUp)
+		keys[i] = a.key
+	}
+	v := &value{typ: Number, warmUp: warmUp, key: n.name + "(" + strings.Join(keys, ", ") + ")", num: func(c *Context, back int) float64 {
+		x := make([]float64, len(args))
+		for i, a := range args {
+			x[i] = a.num(c, back)
+		}
+		return f(x)
+	}}
+	if constant {
+		return constNumber(v.num(nil, 0)), nil
+	}
+	return v, nil
+}
+
+// cross compiles crosses_above (sign 1) or crosses_below (sign -1): a moved from at or below b
+// on the previous candle to above it on this one.
+func cross(sign float64) func(*compiler, *callNode, []*value) (*value, error) {
+	return func(cp *compiler, n *callNode, args []*value) (*value, error) {
+		if err := argCount(n, args, 2); err != nil {
+			return nil, err
+		}
+		a, b := args[0], args[1]
+		for i, v := range args {
+			if err := expectType(n.args[i], v, Number, n.name); err != nil {
+				return nil, err
+			}
+		}
+		if a.constant && b.constant {
+			return constBool(false), nil
+		}
+		return &value{typ: Bool, warmUp: max(a.warmUp, b.warmUp) + 1, key: n.name + "(" + a.key + ", " + b.key + ")", cond: func(c *Context, back int) bool {
+			prevA, prevB := sign*a.num(c, back+1), sign*b.num(c, back+1)
+			curA, curB := sign*a.num(c, back), sign*b.num(c, back)
+			if math.IsNaN(prevA) || math.IsNaN(prevB) || math.IsNaN(curA) || math.IsNaN(curB) {
+				return false
+			}
+			return prevA <= prevB && curA > curB
+		}}, nil
+	}
+}
+
+func argCount(n *callNode, args []*value, want int) error {
+	if len(args) == want {
+		return nil
+	}
+	return &TypeError{Pos: n.pos, Msg: fmt.Sprintf("%s takes %d argument(s), got %d", n.name, want, len(args))}
+}
+
+// numberParam returns a parameter that must be a positive constant.
+func numberParam(n *callNode, v *value, name string) (float64, error) {
+	if v.typ != Number || !v.constant {
+		return 0, &TypeError{Pos: n.pos, Msg: fmt.Sprintf("%s of %s must be a constant number", name, n.name)}
+	}
+	x := v.num(nil, 0)
+	if !(x > 0) {
+		return 0, &TypeError{Pos: n.pos, Msg: fmt.Sprintf("%s of %s must be positive, got %v", name, n.name, x)}
+	}
+	return x, nil
+}
+
+// intParam returns a parameter that must be a positive whole constant.
+func intParam(n *callNode, v *value, name string) (int, error) {
+	x, err := numberParam(n, v, name)
+	if err != nil {
+		return 0, err
+	}
+	if x != math.Trunc(x) {
+		return 0, &TypeError{Pos: n.pos, Msg: fmt.Sprintf("%s of %s must be a whole number, got %v", name, n.name, x)}
+	}
+	return int(x), nil
+}
+
+func functionNames() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package expr
+
+import (
+	"strconv"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp // Operators and punctuation.
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+// twoCharOps are the operators made of two characters.
+var twoCharOps = map[string]bool{"<=": true, ">=": true, "==": true, "!=": true}
+
+// lex splits the source into tokens.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+	pos := func(i int) int { return len(string(runes[:i])) }
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			n, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, &SyntaxError{Pos: pos(start), Msg: "invalid number " + strconv.Quote(text)}
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: text, num: n, pos: pos(start)})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: string(runes[start:i]), pos: pos(start)})
+		default:
+			if i+1 < len(runes) && twoCharOps[string(runes[i:i+2])] {
+				tokens = append(tokens, token{kind: tokOp, text: string(runes[i : i+2]), pos: pos(i)})
+				i += 2
+				continue
+			}
+			switch r {
+			case '<', '>', '+', '-', '*', '/', '(', ')', '[', ']', ',', '.':
+				tokens = append(tokens, token{kind: tokOp, text: string(r), pos: pos(i)})
+				i++
+			default:
+				return nil, &SyntaxError{Pos: pos(i), Msg: "unexpected character " + strconv.QuoteRune(r)}
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
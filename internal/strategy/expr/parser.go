@@ -0,0 +1,268 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// node is an expression AST node.
+type node interface {
+	position() int
+}
+
+type (
+	numberNode struct {
+		pos   int
+		value float64
+	}
+	boolNode struct {
+		pos   int
+		value bool
+	}
+	// identNode is a series name; dotted names like "stoch.k" are one identifier.
+	identNode struct {
+		pos  int
+		name string
+	}
+	callNode struct {
+		pos    int
+		name   string
+		args   []node
+		member string // Output selected with ".member", e.g. bb(20, 2).lower.
+	}
+	indexNode struct {
+		pos  int
+		x    node
+		back int
+	}
+	unaryNode struct {
+		pos int
+		op  string
+		x   node
+	}
+	binaryNode struct {
+		pos  int
+		op   string
+		l, r node
+	}
+)
+
+func (n *numberNode) position() int { return n.pos }
+func (n *boolNode) position() int   { return n.pos }
+func (n *identNode) position() int  { return n.pos }
+func (n *callNode) position() int   { return n.pos }
+func (n *indexNode) position() int  { return n.pos }
+func (n *unaryNode) position() int  { return n.pos }
+func (n *binaryNode) position() int { return n.pos }
+
+// binaryPrecedence lists the binary operators from loosest to tightest binding.
+var binaryPrecedence = map[string]int{
+	"or":  1,
+	"and": 2,
+	"<":   4, "<=": 4, ">": 4, ">=": 4, "==": 4, "!=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6,
+}
+
+const (
+	notPrecedence   = 3
+	unaryPrecedence = 7
+)
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+// parse parses an expression into an AST.
+func parse(src string) (node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.expr(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token { return p.tokens[p.i] }
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) expect(text string) (token, error) {
+	t := p.next()
+	if t.text != text || t.kind == tokEOF || t.kind == tokNumber {
+		found := fmt.Sprintf("%q", t.text)
+		if t.kind == tokEOF {
+			found = "end of expression"
+		}
+		return t, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("expected %q, found %s", text, found)}
+	}
+	return t, nil
+}
+
+// binaryOp returns the binary operator of a token and its precedence.
+func binaryOp(t token) (string, int, bool) {
+	if t.kind != tokOp && t.kind != tokIdent {
+		return "", 0, false
+	}
+	op := strings.ToLower(t.text)
+	prec, ok := binaryPrecedence[op]
+	if t.kind == tokIdent && op != "and" && op != "or" {
+		return "", 0, false
+	}
+	return op, prec, ok
+}
+
+// expr parses with precedence climbing: it consumes operators binding tighter than minPrec.
+func (p *parser) expr(minPrec int) (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		op, prec, ok := binaryOp(t)
+		if !ok || prec <= minPrec {
+			return left, nil
+		}
+		p.next()
+		right, err := p.expr(prec)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: t.pos, op: op, l: left, r: right}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokOp && t.text == "-":
+		p.next()
+		x, err := p.expr(unaryPrecedence - 1)
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{pos: t.pos, op: "-", x: x}, nil
+	case t.kind == tokIdent && strings.ToLower(t.text) == "not":
+		p.next()
+		x, err := p.expr(notPrecedence - 1)
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{pos: t.pos, op: "not", x: x}, nil
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() (node, error) {
+	n, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokOp {
+			return n, nil
+		}
+		switch t.text {
+		case "[":
+			p.next()
+			idx := p.next()
+			if idx.kind != tokNumber || idx.num != float64(int(idx.num)) || idx.num < 0 {
+				return nil, &SyntaxError{Pos: idx.pos, Msg: "series index must be a non-negative whole number"}
+			}
+			if _, err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = &indexNode{pos: t.pos, x: n, back: int(idx.num)}
+		case ".":
+			p.next()
+			member := p.next()
+			if member.kind != tokIdent {
+				return nil, &SyntaxError{Pos: member.pos, Msg: "expected a name after \".\""}
+			}
+			switch x := n.(type) {
+			case *identNode:
+				x.name += "." + member.text
+			case *callNode:
+				if x.member != "" {
+					return nil, &SyntaxError{Pos: member.pos, Msg: "unexpected \".\""}
+				}
+				x.member = member.text
+			default:
+				return nil, &SyntaxError{Pos: t.pos, Msg: "unexpected \".\""}
+			}
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return &numberNode{pos: t.pos, value: t.num}, nil
+	case tokIdent:
+		switch strings.ToLower(t.text) {
+		case "true", "false":
+			return &boolNode{pos: t.pos, value: strings.ToLower(t.text) == "true"}, nil
+		case "and", "or", "not":
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+		}
+		if next := p.peek(); next.kind == tokOp && next.text == "(" {
+			p.next()
+			return p.call(t)
+		}
+		return &identNode{pos: t.pos, name: t.text}, nil
+	case tokOp:
+		if t.text == "(" {
+			n, err := p.expr(0)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	default:
+		return nil, &SyntaxError{Pos: t.pos, Msg: "unexpected end of expression"}
+	}
+}
+
+func (p *parser) call(name token) (node, error) {
+	call := &callNode{pos: name.pos, name: strings.ToLower(name.text)}
+	if t := p.peek(); t.kind == tokOp && t.text == ")" {
+		p.next()
+		return call, nil
+	}
+	for {
+		arg, err := p.expr(0)
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		t := p.next()
+		if t.kind == tokOp && t.text == ")" {
+			return call, nil
+		}
+		if t.kind != tokOp || t.text != "," {
+			return nil, &SyntaxError{Pos: t.pos, Msg: "expected \",\" or \")\" in argument list"}
+		}
+	}
+}
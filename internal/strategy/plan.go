@@ -0,0 +1,17 @@
+package strategy
+
+import (
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// PlanFromStop builds a trade plan with the take-profit rewardRisk times the stop distance away
+// from the entry, on the opposite side of the stop.
+func PlanFromStop(entry, stopLoss, rewardRisk float64, size domain.SizeRule) *domain.TradePlan {
+	risk := entry - stopLoss
+	return &domain.TradePlan{
+		Entry:      entry,
+		StopLoss:   stopLoss,
+		TakeProfit: entry + rewardRisk*risk,
+		Size:       size,
+	}
+}
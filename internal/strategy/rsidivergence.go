@@ -0,0 +1,134 @@
+package strategy
+
+import (
+	"math"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/indicator"
+)
+
+// RSIDivergenceConfig holds the settings of the "RSI Divergence" strategy.
+type RSIDivergenceConfig struct {
+	RSIPeriod int
+	// PivotLeft and PivotRight are the candles on each side of a swing low or high.
+	// A pivot is only confirmed PivotRight candles later, so signals never look ahead.
+	PivotLeft  int
+	PivotRight int
+	// MinDistance and MaxLookback bound the candles between the two pivots of a divergence.
+	MinDistance int
+	MaxLookback int
+	// Oversold is the RSI below which a bullish divergence's last pivot must be; Overbought mirrors it.
+	Oversold   float64
+	Overbought float64
+
+	// Trade plan: the stop is StopATR times the ATR beyond the last pivot, the take-profit
+	// RewardRisk times the stop distance, and the size risks RiskPercent of the balance.
+	ATRPeriod   int
+	StopATR     float64
+	RewardRisk  float64
+	RiskPercent float64
+}
+
+// DefaultRSIDivergenceConfig returns RSI(14) divergences between 3/3 pivots at most 60 candles apart,
+// planned with a 0.5 ATR stop, a 2:1 reward/risk and 1% risk per trade.
+func DefaultRSIDivergenceConfig() RSIDivergenceConfig {
+	return RSIDivergenceConfig{
+		RSIPeriod:   14,
+		PivotLeft:   3,
+		PivotRight:  3,
+		MinDistance: 5,
+		MaxLookback: 60,
+		Oversold:    40,
+		Overbought:  60,
+		ATRPeriod:   14,
+		StopATR:     0.5,
+		RewardRisk:  2,
+		RiskPercent: 1,
+	}
+}
+
+// RSIDivergence signals a possible buy moment on a bullish divergence (price makes a lower low
+// while the RSI makes a higher low) and a possible sell moment on a bearish divergence.
+// Every signal carries a trade plan based on the strategy rules.
+type RSIDivergence struct {
+	cfg RSIDivergenceConfig
+}
+
+// NewRSIDivergence creates an "RSI Divergence" strategy.
+func NewRSIDivergence(cfg RSIDivergenceConfig) *RSIDivergence {
+	return &RSIDivergence{cfg: cfg}
+}
+
+// Name implements Strategy.
+func (s *RSIDivergence) Name() string {
+	return "RSI Divergence"
+}
+
+// WarmUp implements Strategy.
+func (s *RSIDivergence) WarmUp() int {
+	return s.cfg.RSIPeriod + s.cfg.MaxLookback + s.cfg.PivotRight + 1
+}
+
+// OnCandle implements Strategy.
+func (s *RSIDivergence) OnCandle(in Input) []domain.Signal {
+	n := len(in.Candles)
+	p2 := n - 1 - s.cfg.PivotRight
+	if p2-s.cfg.PivotLeft < 0 {
+		return nil
+	}
+	rsi := indicator.RSI(indicator.Closes(in.Candles), s.cfg.RSIPeriod)
+	atr := indicator.Last(indicator.ATR(in.Candles, s.cfg.ATRPeriod))
+	if math.IsNaN(atr) || math.IsNaN(rsi[p2]) {
+		return nil
+	}
+
+	low := func(i int) float64 { return in.Candles[i].Low }
+	high := func(i int) float64 { return in.Candles[i].High }
+
+	entry := in.Last().Close
+	size := domain.SizeRule{Kind: domain.SizeRiskPercent, Value: s.cfg.RiskPercent}
+
+	if s.isPivot(in.Candles, p2, low, -1) && rsi[p2] < s.cfg.Oversold {
+		if p1, ok := s.previousPivot(in.Candles, p2, low, -1); ok && low(p2) < low(p1) && rsi[p2] > rsi[p1] {
+			sig := NewSignal(s, in, domain.Buy)
+			sig.Plan = PlanFromStop(entry, low(p2)-s.cfg.StopATR*atr, s.cfg.RewardRisk, size)
+			return []domain.Signal{sig}
+		}
+	}
+	if s.isPivot(in.Candles, p2, high, 1) && rsi[p2] > s.cfg.Overbought {
+		if p1, ok := s.previousPivot(in.Candles, p2, high, 1); ok && high(p2) > high(p1) && rsi[p2] < rsi[p1] {
+			sig := NewSignal(s, in, domain.Sell)
+			sig.Plan = PlanFromStop(entry, high(p2)+s.cfg.StopATR*atr, s.cfg.RewardRisk, size)
+			return []domain.Signal{sig}
+		}
+	}
+	return nil
+}
+
+// isPivot reports whether candle i is a swing low (sign -1) or swing high (sign 1) of value.
+func (s *RSIDivergence) isPivot(candles []domain.Candle, i int, value func(int) float64, sign float64) bool {
+	if i-s.cfg.PivotLeft < 0 || i+s.cfg.PivotRight >= len(candles) {
+		return false
+	}
+	for j := i - s.cfg.PivotLeft; j <= i+s.cfg.PivotRight; j++ {
+		if j != i && sign*value(j) >= sign*value(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// previousPivot finds the most recent pivot of the same kind before p2 within the lookback window.
+func (s *RSIDivergence) previousPivot(candles []domain.Candle, p2 int, value func(int) float64, sign float64) (int, bool) {
+	for i := p2 - s.cfg.MinDistance; i >= 0 && i >= p2-s.cfg.MaxLookback; i-- {
+		if s.isPivot(candles, i, value, sign) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// OnTicker implements Strategy. The strategy only signals on closed candles.
+func (s *RSIDivergence) OnTicker(in Input, ticker domain.Ticker) []domain.Signal {
+	return nil
+}
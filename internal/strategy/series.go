@@ -0,0 +1,240 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/indicator"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy/expr"
+)
+
+// evalContext holds the series a compiled condition is evaluated against for one candle.
+type evalContext struct {
+	candles []domain.Candle
+	ticker  *domain.Ticker
+	values  map[string][]float64
+	expr    *expr.Context // Caches the indicators computed inline in expressions.
+}
+
+// newEvalContext computes the declared indicators for the last closed candle.
+func newEvalContext(candles []domain.Candle, ticker *domain.Ticker, indicators []indicatorSpec) *evalContext {
+	e := &evalContext{candles: candles, ticker: ticker, values: make(map[string][]float64)}
+	e.expr = expr.NewContext(candles, e)
+	for _, ind := range indicators {
+		ind.compute(candles, e.values)
+	}
+	return e
+}
+
+// at returns the value of a series back candles before the last one, or NaN when out of range.
+func (e *evalContext) at(series []float64, back int) float64 {
+	i := len(series) - 1 - back
+	if i < 0 || i >= len(series) {
+		return math.NaN()
+	}
+	return series[i]
+}
+
+// operand is a compiled value reference; back selects an earlier candle, 0 being the last closed one.
+type operand func(e *evalContext, back int) float64
+
+// condition is a compiled boolean condition evaluated on the last closed candle.
+type condition func(e *evalContext) bool
+
+// indicatorSpec is a validated indicator declaration.
+type indicatorSpec struct {
+	name    string
+	kind    string
+	params  map[string]float64
+	outputs []string // Empty for an indicator with a single, unnamed output.
+	warmUp  int
+}
+
+// indicatorTypes lists the supported indicator types with their parameters and defaults.
+var indicatorTypes = map[string]struct {
+	params  map[string]float64
+	outputs []string
+}{
+	"sma":        {params: map[string]float64{"period": 20}},
+	"ema":        {params: map[string]float64{"period": 20}},
+	"rsi":        {params: map[string]float64{"period": 14}},
+	"atr":        {params: map[string]float64{"period": 14}},
+	"bollinger":  {params: map[string]float64{"period": 20, "stddev": 2}, outputs: []string{"upper", "middle", "lower", "width"}},
+	"stochastic": {params: map[string]float64{"period": 14, "smooth": 1, "d": 3}, outputs: []string{"k", "d"}},
+}
+
+// wholeParams are the indicator parameters that count candles, so they must be whole numbers.
+var wholeParams = map[string]bool{"period": true, "smooth": true, "d": true}
+
+// newIndicatorSpec validates an indicator declaration and fills in default parameters.
+func newIndicatorSpec(name string, def IndicatorDef) (indicatorSpec, error) {
+	if name == "" || strings.ContainsAny(name, ".[]() ") {
+		return indicatorSpec{}, fmt.Errorf("invalid indicator name %q", name)
+	}
+	if _, reserved := builtinSeries[name]; reserved {
+		return indicatorSpec{}, fmt.Errorf("name %q is reserved for a built-in series", name)
+	}
+	t, ok := indicatorTypes[def.Type]
+	if !ok {
+		return indicatorSpec{}, fmt.Errorf("unknown type %q, expected one of %s", def.Type, strings.Join(sortedKeys(indicatorTypes), ", "))
+	}
+	params := make(map[string]float64, len(t.params))
+	for k, v := range t.params {
+		params[k] = v
+	}
+	for k, v := range def.Params {
+		if _, ok := t.params[k]; !ok {
+			return indicatorSpec{}, fmt.Errorf("unknown parameter %q for type %q", k, def.Type)
+		}
+		if v <= 0 {
+			return indicatorSpec{}, fmt.Errorf("parameter %q must be positive, got %v", k, v)
+		}
+		if wholeParams[k] && v != math.Trunc(v) {
+			return indicatorSpec{}, fmt.Errorf("parameter %q must be a whole number, got %v", k, v)
+		}
+		params[k] = v
+	}
+	spec := indicatorSpec{name: name, kind: def.Type, params: params, outputs: t.outputs}
+	p := int(params["period"])
+	switch def.Type {
+	case "rsi":
+		spec.warmUp = p + 1
+	case "stochastic":
+		spec.warmUp = p + int(params["smooth"]) + int(params["d"]) - 1
+	default:
+		spec.warmUp = p
+	}
+	return spec, nil
+}
+
+// compute calculates the outputs of the indicator, keyed by their series name.
+func (s indicatorSpec) compute(candles []domain.Candle, out map[string][]float64) {
+	p := int(s.params["period"])
+	switch s.kind {
+	case "sma":
+		out[s.name] = indicator.SMA(indicator.Closes(candles), p)
+	case "ema":
+		out[s.name] = indicator.EMA(indicator.Closes(candles), p)
+	case "rsi":
+		out[s.name] = indicator.RSI(indicator.Closes(candles), p)
+	case "atr":
+		out[s.name] = indicator.ATR(candles, p)
+	case "bollinger":
+		bb := indicator.Bollinger(indicator.Closes(candles), p, s.params["stddev"])
+		out[s.name+".upper"] = bb.Upper
+		out[s.name+".middle"] = bb.Middle
+		out[s.name+".lower"] = bb.Lower
+		out[s.name+".width"] = bb.Width
+	case "stochastic":
+		k, d := indicator.Stochastic(candles, p, int(s.params["smooth"]), int(s.params["d"]))
+		out[s.name+".k"] = k
+		out[s.name+".d"] = d
+	}
+}
+
+// builtinSeries are the series available without declaring an indicator.
+var builtinSeries = map[string]operand{
+	"open":   candleField(func(c domain.Candle) float64 { return c.Open }),
+	"high":   candleField(func(c domain.Candle) float64 { return c.High }),
+	"low":    candleField(func(c domain.Candle) float64 { return c.Low }),
+	"close":  candleField(func(c domain.Candle) float64 { return c.Close }),
+	"volume": candleField(func(c domain.Candle) float64 { return c.Volume }),
+	// The 24h statistics are NaN without a ticker, or for a stored ticker that has none, so a
+	// condition on them does not hold instead of comparing 0. Only those of the last ticker are
+	// known, so they are NaN for earlier candles too.
+	"volume_24h": func(e *evalContext, back int) float64 {
+		if back > 0 || e.ticker == nil || e.ticker.QuoteVolume.Float == nil {
+			return math.NaN()
+		}
+		return e.ticker.QuoteVolume.Float64Value()
+	},
+	"change_24h": func(e *evalContext, back int) float64 {
+		if back > 0 || e.ticker == nil || e.ticker.PriceChangePercent.Float == nil {
+			return math.NaN()
+		}
+		return e.ticker.PriceChangePercent.Float64Value()
+	},
+}
+
+func candleField(field func(domain.Candle) float64) operand {
+	return func(e *evalContext, back int) float64 {
+		i := len(e.candles) - 1 - back
+		if i < 0 {
+			return math.NaN()
+		}
+		return field(e.candles[i])
+	}
+}
+
+// compileEnv resolves series names while compiling conditions and tracks the warm-up they need.
+type compileEnv struct {
+	specs  map[string]indicatorSpec // Keyed by indicator name.
+	series map[string]indicatorSpec // Keyed by series name.
+	warmUp int
+}
+
+func newCompileEnv(specs []indicatorSpec) *compileEnv {
+	env := &compileEnv{specs: make(map[string]indicatorSpec), series: make(map[string]indicatorSpec)}
+	for _, s := range specs {
+		env.specs[s.name] = s
+		if len(s.outputs) == 0 {
+			env.series[s.name] = s
+		}
+		for _, o := range s.outputs {
+			env.series[s.name+"."+o] = s
+		}
+	}
+	return env
+}
+
+// resolve resolves a series name to an operand.
+func (env *compileEnv) resolve(name string) (operand, error) {
+	if op, ok := builtinSeries[name]; ok {
+		return op, nil
+	}
+	spec, ok := env.series[name]
+	if !ok {
+		if s, ok := env.specs[name]; ok {
+			outputs := make([]string, len(s.outputs))
+			for i, o := range s.outputs {
+				outputs[i] = s.name + "." + o
+			}
+			return nil, fmt.Errorf("indicator %q has several outputs, use one of %s", name, strings.Join(outputs, ", "))
+		}
+		return nil, fmt.Errorf("unknown series %q", name)
+	}
+	env.require(spec.warmUp)
+	return func(e *evalContext, back int) float64 {
+		return e.at(e.values[name], back)
+	}, nil
+}
+
+// require raises the warm-up to at least n candles.
+func (env *compileEnv) require(n int) {
+	if n > env.warmUp {
+		env.warmUp = n
+	}
+}
+
+// exprResolver resolves the series names used in expressions.
+func (env *compileEnv) exprResolver(name string) (expr.Series, int, error) {
+	op, err := env.resolve(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	return func(c *expr.Context, back int) float64 {
+		return op(c.Data.(*evalContext), back)
+	}, env.series[name].warmUp, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,126 @@
+package strategy
+
+import (
+	"math"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/indicator"
+)
+
+// stochBBATRPeriod is the ATR period used for the stop-loss of the suggested trade.
+const stochBBATRPeriod = 14
+
+// StochBBConfig holds the settings of the "Stoch & Bollinger Bands" strategy.
+type StochBBConfig struct {
+	BBPeriod    int
+	BBStdDev    float64
+	StochPeriod int
+	StochSmooth int
+	StochD      int
+	Oversold    float64
+	Overbought  float64
+	// MinBBWidth is the minimum Bollinger Band width in percent. 0 runs the strategy
+	// "without minimal margin".
+	MinBBWidth float64
+	// RiskPercent is the share of the balance the suggested trade risks at its stop-loss.
+	RiskPercent float64
+}
+
+// DefaultStochBBConfig returns BB(20, 2), Stoch(14, 1, 3), 20/80 levels, a 1% minimum band width
+// and 1% risk per trade.
+func DefaultStochBBConfig() StochBBConfig {
+	return StochBBConfig{
+		BBPeriod:    20,
+		BBStdDev:    2,
+		StochPeriod: 14,
+		StochSmooth: 1,
+		StochD:      3,
+		Oversold:    20,
+		Overbought:  80,
+		MinBBWidth:  1,
+		RiskPercent: 1,
+	}
+}
+
+// StochBB signals a possible buy moment when a candle touches the lower Bollinger Band while the
+// Stochastic %K crosses above %D in the oversold zone, and a possible sell moment for the mirror
+// image at the upper band in the overbought zone. The suggested trade targets the middle band with
+// the stop-loss half an ATR beyond the signal candle.
+type StochBB struct {
+	cfg StochBBConfig
+}
+
+// NewStochBB creates a "Stoch & Bollinger Bands" strategy.
+func NewStochBB(cfg StochBBConfig) *StochBB {
+	return &StochBB{cfg: cfg}
+}
+
+// Name implements Strategy.
+func (s *StochBB) Name() string {
+	if s.cfg.MinBBWidth <= 0 {
+		return "Stoch & Bollinger Bands without minimal margin"
+	}
+	return "Stoch & Bollinger Bands"
+}
+
+// WarmUp implements Strategy. One extra candle is needed to detect the Stochastic cross.
+func (s *StochBB) WarmUp() int {
+	stoch := s.cfg.StochPeriod + s.cfg.StochSmooth + s.cfg.StochD - 1
+	if s.cfg.BBPeriod > stoch {
+		return s.cfg.BBPeriod + 1
+	}
+	return stoch + 1
+}
+
+// OnCandle implements Strategy.
+func (s *StochBB) OnCandle(in Input) []domain.Signal {
+	n := len(in.Candles)
+	if n < 2 {
+		return nil
+	}
+	bb := indicator.Bollinger(indicator.Closes(in.Candles), s.cfg.BBPeriod, s.cfg.BBStdDev)
+	k, d := indicator.Stochastic(in.Candles, s.cfg.StochPeriod, s.cfg.StochSmooth, s.cfg.StochD)
+	last := in.Last()
+	width := bb.Width[n-1]
+	if math.IsNaN(width) || width < s.cfg.MinBBWidth {
+		return nil
+	}
+
+	var direction domain.Direction
+	switch {
+	case last.Low <= bb.Lower[n-1] && indicator.CrossesAbove(k, d) && k[n-2] < s.cfg.Oversold:
+		direction = domain.Buy
+	case last.High >= bb.Upper[n-1] && indicator.CrossesBelow(k, d) && k[n-2] > s.cfg.Overbought:
+		direction = domain.Sell
+	default:
+		return nil
+	}
+
+	sig := NewSignal(s, in, direction)
+	atr := indicator.Last(indicator.ATR(in.Candles, stochBBATRPeriod))
+	stop := last.Low - atr/2
+	if direction == domain.Sell {
+		stop = last.High + atr/2
+	}
+	if !math.IsNaN(atr) {
+		sig.Plan = &domain.TradePlan{
+			Entry:      last.Close,
+			StopLoss:   stop,
+			TakeProfit: bb.Middle[n-1],
+			Size:       domain.SizeRule{Kind: domain.SizeRiskPercent, Value: s.cfg.RiskPercent},
+		}
+	}
+	sig.Metrics = indicator.CaptureMetrics(in.Candles, in.Ticker, indicator.MetricsConfig{
+		BBPeriod:    s.cfg.BBPeriod,
+		BBStdDev:    s.cfg.BBStdDev,
+		StochPeriod: s.cfg.StochPeriod,
+		StochSmooth: s.cfg.StochSmooth,
+		StochD:      s.cfg.StochD,
+	})
+	return []domain.Signal{sig}
+}
+
+// OnTicker implements Strategy. The strategy only signals on closed candles.
+func (s *StochBB) OnTicker(in Input, ticker domain.Ticker) []domain.Signal {
+	return nil
+}
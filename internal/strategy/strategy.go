@@ -0,0 +1,99 @@
+// Package strategy defines the interface between trading strategies and the code that drives them,
+// both live in the scanner and on stored history in the backtester.
+package strategy
+
+import (
+	"sort"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// Input is the market data a strategy sees for one pair and timeframe.
+// Candles holds the closed candles, oldest first; it never contains a candle that is still open,
+// so a strategy cannot look ahead.
+type Input struct {
+	Exchange  string
+	Symbol    string
+	Timeframe domain.Timeframe
+	Candles   []domain.Candle
+	// Ticker is the latest ticker of the symbol, or nil when none was received yet.
+	Ticker *domain.Ticker
+	// Higher holds the closed candles of the higher timeframes a MultiTimeframe strategy asked for.
+	// They are aligned with Candles: none of them closed after the last candle of Candles.
+	Higher map[domain.Timeframe][]domain.Candle
+	// Trends are the trends shown next to the signals, or nil when they are not tracked.
+	Trends Trends
+}
+
+// Trends is the source of the trends shown on the opportunity cards: the scanner's trend services
+// live, or the stored trends in a backtest. Confirmations on a trend read it from here, so they see
+// the same trend as the user.
+type Trends interface {
+	// ChartTrend returns the chart trend of a pair on a timeframe, as of its last closed candle.
+	ChartTrend(symbol string, tf domain.Timeframe) (domain.ChartTrend, bool)
+	// MarketTrend returns the market trend of the last completed period of a timeframe.
+	MarketTrend(tf domain.Timeframe) (domain.MarketTrend, bool)
+}
+
+// Last returns the most recent closed candle. It must only be called when Candles is not empty.
+func (in Input) Last() domain.Candle {
+	return in.Candles[len(in.Candles)-1]
+}
+
+// Strategy analyses the data of one pair and timeframe and emits signals.
+// A new instance is created for every pair and timeframe it runs on, so it may keep state.
+type Strategy interface {
+	// Name is the description shown on the opportunity cards.
+	Name() string
+	// WarmUp is the number of closed candles needed before the strategy is called.
+	WarmUp() int
+	// OnCandle is called after a candle closed; the closed candle is in.Last().
+	OnCandle(in Input) []domain.Signal
+	// OnTicker is called for every live ticker of the symbol between candle closes.
+	OnTicker(in Input, ticker domain.Ticker) []domain.Signal
+}
+
+// MultiTimeframe is implemented by strategies that confirm their signals on higher timeframes.
+type MultiTimeframe interface {
+	// HigherTimeframes returns the number of closed candles needed per higher timeframe.
+	HigherTimeframes() map[domain.Timeframe]int
+}
+
+// WarmUpPeriod returns the history a strategy needs on a timeframe: the time its warm-up candles,
+// and those of the higher timeframes it confirms on, take to close. It counts one candle more, as
+// the first candle built from the history is only partly covered. Non-time bars have no length
+// and need no history by this measure.
+func WarmUpPeriod(s Strategy, tf domain.Timeframe) time.Duration {
+	period := time.Duration(s.WarmUp()+1) * tf.Duration()
+	if mtf, ok := s.(MultiTimeframe); ok {
+		for htf, n := range mtf.HigherTimeframes() {
+			period = max(period, time.Duration(n+1)*htf.Duration())
+		}
+	}
+	return period
+}
+
+// ClosedBy returns the leading candles that closed at or before t, so a higher timeframe can be
+// aligned with a lower one without looking ahead. Candles must be ordered by close time.
+func ClosedBy(candles []domain.Candle, t time.Time) []domain.Candle {
+	n := sort.Search(len(candles), func(i int) bool { return candles[i].CloseTime.After(t) })
+	return candles[:n:n]
+}
+
+// Factory creates a new strategy instance for a pair and timeframe.
+type Factory func() Strategy
+
+// NewSignal creates a signal of a strategy for the last closed candle of the input.
+func NewSignal(s Strategy, in Input, direction domain.Direction) domain.Signal {
+	last := in.Last()
+	return domain.Signal{
+		Strategy:  s.Name(),
+		Exchange:  in.Exchange,
+		Symbol:    in.Symbol,
+		Timeframe: in.Timeframe,
+		Direction: direction,
+		Time:      last.CloseTime,
+		Price:     last.Close,
+	}
+}
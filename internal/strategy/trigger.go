@@ -0,0 +1,36 @@
+package strategy
+
+import "time"
+
+// TriggerMode decides whether a strategy whose conditions keep holding signals again.
+type TriggerMode string
+
+const (
+	// EdgeTriggered signals once when the conditions start to hold, and again only after re-arming.
+	EdgeTriggered TriggerMode = "edge"
+	// LevelTriggered signals on every evaluation the conditions hold, limited by the cooldown.
+	LevelTriggered TriggerMode = "level"
+)
+
+// Trigger holds the rules that keep a strategy from emitting the same signal over and over,
+// so the opportunities list only gets a new entry for a genuinely new signal. The rules apply per
+// strategy, pair, timeframe and direction.
+type Trigger struct {
+	Mode TriggerMode
+	// Cooldown is the minimum time between two signals in the same direction.
+	Cooldown time.Duration
+	// ReArmAfter is the number of evaluations without a signal in a direction before an
+	// edge-triggered strategy can signal in that direction again. A signal in the opposite
+	// direction re-arms it at once.
+	ReArmAfter int
+}
+
+// DefaultTrigger signals on the edge, re-armed by a single evaluation without the signal.
+func DefaultTrigger() Trigger {
+	return Trigger{Mode: EdgeTriggered, ReArmAfter: 1}
+}
+
+// Triggered is implemented by strategies with trigger rules of their own.
+type Triggered interface {
+	Trigger() Trigger
+}
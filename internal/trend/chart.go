@@ -0,0 +1,206 @@
+// Package trend calculates the chart trend of a coin and the trend of the market as a whole.
+package trend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+)
+
+// ChartConfig holds the settings of the chart trend calculation.
+type ChartConfig struct {
+	FastPeriod int
+	SlowPeriod int
+	// NeutralBand is the minimum EMA distance, in percent, before a trend is called bullish or bearish.
+	NeutralBand float64
+}
+
+// DefaultChartConfig returns an EMA(20)/EMA(50) crossover with a 0.1% neutral band.
+func DefaultChartConfig() ChartConfig {
+	return ChartConfig{FastPeriod: 20, SlowPeriod: 50, NeutralBand: 0.1}
+}
+
+type chartKey struct {
+	symbol    string
+	timeframe domain.Timeframe
+}
+
+// ChartTrendService keeps the chart trend per symbol and timeframe up to date from closed candles.
+// Every update is persisted, so after a restart the calculation resumes from the stored state
+// instead of being recomputed from the full history.
+type ChartTrendService struct {
+	mu     sync.Mutex
+	cfg    ChartConfig
+	repo   storage.ChartTrendRepository
+	states map[chartKey]*domain.ChartTrend
+}
+
+// NewChartTrendService creates a ChartTrendService. repo may be nil to keep the state in memory only.
+func NewChartTrendService(cfg ChartConfig, repo storage.ChartTrendRepository) *ChartTrendService {
+	return &ChartTrendService{
+		cfg:    cfg,
+		repo:   repo,
+		states: make(map[chartKey]*domain.ChartTrend),
+	}
+}
+
+// Resume loads the stored trends of a symbol and then feeds the history candles that closed after
+// the stored state of their timeframe. Candles must be ordered by close time.
+func (s *ChartTrendService) Resume(ctx context.Context, symbol string, history []domain.Candle) error {
+	if s.repo != nil {
+		trends, err := s.repo.LoadChartTrends(ctx, symbol)
+		if err != nil {
+			return fmt.Errorf("could not load chart trends: %w", err)
+		}
+		s.mu.Lock()
+		for i := range trends {
+			t := trends[i]
+			s.states[chartKey{symbol: t.Symbol, timeframe: t.Timeframe}] = &t
+		}
+		s.mu.Unlock()
+	}
+
+	for _, c := range history {
+		if err := s.OnCandle(ctx, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnCandle updates the trend of the candle's timeframe. Candles that are not newer than the
+// current state are skipped, so history and live candles may overlap.
+func (s *ChartTrendService) OnCandle(ctx context.Context, c domain.Candle) error {
+	// The overall trend ranks the timeframes by their length; non-time bars, e.g. the Renko
+	// bricks a strategy runs on, have none.
+	if c.Timeframe.Duration() == 0 {
+		return nil
+	}
+	// The state is saved under the lock too, so the saves of concurrent candles land in order and
+	// the stored state is never older than the one in memory.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := chartKey{symbol: c.Symbol, timeframe: c.Timeframe}
+	state, ok := s.states[key]
+	if !ok {
+		state = &domain.ChartTrend{Symbol: c.Symbol, Timeframe: c.Timeframe, Trend: domain.Neutral}
+		s.states[key] = state
+	}
+	if !c.CloseTime.After(state.CandleTime) {
+		return nil
+	}
+	s.cfg.update(state, c)
+
+	if s.repo == nil {
+		return nil
+	}
+	if err := s.repo.SaveChartTrend(ctx, *state); err != nil {
+		return fmt.Errorf("could not save chart trend: %w", err)
+	}
+	return nil
+}
+
+// update advances the EMAs of a state by one candle and re-classifies the trend.
+func (cfg ChartConfig) update(state *domain.ChartTrend, c domain.Candle) {
+	if state.Samples == 0 {
+		state.FastEMA = c.Close
+		state.SlowEMA = c.Close
+	} else {
+		state.FastEMA = ema(state.FastEMA, c.Close, cfg.FastPeriod)
+		state.SlowEMA = ema(state.SlowEMA, c.Close, cfg.SlowPeriod)
+	}
+	state.Samples++
+	state.CandleTime = c.CloseTime
+
+	state.Strength = 0
+	if state.SlowEMA != 0 {
+		state.Strength = (state.FastEMA - state.SlowEMA) / state.SlowEMA * 100
+	}
+	state.Trend = domain.Neutral
+	if state.Samples < cfg.SlowPeriod {
+		return
+	}
+	switch {
+	case state.Strength > cfg.NeutralBand && c.Close > state.SlowEMA:
+		state.Trend = domain.Bullish
+	case state.Strength < -cfg.NeutralBand && c.Close < state.SlowEMA:
+		state.Trend = domain.Bearish
+	}
+}
+
+func ema(prev, value float64, period int) float64 {
+	k := 2 / float64(period+1)
+	return value*k + prev*(1-k)
+}
+
+// Trend returns the current trend of a symbol on a timeframe.
+func (s *ChartTrendService) Trend(symbol string, tf domain.Timeframe) (domain.ChartTrend, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[chartKey{symbol: symbol, timeframe: tf}]
+	if !ok {
+		return domain.ChartTrend{}, false
+	}
+	return *state, true
+}
+
+// Trends returns the current trends of a symbol, ordered from the shortest to the longest timeframe.
+func (s *ChartTrendService) Trends(symbol string) []domain.ChartTrend {
+	s.mu.Lock()
+	var trends []domain.ChartTrend
+	for key, state := range s.states {
+		if key.symbol == symbol {
+			trends = append(trends, *state)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(trends, func(i, j int) bool {
+		return trends[i].Timeframe.Duration() < trends[j].Timeframe.Duration()
+	})
+	return trends
+}
+
+// Overall derives the overall chart trend of a symbol from its per-timeframe trends.
+// Each timeframe votes with its rank from the shortest one, 1 for the shortest, so the longer
+// timeframes dominate without one of them outvoting all the others.
+func (s *ChartTrendService) Overall(symbol string) domain.Trend {
+	return OverallTrend(s.Trends(symbol))
+}
+
+// OverallTrend combines per-timeframe trends, ordered from short to long, into one trend. The
+// trend at index i votes with weight i+1.
+func OverallTrend(trends []domain.ChartTrend) domain.Trend {
+	score := 0
+	for i, t := range trends {
+		switch t.Trend {
+		case domain.Bullish:
+			score += i + 1
+		case domain.Bearish:
+			score -= i + 1
+		}
+	}
+	switch {
+	case score > 0:
+		return domain.Bullish
+	case score < 0:
+		return domain.Bearish
+	default:
+		return domain.Neutral
+	}
+}
+
+// ChartHistory recomputes the chart trend after every candle of a single symbol and timeframe.
+func ChartHistory(cfg ChartConfig, candles []domain.Candle) []domain.ChartTrend {
+	var state domain.ChartTrend
+	out := make([]domain.ChartTrend, 0, len(candles))
+	for _, c := range candles {
+		state.Symbol, state.Timeframe = c.Symbol, c.Timeframe
+		cfg.update(&state, c)
+		out = append(out, state)
+	}
+	return out
+}
@@ -0,0 +1,228 @@
+package trend
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/exchange"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
+)
+
+// MarketConfig holds the settings of the market trend calculation.
+type MarketConfig struct {
+	Timeframes []domain.Timeframe
+	// QuoteAsset limits the universe to pairs quoted in this asset, e.g. "USDT". Empty means all pairs.
+	QuoteAsset string
+	// Threshold is the breadth, in percentage points, beyond which the market is bullish or bearish.
+	Threshold float64
+}
+
+// DefaultMarketConfig returns the settings used by the scanner.
+func DefaultMarketConfig() MarketConfig {
+	return MarketConfig{
+		Timeframes: []domain.Timeframe{domain.Minute5, domain.Minute15, domain.Hour1, domain.Hour4, domain.Day1},
+		QuoteAsset: "USDT",
+		Threshold:  10,
+	}
+}
+
+// marketPeriod collects the prices of all pairs during one timeframe period.
+type marketPeriod struct {
+	open   time.Time
+	first  map[string]float64
+	last   map[string]float64
+	weight map[string]float64
+}
+
+// MarketTrendService calculates the breadth of an exchange's market per timeframe from the
+// all-market ticker feed. Each completed period is classified and persisted as a time series.
+type MarketTrendService struct {
+	mu       sync.Mutex
+	cfg      MarketConfig
+	exchange string
+	repo     storage.MarketTrendRepository
+	periods  map[domain.Timeframe]*marketPeriod
+	latest   map[domain.Timeframe]domain.MarketTrend
+}
+
+// NewMarketTrendService creates a MarketTrendService. repo may be nil to keep the results in memory only.
+func NewMarketTrendService(exchangeName string, cfg MarketConfig, repo storage.MarketTrendRepository) *MarketTrendService {
+	return &MarketTrendService{
+		cfg:      cfg,
+		exchange: exchangeName,
+		repo:     repo,
+		periods:  make(map[domain.Timeframe]*marketPeriod),
+		latest:   make(map[domain.Timeframe]domain.MarketTrend),
+	}
+}
+
+// Run consumes the all-market feed until the context is cancelled or the stream fails.
+func (s *MarketTrendService) Run(ctx context.Context, streamer exchange.MarketStreamer) error {
+	batchChan, errChan := streamer.StreamMarket(ctx)
+	for {
+		select {
+		case batch, ok := <-batchChan:
+			if !ok {
+				return nil
+			}
+			if _, err := s.OnTickers(ctx, batch); err != nil {
+				log.Printf("Error updating market trend: %v", err)
+			}
+		case err := <-errChan:
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// OnTickers adds a batch of tickers and returns the market trends of the periods it completed.
+func (s *MarketTrendService) OnTickers(ctx context.Context, batch []domain.Ticker) ([]domain.MarketTrend, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+	var at int64
+	for _, t := range batch {
+		if t.EventTime > at {
+			at = t.EventTime
+		}
+	}
+	now := time.UnixMilli(at).UTC()
+
+	s.mu.Lock()
+	var completed []domain.MarketTrend
+	for _, tf := range s.cfg.Timeframes {
+		open := tf.Truncate(now)
+		p := s.periods[tf]
+		if p != nil && open.Before(p.open) {
+			continue
+		}
+		if p != nil && open.After(p.open) {
+			m := s.cfg.breadth(p, tf)
+			m.Exchange = s.exchange
+			s.latest[tf] = m
+			completed = append(completed, m)
+			// The next period starts from the last known prices, so no move between periods is lost.
+			p = &marketPeriod{open: open, first: p.last, last: copyPrices(p.last), weight: p.weight}
+			s.periods[tf] = p
+		}
+		if p == nil {
+			p = &marketPeriod{
+				open:   open,
+				first:  make(map[string]float64),
+				last:   make(map[string]float64),
+				weight: make(map[string]float64),
+			}
+			s.periods[tf] = p
+		}
+		for _, t := range batch {
+			if !s.cfg.inUniverse(t.Symbol) {
+				continue
+			}
+			price := t.LastPrice.Float64Value()
+			if _, ok := p.first[t.Symbol]; !ok {
+				p.first[t.Symbol] = price
+			}
+			p.last[t.Symbol] = price
+			p.weight[t.Symbol] = t.QuoteVolume.Float64Value()
+		}
+	}
+	s.mu.Unlock()
+
+	if s.repo != nil {
+		for _, m := range completed {
+			if err := s.repo.SaveMarketTrend(ctx, m); err != nil {
+				return completed, fmt.Errorf("could not save market trend: %w", err)
+			}
+		}
+	}
+	return completed, nil
+}
+
+// Latest returns the market trend of the last completed period of a timeframe.
+func (s *MarketTrendService) Latest(tf domain.Timeframe) (domain.MarketTrend, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.latest[tf]
+	return m, ok
+}
+
+func (cfg MarketConfig) inUniverse(symbol string) bool {
+	return cfg.QuoteAsset == "" || strings.HasSuffix(symbol, cfg.QuoteAsset)
+}
+
+// breadth calculates the market trend of a completed period.
+func (cfg MarketConfig) breadth(p *marketPeriod, tf domain.Timeframe) domain.MarketTrend {
+	m := domain.MarketTrend{Timeframe: tf, Time: p.open.Add(tf.Duration())}
+	var weighted, weights float64
+	symbols := make([]string, 0, len(p.last))
+	for symbol := range p.last {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols) // Deterministic summation order.
+	for _, symbol := range symbols {
+		first := p.first[symbol]
+		if first == 0 {
+			continue
+		}
+		change := (p.last[symbol] - first) / first * 100
+		m.Pairs++
+		switch {
+		case change > 0:
+			m.Advancing++
+		case change < 0:
+			m.Declining++
+		}
+		weighted += change * p.weight[symbol]
+		weights += p.weight[symbol]
+	}
+	if weights > 0 {
+		m.VolumeWeightedChange = weighted / weights
+	}
+	m.Breadth = m.AdvancingPct() - m.DecliningPct()
+	m.Trend = ClassifyBreadth(m.Breadth, cfg.Threshold)
+	return m
+}
+
+// ClassifyBreadth turns a breadth value into a trend using a symmetric threshold.
+func ClassifyBreadth(breadth, threshold float64) domain.Trend {
+	switch {
+	case breadth > threshold:
+		return domain.Bullish
+	case breadth < -threshold:
+		return domain.Bearish
+	default:
+		return domain.Neutral
+	}
+}
+
+func copyPrices(prices map[string]float64) map[string]float64 {
+	out := make(map[string]float64, len(prices))
+	for k, v := range prices {
+		out[k] = v
+	}
+	return out
+}
+
+// MarketHistory recomputes the market trend over stored tickers of all pairs, ordered by event time.
+// Tickers are replayed in one-second batches, like the live all-market feed delivers them.
+func MarketHistory(exchangeName string, cfg MarketConfig, tickers []domain.Ticker) []domain.MarketTrend {
+	svc := NewMarketTrendService(exchangeName, cfg, nil)
+	var out []domain.MarketTrend
+	for start := 0; start < len(tickers); {
+		end := start + 1
+		for end < len(tickers) && tickers[end].EventTime/1000 == tickers[start].EventTime/1000 {
+			end++
+		}
+		completed, _ := svc.OnTickers(context.Background(), tickers[start:end])
+		out = append(out, completed...)
+		start = end
+	}
+	return out
+}
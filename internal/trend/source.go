@@ -0,0 +1,26 @@
+package trend
+
+import "github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+
+// Source gives strategies the trends of the trend services, so a confirmation sees the trend the
+// user sees on the opportunity cards. It implements strategy.Trends.
+type Source struct {
+	Chart  *ChartTrendService  // Nil when the chart trend is not tracked.
+	Market *MarketTrendService // Nil when the market trend is not tracked.
+}
+
+// ChartTrend returns the chart trend of a pair on a timeframe.
+func (s Source) ChartTrend(symbol string, tf domain.Timeframe) (domain.ChartTrend, bool) {
+	if s.Chart == nil {
+		return domain.ChartTrend{}, false
+	}
+	return s.Chart.Trend(symbol, tf)
+}
+
+// MarketTrend returns the market trend of the last completed period of a timeframe.
+func (s Source) MarketTrend(tf domain.Timeframe) (domain.MarketTrend, bool) {
+	if s.Market == nil {
+		return domain.MarketTrend{}, false
+	}
+	return s.Market.Latest(tf)
+}
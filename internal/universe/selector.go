@@ -0,0 +1,207 @@
+// Package universe selects the coin pairs the strategies run on from the all-market feed.
+package universe
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+// Bounds is an optional minimum and maximum; a nil bound is not checked.
+type Bounds struct {
+	Min *float64
+	Max *float64
+}
+
+// Config holds the pair filters of the strategy settings.
+type Config struct {
+	// QuoteAsset limits the universe to pairs quoted in this asset, e.g. "USDT". Empty means all pairs.
+	QuoteAsset string
+	// Volume24h bounds the 24h trading volume, in the quote asset.
+	Volume24h Bounds
+	// Change24h bounds the 24h price change in percent: the minimum filters out bearish pairs,
+	// the maximum bullish ones.
+	Change24h Bounds
+	// VolumeHysteresis widens the volume bounds, in percent, before a selected pair is removed.
+	VolumeHysteresis float64
+	// ChangeHysteresis widens the change bounds, in percentage points, before a selected pair is removed.
+	ChangeHysteresis float64
+	// MinHold is the minimum time a pair stays selected once added.
+	MinHold time.Duration
+	// MaxPairs caps the number of selected pairs; zero means no limit. When more pairs qualify,
+	// those with the highest volume are added first.
+	MaxPairs int
+}
+
+// DefaultConfig selects the USDT pairs with at least 1M USDT of 24h volume.
+func DefaultConfig() Config {
+	minVolume := 1_000_000.0
+	return Config{
+		QuoteAsset:       "USDT",
+		Volume24h:        Bounds{Min: &minVolume},
+		VolumeHysteresis: 10,
+		ChangeHysteresis: 1,
+		MinHold:          15 * time.Minute,
+		MaxPairs:         20,
+	}
+}
+
+// Membership is told which pairs entered or left the universe, e.g. to start or stop strategies.
+type Membership interface {
+	AddPair(ctx context.Context, symbol string) error
+	RemovePair(ctx context.Context, symbol string) error
+}
+
+// Change records a pair entering or leaving the universe.
+type Change struct {
+	Symbol string
+	Added  bool
+	Reason string
+	Time   time.Time
+}
+
+// Selector keeps the set of pairs that pass the filters up to date from the all-market feed.
+// A selected pair is only removed when it fails the filters widened by the hysteresis and it was
+// selected for at least MinHold, so pairs near a bound do not flap in and out.
+type Selector struct {
+	mu       sync.Mutex
+	cfg      Config
+	target   Membership
+	selected map[string]time.Time // Selected pairs and the time they were added.
+}
+
+// NewSelector creates a Selector. target may be nil when the changes are only read from OnTickers.
+func NewSelector(cfg Config, target Membership) *Selector {
+	return &Selector{cfg: cfg, target: target, selected: make(map[string]time.Time)}
+}
+
+// OnTickers re-evaluates the pairs in a batch of tickers and returns the pairs that entered or left.
+// Pairs missing from the batch keep their state.
+func (s *Selector) OnTickers(ctx context.Context, batch []domain.Ticker) []Change {
+	s.mu.Lock()
+	var changes, candidates []Change
+	volumes := make(map[string]float64)
+	for _, t := range batch {
+		if s.cfg.QuoteAsset != "" && !strings.HasSuffix(t.Symbol, s.cfg.QuoteAsset) {
+			continue
+		}
+		now := time.UnixMilli(t.EventTime).UTC()
+		volume, change := t.QuoteVolume.Float64Value(), t.PriceChangePercent.Float64Value()
+		since, selected := s.selected[t.Symbol]
+		if !selected {
+			if reason, ok := s.cfg.check(volume, change, 0); ok {
+				candidates = append(candidates, Change{Symbol: t.Symbol, Added: true, Reason: reason, Time: now})
+				volumes[t.Symbol] = volume
+			}
+			continue
+		}
+		if reason, ok := s.cfg.check(volume, change, 1); !ok && now.Sub(since) >= s.cfg.MinHold {
+			delete(s.selected, t.Symbol)
+			changes = append(changes, Change{Symbol: t.Symbol, Reason: reason, Time: now})
+		}
+	}
+
+	// The pairs with the highest volume get the free places first.
+	sort.Slice(candidates, func(i, j int) bool { return volumes[candidates[i].Symbol] > volumes[candidates[j].Symbol] })
+	for _, c := range candidates {
+		if s.cfg.MaxPairs > 0 && len(s.selected) >= s.cfg.MaxPairs {
+			break
+		}
+		s.selected[c.Symbol] = c.Time
+		changes = append(changes, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range changes {
+		s.apply(ctx, c)
+	}
+	return changes
+}
+
+// apply logs a change and passes it to the target.
+func (s *Selector) apply(ctx context.Context, c Change) {
+	var err error
+	if c.Added {
+		log.Printf("Universe: added %s, %s", c.Symbol, c.Reason)
+		if s.target != nil {
+			err = s.target.AddPair(ctx, c.Symbol)
+		}
+	} else {
+		log.Printf("Universe: removed %s, %s", c.Symbol, c.Reason)
+		if s.target != nil {
+			err = s.target.RemovePair(ctx, c.Symbol)
+		}
+	}
+	if err != nil {
+		log.Printf("Universe: could not update %s: %v", c.Symbol, err)
+	}
+}
+
+// Pairs returns the selected pairs, sorted by symbol.
+func (s *Selector) Pairs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pairs := make([]string, 0, len(s.selected))
+	for symbol := range s.selected {
+		pairs = append(pairs, symbol)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// Selected reports whether a pair is in the universe.
+func (s *Selector) Selected(symbol string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.selected[symbol]
+	return ok
+}
+
+// check tests a pair against the filters, widened by the hysteresis times the given factor.
+// It returns why the pair passes, or why it fails.
+func (cfg Config) check(volume, change, hysteresis float64) (string, bool) {
+	volumeMargin := cfg.VolumeHysteresis / 100 * hysteresis
+	changeMargin := cfg.ChangeHysteresis * hysteresis
+	if b := cfg.Volume24h.Min; b != nil && volume < *b*(1-volumeMargin) {
+		return fmt.Sprintf("24h volume %.0f below minimum %.0f", volume, *b*(1-volumeMargin)), false
+	}
+	if b := cfg.Volume24h.Max; b != nil && volume > *b*(1+volumeMargin) {
+		return fmt.Sprintf("24h volume %.0f above maximum %.0f", volume, *b*(1+volumeMargin)), false
+	}
+	if b := cfg.Change24h.Min; b != nil && change < *b-changeMargin {
+		return fmt.Sprintf("24h change %.2f%% below minimum %.2f%%", change, *b-changeMargin), false
+	}
+	if b := cfg.Change24h.Max; b != nil && change > *b+changeMargin {
+		return fmt.Sprintf("24h change %.2f%% above maximum %.2f%%", change, *b+changeMargin), false
+	}
+	return fmt.Sprintf("24h volume %.0f within %s, 24h change %.2f%% within %s", volume, cfg.Volume24h, change, cfg.Change24h), true
+}
+
+// String formats the bounds as an interval, e.g. "[1000000, ∞]".
+func (b Bounds) String() string {
+	lo, hi := math.Inf(-1), math.Inf(1)
+	if b.Min != nil {
+		lo = *b.Min
+	}
+	if b.Max != nil {
+		hi = *b.Max
+	}
+	format := func(v float64) string {
+		if math.IsInf(v, 0) {
+			if v < 0 {
+				return "-∞"
+			}
+			return "∞"
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return "[" + format(lo) + ", " + format(hi) + "]"
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/audit"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/report"
+)
+
+// auditTicker returns a ticker with 24h statistics that close at its event time.
+func auditTicker(at time.Time, price, volume float64, count int64) domain.Ticker {
+	t := marketTicker("BTCUSDT", at, price, 0)
+	t.Volume = domain.BigString{Float: bigFloat(volume)}
+	t.CloseTime, t.Count = at.UnixMilli(), count
+	return t
+}
+
+func TestAuditFindsIssues(t *testing.T) {
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	var tickers []domain.Ticker
+	for i := 0; i < 40; i++ {
+		price := 100.0
+		if i == 25 {
+			price = 150
+		}
+		tickers = append(tickers, auditTicker(start.Add(time.Duration(i)*10*time.Second), price, float64(i), int64(i)))
+		if i == 30 {
+			// The same update stored again half a second later.
+			dup := tickers[i]
+			dup.EventTime += 500
+			tickers = append(tickers, dup)
+		}
+	}
+	// After 20 minutes without tickers, ten minutes in which the volume does not grow.
+	resume := start.Add(400*time.Second + 20*time.Minute)
+	for i := 0; i < 60; i++ {
+		tickers = append(tickers, auditTicker(resume.Add(time.Duration(i)*10*time.Second), 100, 1000, 1000))
+	}
+	to := resume.Add(600 * time.Second)
+
+	rep := audit.Check("BTCUSDT", start, to, tickers, audit.DefaultConfig())
+	for kind, want := range map[audit.Kind]int{audit.Gap: 1, audit.Duplicate: 1, audit.Outlier: 1, audit.ZeroVolume: 1, audit.OutOfOrder: 0} {
+		if got := rep.Count(kind); got != want {
+			t.Errorf("expected %d %s issues, got %d: %+v", want, kind, got, rep.Issues)
+		}
+	}
+	if rep.Missing != 20*time.Minute+10*time.Second {
+		t.Errorf("expected the gap to miss 20m10s, got %s", rep.Missing)
+	}
+	for i := 1; i < len(rep.Issues); i++ {
+		if rep.Issues[i].From.Before(rep.Issues[i-1].From) {
+			t.Fatalf("expected the issues in time order, got %+v", rep.Issues)
+		}
+	}
+	if rep.OK() || !strings.Contains(rep.Summary(), "gap: 1 (20m10s missing)") {
+		t.Errorf("expected a summary of the issues, got %q", rep.Summary())
+	}
+
+	clean := audit.Check("BTCUSDT", start, start.Add(400*time.Second), tickers[:25], audit.DefaultConfig())
+	if !clean.OK() || clean.Summary() != "no issues" {
+		t.Errorf("expected a clean history, got %+v", clean.Issues)
+	}
+}
+
+func TestAuditFindsTickersStoredOutOfOrder(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	storeRamp(t, repo, "BTCUSDT", start, 12)
+	late := marketTicker("BTCUSDT", start.Add(45*time.Second), 101.5, 0)
+	late.Volume = domain.BigString{Float: bigFloat(1.5)}
+	if err := repo.SaveTicker(ctx, late); err != nil {
+		t.Fatalf("SaveTicker failed: %v", err)
+	}
+
+	rep, err := audit.Run(ctx, repo, "BTCUSDT", start, start.Add(6*time.Minute), audit.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if rep.Tickers != 13 || len(rep.Issues) != 1 || rep.Issues[0].Kind != audit.OutOfOrder || !rep.Issues[0].From.Equal(start.Add(45*time.Second)) {
+		t.Fatalf("expected the late ticker to be out of order, got %d tickers and %+v", rep.Tickers, rep.Issues)
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteAudit(&out, rep, 0); err != nil {
+		t.Fatalf("WriteAudit failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "out-of-order: 1") || !strings.Contains(out.String(), "stored after a ticker of 2025-10-01 00:05:30") {
+		t.Errorf("expected the issue in the audit, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,402 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/report"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+)
+
+// storeRamp stores a ticker every 30 seconds from start, with prices 100, 101, 102, ...
+func storeRamp(t *testing.T, repo interface {
+	SaveTicker(ctx context.Context, ticker domain.Ticker) error
+}, symbol string, start time.Time, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		ticker := marketTicker(symbol, start.Add(time.Duration(i)*30*time.Second), 100+float64(i), 0)
+		ticker.Volume = domain.BigString{Float: bigFloat(float64(i))}
+		if err := repo.SaveTicker(context.Background(), ticker); err != nil {
+			t.Fatalf("SaveTicker failed: %v", err)
+		}
+	}
+}
+
+func TestBacktestFillsOnTheNextTick(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	storeRamp(t, repo, "BTCUSDT", start, 12)
+
+	cfg := backtest.DefaultConfig()
+	cfg.Symbol, cfg.Timeframe, cfg.From, cfg.To, cfg.WarmUp = "BTCUSDT", domain.Minute1, start, start.Add(time.Hour), 0
+	engine := backtest.NewEngine(cfg, repo)
+	engine.Add(domain.Minute1, func() strategy.Strategy {
+		return &scripted{script: []domain.Direction{domain.Buy, "", domain.Sell}}
+	})
+	res, err := engine.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// The first candle closes on the ticker at 100+2; its buy fills on the next ticker at 103.
+	// The sell of the third candle fills at 107, and the second buy at 109 is still open at the
+	// end, where it is closed at the last price of 111.
+	want := []struct {
+		entry, exit float64
+		reason      backtest.ExitReason
+	}{{103, 107, backtest.ExitSignal}, {109, 111, backtest.ExitEnd}}
+	if len(res.Trades) != len(want) {
+		t.Fatalf("expected %d trades, got %+v", len(want), res.Trades)
+	}
+	equity := cfg.InitialBalance
+	for i, w := range want {
+		tr := res.Trades[i]
+		if tr.EntryPrice != w.entry || tr.ExitPrice != w.exit || tr.ExitReason != w.reason {
+			t.Errorf("trade %d: expected %v → %v (%s), got %v → %v (%s)", i, w.entry, w.exit, w.reason, tr.EntryPrice, tr.ExitPrice, tr.ExitReason)
+		}
+		if !almostEqual(tr.Quantity, equity/w.entry) || !almostEqual(tr.PnL, (w.exit-w.entry)*tr.Quantity) || tr.RunUp < tr.PnL || tr.Drawdown != 0 {
+			t.Errorf("trade %d: unexpected quantity, P&L or excursions: %+v", i, tr)
+		}
+		equity += tr.PnL
+	}
+	if !almostEqual(res.FinalEquity, equity) {
+		t.Errorf("expected a final equity of %v, got %v", equity, res.FinalEquity)
+	}
+	// Five 1m candles closed; the position opened at 103 is valued at the close of the second.
+	if len(res.Equity) != 5 || !res.Equity[1].InPosition || !almostEqual(res.Equity[1].Equity, cfg.InitialBalance+(103-103)*res.Trades[0].Quantity) {
+		t.Errorf("unexpected equity curve: %+v", res.Equity)
+	}
+}
+
+func TestBacktestWarmUpDoesNotTrade(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	storeRamp(t, repo, "BTCUSDT", start, 12)
+
+	cfg := backtest.DefaultConfig()
+	cfg.Symbol, cfg.Timeframe, cfg.WarmUp = "BTCUSDT", domain.Minute1, 10*time.Minute
+	cfg.From, cfg.To = start.Add(4*time.Minute), start.Add(time.Hour)
+	cfg.Trigger = strategy.Trigger{Mode: strategy.LevelTriggered}
+	s := &scripted{script: []domain.Direction{domain.Buy}}
+	engine := backtest.NewEngine(cfg, repo)
+	engine.Add(domain.Minute1, func() strategy.Strategy { return s })
+	res, err := engine.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// The strategy saw the warm-up candles, but only the buys of the candles closing in the period
+	// count; the first fills at 109, the second is ignored while the position is open.
+	if s.calls != 5 || len(res.Signals) != 2 || len(res.Trades) != 1 || res.Trades[0].EntryPrice != 109 {
+		t.Errorf("expected 5 strategy calls and one trade entered at 109, got %d calls, signals %+v, trades %+v", s.calls, res.Signals, res.Trades)
+	}
+}
+
+func TestBacktestAggregatesTheStrategyTimeframes(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	storeRamp(t, repo, "BTCUSDT", start, 4*60*2+1)
+
+	// 30m is not one of the default timeframes of the application.
+	cfg := backtest.DefaultConfig()
+	cfg.Symbol, cfg.Timeframe, cfg.From, cfg.To, cfg.WarmUp = "BTCUSDT", domain.Minute30, start, start.Add(5*time.Hour), 0
+	s := &scripted{script: []domain.Direction{""}}
+	engine := backtest.NewEngine(cfg, repo)
+	engine.Add(domain.Minute30, func() strategy.Strategy { return s })
+	res, err := engine.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if s.calls != 8 || len(res.Equity) != 8 {
+		t.Errorf("expected the eight 30m candles of four hours, got %d calls and %d equity points", s.calls, len(res.Equity))
+	}
+}
+
+// slowScripted is a scripted strategy that needs five candles.
+type slowScripted struct{ *scripted }
+
+func (slowScripted) WarmUp() int { return 5 }
+
+func TestBacktestExtendsTheWarmUpToWhatTheStrategiesNeed(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	storeRamp(t, repo, "BTCUSDT", start.Add(-10*time.Minute), 40)
+
+	cfg := backtest.DefaultConfig()
+	cfg.Symbol, cfg.Timeframe, cfg.From, cfg.To, cfg.WarmUp = "BTCUSDT", domain.Minute1, start, start.Add(10*time.Minute), 0
+	s := slowScripted{&scripted{script: []domain.Direction{""}}}
+	engine := backtest.NewEngine(cfg, repo)
+	engine.Add(domain.Minute1, func() strategy.Strategy { return s })
+	if _, err := engine.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// Six minutes of warm-up: the 1m candles from 23:54 to 00:08 close, the first five warm up.
+	if s.calls != 11 {
+		t.Errorf("expected the strategy to be warmed up before the period, got %d calls", s.calls)
+	}
+}
+
+func TestBacktestBuildsTheBarsOfTheStrategies(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	storeRamp(t, repo, "BTCUSDT", start, 121)
+
+	cfg := backtest.DefaultConfig()
+	cfg.Symbol, cfg.Timeframe, cfg.From, cfg.To, cfg.WarmUp = "BTCUSDT", domain.Minute5, start, start.Add(time.Hour), 0
+	heikinAshi := &scripted{script: []domain.Direction{""}}
+	renko := &scripted{script: []domain.Direction{""}}
+	engine := backtest.NewEngine(cfg, repo)
+	engine.Add("ha:5m", func() strategy.Strategy { return heikinAshi })
+	engine.Add("renko:5", func() strategy.Strategy { return renko })
+	if _, err := engine.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// The price climbs from 100 to 219 within the hour: eleven 5m candles close, and 23 bricks of 5.
+	if heikinAshi.calls != 11 || renko.calls != 23 {
+		t.Errorf("expected 11 Heikin-Ashi candles and 23 bricks, got %d and %d", heikinAshi.calls, renko.calls)
+	}
+}
+
+func TestAnalyzeBacktest(t *testing.T) {
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return start.Add(time.Duration(h) * time.Hour) }
+	res := &backtest.Result{
+		Timeframe: domain.Hour1, From: start, To: at(10), InitialBalance: 1000, FinalEquity: 1100,
+		Trades: []backtest.Trade{
+			{EntryTime: at(0), ExitTime: at(2), PnL: 150, RunUp: 200, Drawdown: 10},
+			{EntryTime: at(3), ExitTime: at(4), PnL: -100, RunUp: 20, Drawdown: 120},
+			{EntryTime: at(5), ExitTime: at(8), PnL: 50, RunUp: 60, Drawdown: 0},
+		},
+		Candles: []domain.Candle{{Open: 10, Close: 11}, {Open: 11, Close: 12}},
+	}
+	for i, e := range []float64{1050, 1150, 1100, 1050, 1050, 1020, 1080, 1100, 1100, 1100} {
+		res.Equity = append(res.Equity, backtest.EquityPoint{Time: at(i + 1), Equity: e})
+	}
+
+	m := backtest.Analyze(res)
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"net profit", m.NetProfit, 100},
+		{"net profit %", m.NetProfitPercent, 10},
+		{"gross profit", m.GrossProfit, 200},
+		{"gross loss", m.GrossLoss, 100},
+		{"max drawdown", m.MaxDrawdown, 130},
+		{"max drawdown %", m.MaxDrawdownPercent, 130.0 / 1150 * 100},
+		{"profitable trades %", float64(m.ProfitableTrades), 200.0 / 3},
+		{"profit factor", float64(m.ProfitFactor), 2},
+		{"average trade", float64(m.AverageTrade), 100.0 / 3},
+		{"average loss", float64(m.AverageLoss), -100},
+		{"largest loss", m.LargestLoss, -100},
+		{"average run-up", float64(m.AverageRunUp), 280.0 / 3},
+		{"max trade drawdown", m.MaxTradeDrawdown, 120},
+		{"buy & hold", m.BuyHold, 200},
+		{"buy & hold %", m.BuyHoldPercent, 20},
+		{"exposure %", m.Exposure, 60},
+		{"average duration (h)", time.Duration(m.AverageTradeDuration).Hours(), 2},
+	}
+	for _, c := range checks {
+		if !almostEqual(c.got, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, c.got)
+		}
+	}
+	if m.TotalTrades != 3 || m.WinningTrades != 2 || m.LosingTrades != 1 {
+		t.Errorf("unexpected trade counts: %+v", m)
+	}
+	if !(m.Sharpe > 0) || !(m.Sortino > m.Sharpe) {
+		t.Errorf("expected a positive Sharpe ratio below the Sortino ratio, got %v and %v", m.Sharpe, m.Sortino)
+	}
+}
+
+func TestBacktestReportJSON(t *testing.T) {
+	res := &backtest.Result{Symbol: "BTCUSDT", Timeframe: domain.Hour1, InitialBalance: 1000, FinalEquity: 1000}
+	var b strings.Builder
+	if err := report.NewBacktestReport(res).WriteJSON(&b); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	for _, want := range []string{`"profit_factor": null`, `"sharpe": null`, `"trades": []`, `"total_trades": 0`} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("expected the JSON report to contain %s, got %s", want, b.String())
+		}
+	}
+	b.Reset()
+	if err := report.NewBacktestReport(res).WriteTable(&b); err != nil || !strings.Contains(b.String(), "Profit factor") {
+		t.Errorf("expected a metrics table, got %v: %s", err, b.String())
+	}
+}
+
+func TestBacktestReportHTML(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	storeRamp(t, repo, "BTCUSDT", start, 12)
+
+	cfg := backtest.DefaultConfig()
+	cfg.Symbol, cfg.Timeframe, cfg.From, cfg.To, cfg.WarmUp = "BTCUSDT", domain.Minute1, start, start.Add(time.Hour), 0
+	engine := backtest.NewEngine(cfg, repo)
+	engine.Add(domain.Minute1, func() strategy.Strategy {
+		return &scripted{script: []domain.Direction{domain.Buy, "", domain.Sell}}
+	})
+	res, err := engine.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	var b strings.Builder
+	if err := report.NewBacktestReport(res).WriteHTML(&b, res); err != nil {
+		t.Fatalf("WriteHTML failed: %v", err)
+	}
+	page := b.String()
+	for _, want := range []string{"<svg", "Buy &amp; hold", "Drawdown %", "#1 buy entry at 103.0000", `<td data-sort="388.`, "Profit factor"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected the HTML report to contain %q", want)
+		}
+	}
+	for _, external := range []string{"<script src", "<link", "@import"} {
+		if strings.Contains(page, external) {
+			t.Errorf("expected a self-contained HTML report, found %q", external)
+		}
+	}
+}
+
+func TestFillModels(t *testing.T) {
+	buy := backtest.Order{Direction: domain.Buy, Quantity: 2}
+	sell := backtest.Order{Direction: domain.Sell, Quantity: 2}
+	m := backtest.Market{Price: 100}
+
+	vip0 := backtest.BinanceSpotTiers[0]
+	cost := backtest.CostFill{Fees: vip0, Slippage: backtest.FixedSlippage(0.5)}
+	if f := cost.Fill(buy, m); !almostEqual(f.Price, 100.5) || !almostEqual(f.Fee, 2*100.5*0.001) {
+		t.Errorf("expected a buy to fill at 100.5 with a taker fee, got %+v", f)
+	}
+	if f := cost.Fill(sell, m); !almostEqual(f.Price, 99.5) {
+		t.Errorf("expected a sell to fill at 99.5, got %+v", f)
+	}
+	maker := backtest.CostFill{Fees: backtest.BinanceSpotTiers[3], Slippage: backtest.FixedSlippage(0.5)}
+	if f := maker.Fill(backtest.Order{Direction: domain.Sell, Quantity: 2, Maker: true}, m); f.Price != 100 || !almostEqual(f.Fee, 200*0.00042) {
+		t.Errorf("expected a maker order to fill at the price with the maker fee, got %+v", f)
+	}
+
+	// Candles with a true range of 2 give an ATR of 2, 2% of the price.
+	var candles []domain.Candle
+	for i := 0; i < 20; i++ {
+		candles = append(candles, domain.Candle{Open: 100, High: 101, Low: 99, Close: 100})
+	}
+	vol := backtest.VolatilitySlippage{Factor: 0.5, Period: 14}
+	if p := vol.Percent(backtest.Market{Price: 100, Candles: candles}); !almostEqual(p, 1) {
+		t.Errorf("expected a slippage of half the ATR, 1%%, got %v", p)
+	}
+	if p := vol.Percent(backtest.Market{Price: 100, Candles: candles[:10]}); p != 0 {
+		t.Errorf("expected no slippage before the ATR is warmed up, got %v", p)
+	}
+
+	book := &domain.OrderBook{Time: m.Time, Asks: []domain.PriceLevel{{Price: 101, Quantity: 1}, {Price: 103, Quantity: 0.5}}}
+	depth := backtest.DepthFill{}
+	if f := depth.Fill(buy, backtest.Market{Price: 100, Book: book}); !almostEqual(f.Price, (101+0.5*103+0.5*103)/2) {
+		t.Errorf("expected the rest beyond the book to fill at its last level, got %+v", f)
+	}
+	if f := depth.Fill(buy, backtest.Market{Price: 100, Time: m.Time.Add(time.Hour), Book: book}); f.Price != 100 {
+		t.Errorf("expected a stale book to be ignored, got %+v", f)
+	}
+}
+
+func TestBacktestWithFeesLatencyAndDepth(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	storeRamp(t, repo, "BTCUSDT", start, 12)
+	// The book at the fill of the sell only has 10 units at 106, the rest is at 105.
+	book := domain.OrderBook{Symbol: "BTCUSDT", Time: start.Add(3 * time.Minute),
+		Bids: []domain.PriceLevel{{Price: 106, Quantity: 10}, {Price: 105, Quantity: 1000}}}
+	if err := repo.SaveOrderBook(context.Background(), book); err != nil {
+		t.Fatalf("SaveOrderBook failed: %v", err)
+	}
+
+	cfg := backtest.DefaultConfig()
+	cfg.Symbol, cfg.Timeframe, cfg.From, cfg.To, cfg.WarmUp = "BTCUSDT", domain.Minute1, start, start.Add(time.Hour), 0
+	cfg.Latency = 45 * time.Second
+	cfg.Fill = backtest.DepthFill{Fees: backtest.FeeTier{Taker: 0.1}}
+	engine := backtest.NewEngine(cfg, repo)
+	engine.Add(domain.Minute1, func() strategy.Strategy {
+		return &scripted{script: []domain.Direction{domain.Buy, "", domain.Sell}}
+	})
+	res, err := engine.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(res.Trades) == 0 {
+		t.Fatalf("expected trades")
+	}
+
+	// The buy of 00:01:00 fills at 00:02:00 after the latency, without a book at 104; the sell
+	// of 00:03:00 fills at 00:04:00 through the book.
+	tr := res.Trades[0]
+	quantity := tr.Quantity
+	exit := (10*106 + (quantity-10)*105) / quantity
+	fees := quantity*104*0.001 + quantity*exit*0.001
+	if !tr.EntryTime.Equal(start.Add(2*time.Minute)) || tr.EntryPrice != 104 || !almostEqual(tr.ExitPrice, exit) {
+		t.Errorf("expected an entry at 104 at 00:02 and an exit at %v, got %+v", exit, tr)
+	}
+	if !almostEqual(tr.Fees, fees) || !almostEqual(tr.PnL, (exit-104)*quantity-fees) {
+		t.Errorf("expected fees of %v deducted from the P&L, got %+v", fees, tr)
+	}
+	if !almostEqual(quantity*104+quantity*104*0.001, cfg.InitialBalance) {
+		t.Errorf("expected the entry and its fee to spend the balance, got a quantity of %v", quantity)
+	}
+}
+
+// planned is a scripted strategy whose signals carry a trade plan.
+type planned struct {
+	*scripted
+	plan domain.TradePlan
+}
+
+func (s planned) OnCandle(in strategy.Input) []domain.Signal {
+	signals := s.scripted.OnCandle(in)
+	for i := range signals {
+		plan := s.plan
+		signals[i].Plan = &plan
+	}
+	return signals
+}
+
+func TestBacktestTakeProfitFillsAtItsPrice(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	storeRamp(t, repo, "BTCUSDT", start, 12)
+
+	cfg := backtest.DefaultConfig()
+	cfg.Symbol, cfg.Timeframe, cfg.From, cfg.To, cfg.WarmUp = "BTCUSDT", domain.Minute1, start, start.Add(time.Hour), 0
+	cfg.Fill = backtest.CostFill{Fees: backtest.BinanceSpotTiers[3], Slippage: backtest.FixedSlippage(0.5)}
+	engine := backtest.NewEngine(cfg, repo)
+	engine.Add(domain.Minute1, func() strategy.Strategy {
+		return planned{
+			scripted: &scripted{script: []domain.Direction{domain.Buy, "", "", "", ""}},
+			plan:     domain.TradePlan{TakeProfit: 105.5, Size: domain.SizeRule{Kind: domain.SizeBalancePercent, Value: 50}},
+		}
+	})
+	res, err := engine.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// The buy fills at 103 plus the slippage; the tick at 106 reaches the take-profit, which
+	// rests in the book and fills at 105.5, without slippage and with the maker fee.
+	if len(res.Trades) == 0 {
+		t.Fatalf("expected trades")
+	}
+	tr := res.Trades[0]
+	exitFee := tr.Quantity * 105.5 * 0.00042
+	if tr.ExitReason != backtest.ExitTakeProfit || tr.ExitPrice != 105.5 || !almostEqual(tr.Fees, tr.Quantity*tr.EntryPrice*0.0006+exitFee) {
+		t.Errorf("expected a take-profit exit at 105.5 with the maker fee, got %+v", tr)
+	}
+}
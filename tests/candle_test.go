@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/candle"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+)
+
+func TestHeikinAshi(t *testing.T) {
+	ha := candle.NewHeikinAshi(domain.Minute1)
+	bars := candle.ApplyAll(ha, []domain.Candle{
+		{Symbol: "BTCUSDT", Timeframe: domain.Minute1, Open: 10, High: 14, Low: 8, Close: 12},
+		{Symbol: "BTCUSDT", Timeframe: domain.Minute1, Open: 12, High: 16, Low: 12, Close: 16},
+	})
+	if len(bars) != 2 || bars[0].Timeframe != "ha:1m" {
+		t.Fatalf("expected two ha:1m candles, got %+v", bars)
+	}
+	// First: open (10+12)/2 = 11, close (10+14+8+12)/4 = 11. Second: open (11+11)/2 = 11, close 14.
+	if bars[0].Open != 11 || bars[0].Close != 11 || bars[1].Open != 11 || bars[1].Close != 14 || bars[1].Low != 11 {
+		t.Errorf("unexpected Heikin-Ashi candles: %+v", bars)
+	}
+}
+
+func TestRenkoFixedBoxSize(t *testing.T) {
+	r := candle.NewRenko("", 10)
+	start := time.Date(2025, 10, 2, 20, 0, 0, 0, time.UTC)
+	prices := []float64{100, 105, 125, 112, 99}
+
+	var bricks []domain.Candle
+	for i, p := range prices {
+		bricks = append(bricks, r.Add("BTCUSDT", start.Add(time.Duration(i)*time.Second), p, 1)...)
+	}
+	// 100 -> 125 makes two up bricks (110, 120); 112 is no reversal; 99 reverses with one brick 110 -> 100.
+	if len(bricks) != 3 {
+		t.Fatalf("expected 3 bricks, got %+v", bricks)
+	}
+	if bricks[1].Open != 110 || bricks[1].Close != 120 || bricks[2].Open != 110 || bricks[2].Close != 100 {
+		t.Errorf("unexpected bricks: %+v", bricks)
+	}
+	if bricks[0].Volume != 3 || bricks[2].Volume != 2 {
+		t.Errorf("unexpected brick volumes: %v, %v", bricks[0].Volume, bricks[2].Volume)
+	}
+}
+
+func TestATRRenkoWaitsForWarmUp(t *testing.T) {
+	// The ATR(3) is 2 until the jump to 106, which has a true range of 6 and lifts the ATR to 10/3.
+	candles := makeCandles(100, 100, 100, 101, 106)
+	bricks := candle.ApplyAll(candle.NewATRRenko(domain.Minute1, 3), candles)
+	if len(bricks) != 1 || bricks[0].Timeframe != "renko:1m:atr3" {
+		t.Fatalf("expected one renko:1m:atr3 brick, got %+v", bricks)
+	}
+	if !almostEqual(bricks[0].Close, 100+10.0/3) {
+		t.Errorf("expected the brick to use the ATR box size, got %+v", bricks[0])
+	}
+}
+
+func TestRangeAndVolumeBars(t *testing.T) {
+	candles := makeCandles(100, 101, 104, 103)
+	bars := candle.FromCandles(candle.NewRangeBars(3), candles)
+	for _, b := range bars {
+		if b.High-b.Low < 3 || b.Timeframe != "range:3" {
+			t.Errorf("unexpected range bar: %+v", b)
+		}
+	}
+	if len(bars) == 0 {
+		t.Errorf("expected at least one range bar")
+	}
+
+	volumeBars := candle.FromCandles(candle.NewVolumeBars(2), candles)
+	if len(volumeBars) != 2 || volumeBars[0].Volume != 2 {
+		t.Errorf("expected two volume bars of volume 2, got %+v", volumeBars)
+	}
+}
+
+func TestParseBarTimeframes(t *testing.T) {
+	for in, want := range map[string]domain.Timeframe{
+		"HA:1h":          "ha:1h",
+		"renko:10":       "renko:10",
+		"renko:1m:2.50":  "renko:1m:2.5",
+		"renko:1m:atr14": "renko:1m:atr14",
+		"range:3":        "range:3",
+		"volume:1e3":     "volume:1000",
+	} {
+		tf, err := domain.ParseTimeframe(in)
+		if err != nil || tf != want {
+			t.Errorf("expected %q to parse as %s, got %s, %v", in, want, tf, err)
+			continue
+		}
+		// The parsed name is the name of the bars the candle package builds.
+		builder, transform, err := candle.NewBars(tf)
+		if err != nil {
+			t.Fatalf("NewBars(%s) failed: %v", tf, err)
+		}
+		var name domain.Timeframe
+		switch b := builder.(type) {
+		case *candle.ThresholdBars:
+			name = b.Timeframe()
+		case *candle.Renko:
+			name = b.Timeframe()
+		}
+		switch tr := transform.(type) {
+		case *candle.HeikinAshi:
+			name = candle.HeikinAshiTimeframe(tr.Base())
+		case *candle.Renko:
+			name = tr.Timeframe()
+		}
+		if name != tf {
+			t.Errorf("expected the bars of %s to be named %s, got %s", in, tf, name)
+		}
+	}
+	for _, in := range []string{"ha:2h", "ha:renko:10", "renko:1m:-1", "renko:1m:atr0", "range:nan", "volume", "kagi:1"} {
+		if tf, err := domain.ParseTimeframe(in); err == nil {
+			t.Errorf("expected %q to be rejected, got %s", in, tf)
+		}
+	}
+}
+
+func TestBuildBars(t *testing.T) {
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	var tickers []domain.Ticker
+	for i := 0; i <= 60; i++ {
+		ticker := marketTicker("BTCUSDT", start.Add(time.Duration(i)*30*time.Second), 100+float64(i), 0)
+		tickers = append(tickers, ticker)
+	}
+	candles := candle.Build(tickers, "ha:5m", "renko:5", "range:100")
+	if n := len(candle.OfTimeframe(candles, "ha:5m")); n != 6 {
+		t.Errorf("expected six ha:5m candles, got %d", n)
+	}
+	if n := len(candle.OfTimeframe(candles, "renko:5")); n != 12 {
+		t.Errorf("expected twelve renko:5 bricks, got %d", n)
+	}
+	if n := len(candle.OfTimeframe(candles, domain.Minute5)); n != 0 {
+		t.Errorf("expected no candles of the base timeframe, got %d", n)
+	}
+	if len(candles) != 18 {
+		t.Errorf("expected only the bars asked for, got %d candles", len(candles))
+	}
+}
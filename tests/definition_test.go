@@ -0,0 +1,377 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/app"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/trend"
+)
+
+const emaCrossDefinition = `{
+  "name": "EMA cross",
+  "timeframes": ["1 hour"],
+  "indicators": {
+    "fast": {"type": "ema", "params": {"period": 2}},
+    "slow": {"type": "sma", "params": {"period": 4}}
+  },
+  "filters": [{"left": "close", "op": ">", "right": 0}],
+  "entry": {"left": "fast", "op": "crosses_above", "right": "slow"},
+  "exit": {"any": [
+    {"left": "fast", "op": "crosses_below", "right": "slow"},
+    {"not": {"left": "close", "op": "<", "right": 1000}}
+  ]}
+}`
+
+func TestDefinitionCompilesIntoStrategy(t *testing.T) {
+	def, err := strategy.ParseDefinition([]byte(emaCrossDefinition))
+	if err != nil {
+		t.Fatalf("ParseDefinition failed with an unexpected error: %v", err)
+	}
+	if tfs := def.ParsedTimeframes(); len(tfs) != 1 || tfs[0] != domain.Hour1 {
+		t.Errorf("unexpected timeframes: %v", tfs)
+	}
+	factory, err := def.Factory()
+	if err != nil {
+		t.Fatalf("Factory failed with an unexpected error: %v", err)
+	}
+
+	s := factory()
+	if s.WarmUp() != 5 {
+		t.Errorf("expected a warm-up of 5 candles, got %d", s.WarmUp())
+	}
+	candles := makeCandles(10, 10, 10, 10, 9, 8, 12, 14, 15, 11, 8, 7, 12, 13)
+	in := strategy.Input{Symbol: "BTCUSDT", Timeframe: domain.Hour1}
+	var directions []domain.Direction
+	for i := s.WarmUp(); i <= len(candles); i++ {
+		in.Candles = candles[:i]
+		for _, sig := range s.OnCandle(in) {
+			directions = append(directions, sig.Direction)
+		}
+	}
+	want := []domain.Direction{domain.Buy, domain.Sell, domain.Buy}
+	if len(directions) != len(want) {
+		t.Fatalf("expected signals %v, got %v", want, directions)
+	}
+	for i := range want {
+		if directions[i] != want[i] {
+			t.Fatalf("expected signals %v, got %v", want, directions)
+		}
+	}
+}
+
+func TestDefinitionValidationReportsAllErrors(t *testing.T) {
+	_, err := strategy.ParseDefinition([]byte(`{
+	  "timeframes": ["2h"],
+	  "indicators": {"bb": {"type": "bolinger"}, "stoch": {"type": "stochastic", "params": {"lenght": 14}}, "fast": {"type": "sma", "params": {"period": 14.5}}},
+	  "entry": {"all": [
+	    {"left": "close", "op": "<", "right": "bb"},
+	    {"left": "rsi", "op": "above", "right": 30}
+	  ]}
+	}`))
+	var verrs strategy.ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		"name: is required",
+		`timeframes[0]: unsupported timeframe "2h"`,
+		`indicators.bb: unknown type "bolinger"`,
+		`indicators.stoch: unknown parameter "lenght"`,
+		`indicators.fast: parameter "period" must be a whole number, got 14.5`,
+		`entry.all[0].right: unknown series "bb"`,
+		`entry.all[1].op: unknown operator "above"`,
+		`entry.all[1].left: unknown series "rsi"`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected the error to contain %q, got:\n%s", want, msg)
+		}
+	}
+}
+
+func TestWarmUpPeriodCoversTheLongestIndicator(t *testing.T) {
+	def, err := strategy.LoadDefinition("../strategies/rsi-trend.json")
+	if err != nil {
+		t.Fatalf("LoadDefinition failed: %v", err)
+	}
+	factory, err := def.Factory()
+	if err != nil {
+		t.Fatalf("Factory failed: %v", err)
+	}
+	// The ema(200) of the 1h entry needs more than the week of history the scanner loads by default.
+	if got := strategy.WarmUpPeriod(factory(), domain.Hour1); got < 201*time.Hour {
+		t.Errorf("expected a warm-up of at least 201h, got %s", got)
+	}
+}
+
+func TestDefinitionOnBarTimeframes(t *testing.T) {
+	def, err := strategy.ParseDefinition([]byte(`{
+	  "name": "Bricks",
+	  "timeframes": ["HA:1h", "renko:1m:atr14"],
+	  "entry": {"left": "close", "op": ">", "right": "open"},
+	  "confirm": [{"timeframe": "4h", "trend": "bullish"}]
+	}`))
+	if err != nil {
+		t.Fatalf("ParseDefinition failed with an unexpected error: %v", err)
+	}
+	if tfs := def.ParsedTimeframes(); len(tfs) != 2 || tfs[0] != "ha:1h" || tfs[1] != "renko:1m:atr14" {
+		t.Errorf("unexpected timeframes: %v", tfs)
+	}
+
+	_, err = strategy.ParseDefinition([]byte(`{
+	  "name": "Bricks",
+	  "timeframes": ["renko:1m:0"],
+	  "entry": {"left": "close", "op": ">", "right": "open"}
+	}`))
+	if err == nil || !strings.Contains(err.Error(), `timeframes[0]: unsupported timeframe "renko:1m:0"`) {
+		t.Errorf("expected the box size to be rejected, got %v", err)
+	}
+}
+
+func TestExampleDefinitionsAreValid(t *testing.T) {
+	defs, err := strategy.LoadDefinitions("../strategies")
+	if err != nil {
+		t.Fatalf("LoadDefinitions failed with an unexpected error: %v", err)
+	}
+	if len(defs) == 0 {
+		t.Errorf("expected at least one example strategy definition")
+	}
+}
+
+func TestHigherTimeframeConfirmationDoesNotLookAhead(t *testing.T) {
+	def, err := strategy.ParseDefinition([]byte(`{
+	  "name": "1m entry confirmed on 1h",
+	  "timeframes": ["1m"],
+	  "entry": "close > 0",
+	  "confirm": [{"timeframe": "1h", "condition": "close > 100"}]
+	}`))
+	if err != nil {
+		t.Fatalf("ParseDefinition failed with an unexpected error: %v", err)
+	}
+	factory, err := def.Factory()
+	if err != nil {
+		t.Fatalf("Factory failed with an unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	runner := app.NewStrategyRunner("BINANCE", nil)
+	collector := &signalCollector{}
+	runner.AddSignalHandler(collector)
+	runner.Add("BTCUSDT", domain.Minute1, factory)
+	if tfs := runner.Timeframes("BTCUSDT"); len(tfs) != 2 || tfs[1] != domain.Hour1 {
+		t.Fatalf("expected the runner to keep 1m and 1h candles, got %v", tfs)
+	}
+
+	start := time.Date(2025, 10, 2, 10, 0, 0, 0, time.UTC)
+	minute := func(close time.Time) domain.Candle {
+		return domain.Candle{Symbol: "BTCUSDT", Timeframe: domain.Minute1, OpenTime: close.Add(-time.Minute), CloseTime: close, Close: 10}
+	}
+	hour := domain.Candle{Symbol: "BTCUSDT", Timeframe: domain.Hour1, OpenTime: start, CloseTime: start.Add(time.Hour), Close: 150}
+
+	// The 1h candle is known, but it closes after the 1m candle, so it must not confirm it.
+	if err := runner.OnCandle(ctx, hour); err != nil {
+		t.Fatalf("OnCandle failed: %v", err)
+	}
+	if err := runner.OnCandle(ctx, minute(start.Add(2*time.Minute))); err != nil {
+		t.Fatalf("OnCandle failed: %v", err)
+	}
+	if len(collector.signals) != 0 {
+		t.Fatalf("expected no signal before the 1h candle closed, got %d", len(collector.signals))
+	}
+
+	// The 1m candle closing together with the 1h candle sees it.
+	if err := runner.OnCandle(ctx, minute(start.Add(time.Hour))); err != nil {
+		t.Fatalf("OnCandle failed: %v", err)
+	}
+	if len(collector.signals) != 1 {
+		t.Fatalf("expected one confirmed signal, got %d", len(collector.signals))
+	}
+
+	_, err = strategy.ParseDefinition([]byte(`{
+	  "name": "x", "timeframes": ["1h"], "entry": "close > 0",
+	  "confirm": [{"timeframe": "15m", "trend": "bullish"}, {"timeframe": "4h", "trend": "up"}]
+	}`))
+	for _, want := range []string{
+		"confirm[0].timeframe: must be higher than the strategy timeframe 1h",
+		`confirm[1].trend: must be bullish, bearish or neutral, got "up"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to contain %q, got %v", want, err)
+		}
+	}
+}
+
+func TestTrendConfirmationSeesTheChartTrendService(t *testing.T) {
+	def, err := strategy.ParseDefinition([]byte(`{
+	  "name": "1m entry in a 1h uptrend",
+	  "timeframes": ["1m"],
+	  "entry": "close > 0",
+	  "confirm": [{"timeframe": "1h", "trend": "bullish"}]
+	}`))
+	if err != nil {
+		t.Fatalf("ParseDefinition failed with an unexpected error: %v", err)
+	}
+	factory, err := def.Factory()
+	if err != nil {
+		t.Fatalf("Factory failed with an unexpected error: %v", err)
+	}
+
+	// The service is resumed from a rising 1h history the runner never saw.
+	ctx := context.Background()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	chart := trend.NewChartTrendService(trend.DefaultChartConfig(), nil)
+	for i := 0; i < 60; i++ {
+		open := start.Add(time.Duration(i) * time.Hour)
+		c := domain.Candle{Symbol: "BTCUSDT", Timeframe: domain.Hour1, OpenTime: open, CloseTime: open.Add(time.Hour), Close: 100 + float64(i)}
+		if err := chart.OnCandle(ctx, c); err != nil {
+			t.Fatalf("OnCandle failed: %v", err)
+		}
+	}
+	if got, _ := chart.Trend("BTCUSDT", domain.Hour1); got.Trend != domain.Bullish {
+		t.Fatalf("expected a bullish 1h chart trend, got %+v", got)
+	}
+	last := start.Add(60 * time.Hour)
+	minute := func(close time.Time) domain.Candle {
+		return domain.Candle{Symbol: "BTCUSDT", Timeframe: domain.Minute1, OpenTime: close.Add(-time.Minute), CloseTime: close, Close: 10}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		source trend.Source
+		close  time.Time
+		want   int
+	}{
+		{"confirmed", trend.Source{Chart: chart}, last, 1},
+		{"trend of a later candle", trend.Source{Chart: chart}, last.Add(-30 * time.Minute), 0},
+		{"chart trend not tracked", trend.Source{}, last, 0},
+	} {
+		runner := app.NewStrategyRunner("BINANCE", nil)
+		collector := &signalCollector{}
+		runner.AddSignalHandler(collector)
+		runner.SetTrends(tc.source)
+		runner.Add("BTCUSDT", domain.Minute1, factory)
+		if err := runner.OnCandle(ctx, minute(tc.close)); err != nil {
+			t.Fatalf("OnCandle failed: %v", err)
+		}
+		if len(collector.signals) != tc.want {
+			t.Errorf("%s: expected %d signals, got %d", tc.name, tc.want, len(collector.signals))
+		}
+	}
+}
+
+func TestBacktestFiltersOnStoredTickerStatistics(t *testing.T) {
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		symbol      string
+		filter      string
+		quoteVolume float64
+		want        bool
+	}{
+		{"BTCUSDT", "volume_24h > 1000000", 2000000, true},
+		{"ETHUSDT", "volume_24h > 1000000", 500000, false},
+		// Only the statistics of the last ticker are known, not those of earlier candles.
+		{"BTCUSDT", "volume_24h[1] > 1000000", 2000000, false},
+	} {
+		def, err := strategy.ParseDefinition([]byte(`{
+		  "name": "Liquid pairs only",
+		  "timeframes": ["1m"],
+		  "filters": ["` + tc.filter + `"],
+		  "entry": "close > 0"
+		}`))
+		if err != nil {
+			t.Fatalf("ParseDefinition failed with an unexpected error: %v", err)
+		}
+		factory, err := def.Factory()
+		if err != nil {
+			t.Fatalf("Factory failed with an unexpected error: %v", err)
+		}
+		repo, cleanup := setupTestDB(t)
+		for i := 0; i < 10; i++ {
+			ticker := marketTicker(tc.symbol, start.Add(time.Duration(i)*30*time.Second), 100+float64(i), tc.quoteVolume)
+			ticker.Volume = domain.BigString{Float: bigFloat(float64(i))}
+			if err := repo.SaveTicker(context.Background(), ticker); err != nil {
+				t.Fatalf("SaveTicker failed: %v", err)
+			}
+		}
+		cfg := backtest.DefaultConfig()
+		cfg.Symbol, cfg.Timeframe, cfg.From, cfg.To, cfg.WarmUp = tc.symbol, domain.Minute1, start, start.Add(5*time.Minute), 0
+		engine := backtest.NewEngine(cfg, repo)
+		engine.Add(domain.Minute1, factory)
+		res, err := engine.Run(context.Background())
+		cleanup()
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if got := len(res.Signals) > 0; got != tc.want {
+			t.Errorf("%s with a 24h volume of %v filtered on %s: expected signals %v, got %d", tc.symbol, tc.quoteVolume, tc.filter, tc.want, len(res.Signals))
+		}
+	}
+}
+
+func TestMarketTrendConfirmation(t *testing.T) {
+	def, err := strategy.ParseDefinition([]byte(`{
+	  "name": "1m entry in a bullish market",
+	  "timeframes": ["1m"],
+	  "entry": "close > 0",
+	  "confirm": [{"timeframe": "1h", "market": "bullish"}]
+	}`))
+	if err != nil {
+		t.Fatalf("ParseDefinition failed with an unexpected error: %v", err)
+	}
+	factory, err := def.Factory()
+	if err != nil {
+		t.Fatalf("Factory failed with an unexpected error: %v", err)
+	}
+	// Stored market trends drive the confirmation in a backtest, as the live service does in the scanner.
+	run := func(market domain.Trend) int {
+		repo, cleanup := setupTestDB(t)
+		defer cleanup()
+		start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+		storeRamp(t, repo, "BTCUSDT", start, 12)
+		if market != "" {
+			m := domain.MarketTrend{Exchange: "BINANCE", Timeframe: domain.Hour1, Time: start, Trend: market}
+			if err := repo.SaveMarketTrend(context.Background(), m); err != nil {
+				t.Fatalf("SaveMarketTrend failed: %v", err)
+			}
+		}
+		cfg := backtest.DefaultConfig()
+		cfg.Symbol, cfg.Timeframe, cfg.From, cfg.To, cfg.WarmUp = "BTCUSDT", domain.Minute1, start, start.Add(time.Hour), 0
+		engine := backtest.NewEngine(cfg, repo)
+		engine.Add(domain.Minute1, factory)
+		res, err := engine.Run(context.Background())
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		return len(res.Signals)
+	}
+	if n := run(domain.Bullish); n != 1 {
+		t.Errorf("expected the entry in a bullish market, got %d signals", n)
+	}
+	if n := run(domain.Bearish); n != 0 {
+		t.Errorf("expected no entry in a bearish market, got %d signals", n)
+	}
+	if n := run(""); n != 0 {
+		t.Errorf("expected no entry without a market trend, got %d signals", n)
+	}
+
+	_, err = strategy.ParseDefinition([]byte(`{
+	  "name": "x", "timeframes": ["1h"], "entry": "close > 0",
+	  "confirm": [{"timeframe": "30m", "market": "bullish"}, {"timeframe": "4h", "trend": "bullish", "market": "bullish"}]
+	}`))
+	for _, want := range []string{
+		"confirm[0].timeframe: the market trend is not tracked on 30m",
+		"confirm[1]: needs exactly one of a trend, a market trend or a condition",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to contain %q, got %v", want, err)
+		}
+	}
+}
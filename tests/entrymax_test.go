@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/exchange"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/opportunity"
+)
+
+func TestOrderBookFill(t *testing.T) {
+	book := domain.OrderBook{
+		Bids: []domain.PriceLevel{{Price: 99, Quantity: 1}, {Price: 98, Quantity: 5}},
+		Asks: []domain.PriceLevel{{Price: 100, Quantity: 1}, {Price: 100.4, Quantity: 2}, {Price: 101, Quantity: 10}},
+	}
+	for _, tc := range []struct {
+		d                  domain.Direction
+		max, slippage      float64
+		wantBase, wantQuot float64
+	}{
+		{domain.Buy, 0, 0.5, 3, 100 + 200.8},       // The level at 101 is beyond 0.5%.
+		{domain.Buy, 2, 0.5, 2, 100 + 100.4},       // Stops at the maximum quantity.
+		{domain.Buy, 0, 1, 13, 100 + 200.8 + 1010}, // Every level is within 1%.
+		{domain.Sell, 0, 0.5, 1, 99},               // 98 is more than 0.5% below 99.
+		{domain.Sell, 3, 2, 3, 99 + 196},           // Bids are taken from the top down.
+	} {
+		base, quote := book.Fill(tc.d, tc.max, tc.slippage)
+		if !almostEqual(base, tc.wantBase) || !almostEqual(quote, tc.wantQuot) {
+			t.Errorf("%s max %v within %v%%: expected %v / %v, got %v / %v", tc.d, tc.max, tc.slippage, tc.wantBase, tc.wantQuot, base, quote)
+		}
+	}
+}
+
+func TestSymbolInfoQuantity(t *testing.T) {
+	info := domain.SymbolInfo{StepSize: 0.1, MinQuantity: 0.2, MaxQuantity: 50, MinNotional: 5}
+	for _, tc := range []struct{ quantity, price, want float64 }{
+		{2.37, 10, 2.3}, // Rounded down to the step size.
+		{0.3, 20, 0.3},  // A whole number of steps is kept.
+		{80, 10, 50},    // Capped at the maximum quantity.
+		{0.15, 100, 0},  // Below the minimum quantity.
+		{0.4, 10, 0},    // Below the minimum notional.
+	} {
+		if got := info.Quantity(tc.quantity, tc.price); !almostEqual(got, tc.want) {
+			t.Errorf("Quantity(%v, %v): expected %v, got %v", tc.quantity, tc.price, tc.want, got)
+		}
+	}
+}
+
+func TestSizerEntryMax(t *testing.T) {
+	sizer := opportunity.NewSizer(opportunity.DefaultSizerConfig(), opportunity.FixedBalances{"USDT": 10000, "AVNT": 4})
+	sizer.SetSymbols(map[string]domain.SymbolInfo{
+		"AVNTUSDT": {Symbol: "AVNTUSDT", BaseAsset: "AVNT", QuoteAsset: "USDT", StepSize: 0.1, MinNotional: 5},
+	})
+	buy := domain.Signal{Symbol: "AVNTUSDT", Direction: domain.Buy, Price: 1}
+
+	// 1% of 10000 at the default 2% stop is a position of 5000 USDT.
+	if got := sizer.EntryMax(buy); !almostEqual(got.Base, 5000) || !almostEqual(got.Quote, 5000) {
+		t.Errorf("expected an entry max of 5000 AVNT / 5000 USDT without a book, got %+v", got)
+	}
+	// A tighter stop of the trade plan is capped at the free balance.
+	tight := buy
+	tight.Plan = &domain.TradePlan{Entry: 1, StopLoss: 0.999}
+	if got := sizer.EntryMax(tight); !almostEqual(got.Base, 10000) {
+		t.Errorf("expected an entry max of the whole balance, got %+v", got)
+	}
+
+	// The book only offers 3000 AVNT within 0.5%.
+	sizer.OnOrderBook(domain.OrderBook{Symbol: "AVNTUSDT", Asks: []domain.PriceLevel{
+		{Price: 1, Quantity: 1000}, {Price: 1.004, Quantity: 2000}, {Price: 1.01, Quantity: 100000},
+	}})
+	if got := sizer.EntryMax(buy); !almostEqual(got.Base, 3000) || !almostEqual(got.Quote, 1000+2008) {
+		t.Errorf("expected an entry max of 3000 AVNT / 3008 USDT, got %+v", got)
+	}
+
+	// A sell can only sell the 4 AVNT held, which is below the minimum notional.
+	sell := domain.Signal{Symbol: "AVNTUSDT", Direction: domain.Sell, Price: 1}
+	if got := sizer.EntryMax(sell); got != (domain.EntryMax{}) {
+		t.Errorf("expected no entry below the minimum notional, got %+v", got)
+	}
+	unknown := domain.Signal{Symbol: "XYZUSDT", Direction: domain.Buy, Price: 1}
+	if got := sizer.EntryMax(unknown); got != (domain.EntryMax{}) {
+		t.Errorf("expected no entry max for an unknown pair, got %+v", got)
+	}
+}
+
+func TestFetchBinanceSymbols(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/exchangeInfo" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"symbols": [
+		  {"symbol": "AVNTUSDT", "status": "TRADING", "baseAsset": "AVNT", "quoteAsset": "USDT", "filters": [
+		    {"filterType": "PRICE_FILTER", "tickSize": "0.00010000"},
+		    {"filterType": "LOT_SIZE", "minQty": "0.10000000", "maxQty": "9000000.00000000", "stepSize": "0.10000000"},
+		    {"filterType": "NOTIONAL", "minNotional": "5.00000000"}]},
+		  {"symbol": "OLDUSDT", "status": "BREAK", "baseAsset": "OLD", "quoteAsset": "USDT", "filters": []}
+		]}`))
+	}))
+	defer server.Close()
+
+	symbols, err := exchange.FetchBinanceSymbols(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("FetchBinanceSymbols failed: %v", err)
+	}
+	want := domain.SymbolInfo{Symbol: "AVNTUSDT", BaseAsset: "AVNT", QuoteAsset: "USDT",
+		StepSize: 0.1, MinQuantity: 0.1, MaxQuantity: 9000000, MinNotional: 5}
+	if len(symbols) != 1 || symbols["AVNTUSDT"] != want {
+		t.Errorf("expected only %+v, got %+v", want, symbols)
+	}
+}
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy/expr"
+)
+
+// closeResolver resolves "close" from the candles of the context.
+func closeResolver(name string) (expr.Series, int, error) {
+	if name != "close" {
+		return nil, 0, fmt.Errorf("unknown series %q", name)
+	}
+	return func(c *expr.Context, back int) float64 {
+		i := len(c.Candles) - 1 - back
+		if i < 0 {
+			return math.NaN()
+		}
+		return c.Candles[i].Close
+	}, 0, nil
+}
+
+func TestExprEvaluatesSeriesAndIndicators(t *testing.T) {
+	ctx := expr.NewContext(makeCandles(1, 2, 3, 4, 5), nil)
+	for src, want := range map[string]float64{
+		"close":                      5,
+		"close[1]":                   4,
+		"close - close[4]":           4,
+		"sma(3)":                     4,
+		"sma(close[1], 2)":           3.5,
+		"highest(close, 3)[1]":       4,
+		"-(1 + 2) * 3":               -9,
+		"max(close, 10) / 2":         5,
+		"close[5]":                   math.NaN(),
+		"close / (close - close[0])": math.NaN(),
+	} {
+		p, err := expr.CompileNumber(src, closeResolver)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", src, err)
+			continue
+		}
+		got := p.Number(ctx)
+		if math.IsNaN(want) != math.IsNaN(got) || (!math.IsNaN(want) && !almostEqual(got, want)) {
+			t.Errorf("%s: expected %v, got %v", src, want, got)
+		}
+	}
+
+	for src, want := range map[string]bool{
+		"close > close[1] and not (close < 3)":       true,
+		"crosses_above(close, 4.5)":                  true,
+		"crosses_above(close, 4.5)[1]":               false,
+		"close[9] < 1 or close >= 5":                 true,
+		"rsi(2) < 30":                                false,
+		"crosses_below(close, 10) or false":          false,
+		"close == 5 and close != 4 and close <= 5.0": true,
+	} {
+		p, err := expr.CompileCondition(src, closeResolver)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", src, err)
+			continue
+		}
+		if got := p.Bool(ctx); got != want {
+			t.Errorf("%s: expected %v, got %v", src, want, got)
+		}
+	}
+}
+
+func TestExprFoldsConstantsAndTracksWarmUp(t *testing.T) {
+	for src, constant := range map[string]bool{
+		"(1 + 2) * 3 > 8":     true,
+		"false and close > 1": true,
+		"abs(-2) < min(3, 4)": true,
+		"true and close > 1":  false,
+	} {
+		p, err := expr.Compile(src, closeResolver)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", src, err)
+		}
+		if p.Constant() != constant {
+			t.Errorf("%s: expected constant %v, got %v", src, constant, p.Constant())
+		}
+	}
+
+	for src, want := range map[string]int{
+		"close":                            0,
+		"close[3] > 1":                     3,
+		"rsi(14) < 30 and close > ema(50)": 50,
+		"crosses_above(sma(5), sma(10))":   11,
+		"sma(close[2], 4)":                 5,
+	} {
+		p, err := expr.Compile(src, closeResolver)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", src, err)
+		}
+		if p.WarmUp() != want {
+			t.Errorf("%s: expected a warm-up of %d, got %d", src, want, p.WarmUp())
+		}
+	}
+}
+
+func TestExprReportsTypedErrors(t *testing.T) {
+	var syntax *expr.SyntaxError
+	var typ *expr.TypeError
+	var name *expr.NameError
+	for _, tc := range []struct {
+		src    string
+		target any
+		msg    string
+	}{
+		{"close >", &syntax, "position 8"},
+		{"close[1.5]", &syntax, "whole number"},
+		{"(close", &syntax, `expected ")"`},
+		{"close $ 1", &syntax, "unexpected character"},
+		{"close and true", &typ, `"and" needs a condition, got a number`},
+		{"sma(close)", &typ, "period of sma must be a constant"},
+		{"ema(close, 0)", &typ, "must be positive"},
+		{"atr(1, 2)", &typ, "usage: atr(period)"},
+		{"close", &typ, "expression is a number, expected a condition"},
+		{"volume > 1", &name, `unknown series "volume" at position 1`},
+		{"foo(1) > 1", &name, `unknown function "foo"`},
+		{"bb(20, 2) > 1", &name, "bb needs one of the outputs"},
+	} {
+		_, err := expr.CompileCondition(tc.src, closeResolver)
+		if err == nil {
+			t.Errorf("%s: expected an error", tc.src)
+			continue
+		}
+		if !errors.As(err, tc.target) {
+			t.Errorf("%s: expected a %T, got %T: %v", tc.src, tc.target, err, err)
+		}
+		if !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("%s: expected the error to contain %q, got %q", tc.src, tc.msg, err)
+		}
+	}
+}
+
+func TestDefinitionWithExpressions(t *testing.T) {
+	def, err := strategy.ParseDefinition([]byte(`{
+	  "name": "EMA cross expression",
+	  "timeframes": ["1h"],
+	  "indicators": {"fast": {"type": "ema", "params": {"period": 2}}},
+	  "filters": [{"left": "close - close[1]", "op": "!=", "right": 0}],
+	  "entry": "crosses_above(fast, sma(4)) and close > 0",
+	  "exit": {"any": ["crosses_below(fast, sma(4))", {"not": "close < 1000"}]}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseDefinition failed with an unexpected error: %v", err)
+	}
+	factory, err := def.Factory()
+	if err != nil {
+		t.Fatalf("Factory failed with an unexpected error: %v", err)
+	}
+	s := factory()
+	if s.WarmUp() != 6 {
+		t.Errorf("expected a warm-up of 6 candles, got %d", s.WarmUp())
+	}
+
+	candles := makeCandles(10, 10, 10, 10, 9, 8, 12, 14, 15, 11, 8, 7, 12, 13)
+	in := strategy.Input{Symbol: "BTCUSDT"}
+	var signals int
+	for i := s.WarmUp(); i <= len(candles); i++ {
+		in.Candles = candles[:i]
+		signals += len(s.OnCandle(in))
+	}
+	if signals != 3 {
+		t.Errorf("expected 3 signals, got %d", signals)
+	}
+
+	_, err = strategy.ParseDefinition([]byte(`{"name": "x", "timeframes": ["1h"], "entry": "close > ema(close)"}`))
+	if err == nil || !strings.Contains(err.Error(), "entry: type error at position 9") {
+		t.Errorf("expected a type error for the entry expression, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/indicator"
+)
+
+// makeCandles builds one-minute candles from closing prices, with a high/low one unit around the close.
+func makeCandles(closes ...float64) []domain.Candle {
+	start := time.Date(2025, 10, 2, 20, 0, 0, 0, time.UTC)
+	candles := make([]domain.Candle, len(closes))
+	for i, c := range closes {
+		open := start.Add(time.Duration(i) * time.Minute)
+		candles[i] = domain.Candle{
+			Symbol:    "BTCUSDT",
+			Timeframe: domain.Minute1,
+			OpenTime:  open,
+			CloseTime: open.Add(time.Minute),
+			Open:      c,
+			High:      c + 1,
+			Low:       c - 1,
+			Close:     c,
+			Volume:    1,
+		}
+	}
+	return candles
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestSMA(t *testing.T) {
+	got := indicator.SMA([]float64{1, 2, 3, 4, 5}, 3)
+	if !math.IsNaN(got[1]) {
+		t.Errorf("expected NaN during warm-up, got %v", got[1])
+	}
+	if !almostEqual(got[2], 2) || !almostEqual(got[4], 4) {
+		t.Errorf("unexpected SMA values: %v", got)
+	}
+}
+
+func TestBollingerWidth(t *testing.T) {
+	bb := indicator.Bollinger([]float64{10, 10, 10, 10}, 4, 2)
+	if !almostEqual(indicator.Last(bb.Width), 0) {
+		t.Errorf("expected zero width for a flat series, got %v", indicator.Last(bb.Width))
+	}
+
+	// Mean 100, population standard deviation 1 -> width = 4 / 100 = 4%.
+	bb = indicator.Bollinger([]float64{99, 101, 99, 101}, 4, 2)
+	if !almostEqual(indicator.Last(bb.Width), 4) {
+		t.Errorf("expected a width of 4%%, got %v", indicator.Last(bb.Width))
+	}
+}
+
+func TestStochastic(t *testing.T) {
+	// Falling closes end at the lowest low plus one, rising closes at the highest high minus one.
+	falling := makeCandles(20, 19, 18, 17, 16)
+	k, _ := indicator.Stochastic(falling, 5, 1, 3)
+	// Range is 15..21, close 16 -> (16-15)/(21-15) = 16.67%.
+	if !almostEqual(indicator.Last(k), 100.0/6) {
+		t.Errorf("unexpected %%K for falling prices: %v", indicator.Last(k))
+	}
+
+	k, d := indicator.Stochastic(makeCandles(1, 2, 3, 4, 5, 6, 7), 5, 1, 3)
+	if !almostEqual(indicator.Last(k), 500.0/6) || !almostEqual(indicator.Last(d), 500.0/6) {
+		t.Errorf("unexpected %%K/%%D for rising prices: %v / %v", indicator.Last(k), indicator.Last(d))
+	}
+}
+
+func TestCaptureMetrics(t *testing.T) {
+	candles := makeCandles(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20)
+	ticker := &domain.Ticker{
+		QuoteVolume:        domain.BigString{Float: bigFloat(3814656.28)},
+		PriceChangePercent: domain.BigString{Float: bigFloat(-2.43)},
+	}
+
+	m := indicator.CaptureMetrics(candles, ticker, indicator.DefaultMetricsConfig())
+	if !m.CandleTime.Equal(candles[len(candles)-1].CloseTime) {
+		t.Errorf("unexpected candle time %v", m.CandleTime)
+	}
+	if math.IsNaN(m.BBWidth) || math.IsNaN(m.StochK) || math.IsNaN(m.StochD) {
+		t.Errorf("expected all indicator metrics to be warmed up: %+v", m)
+	}
+	if m.Volume24h != 3814656.28 || m.Change24h != -2.43 {
+		t.Errorf("unexpected 24h metrics: %+v", m)
+	}
+}
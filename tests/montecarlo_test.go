@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/report"
+)
+
+// monteCarloRun is a backtest of 100 that wins 10% twice and then loses 10% twice, each on the
+// equity of the moment: 100 → 110 → 121 → 108.9 → 98.01.
+func monteCarloRun() *backtest.Result {
+	res := &backtest.Result{InitialBalance: 100}
+	equity := 100.0
+	for _, r := range []float64{0.1, 0.1, -0.1, -0.1} {
+		pnl := equity * r
+		equity += pnl
+		res.Trades = append(res.Trades, backtest.Trade{PnL: pnl})
+	}
+	res.FinalEquity = equity
+	return res
+}
+
+func TestMonteCarloShuffle(t *testing.T) {
+	cfg := backtest.DefaultMonteCarloConfig()
+	cfg.Runs = 500
+	mc, err := backtest.MonteCarlo(monteCarloRun(), cfg)
+	if err != nil {
+		t.Fatalf("MonteCarlo failed: %v", err)
+	}
+	// Compounding does not depend on the order, so every run ends at -1.99.
+	if !almostEqual(mc.NetProfit.Min, -1.99) || !almostEqual(mc.NetProfit.Max, -1.99) || mc.LossPercent != 100 || mc.NetProfitRank != 0 {
+		t.Errorf("expected every shuffled run to lose 1.99, got %+v", mc)
+	}
+	// The drawdown ranges from 10%, with both wins between the losses, to 19% with the losses in a
+	// row as in the backtest; no run can do worse.
+	if !almostEqual(mc.MaxDrawdownPercent.Min, 10) || !almostEqual(mc.MaxDrawdownPercent.Max, 19) || mc.DrawdownRank != 0 {
+		t.Errorf("expected drawdowns from 10%% to 19%%, got %+v", mc.MaxDrawdownPercent)
+	}
+	d := mc.MaxDrawdownPercent
+	if !(d.Min <= d.P5 && d.P5 <= d.P25 && d.P25 <= d.P50 && d.P50 <= d.P75 && d.P75 <= d.P95 && d.P95 <= d.Max) {
+		t.Errorf("expected ordered percentiles, got %+v", d)
+	}
+
+	again, _ := backtest.MonteCarlo(monteCarloRun(), cfg)
+	if again != mc {
+		t.Error("expected the same seed to give the same simulation")
+	}
+}
+
+func TestMonteCarloBootstrapAndSkip(t *testing.T) {
+	cfg := backtest.DefaultMonteCarloConfig()
+	cfg.Method, cfg.Runs = backtest.MonteCarloBootstrap, 2000
+	mc, err := backtest.MonteCarlo(monteCarloRun(), cfg)
+	if err != nil {
+		t.Fatalf("MonteCarlo failed: %v", err)
+	}
+	// Four wins make 1.1^4 and four losses 0.9^4 of the balance.
+	if !almostEqual(mc.NetProfit.Max, 46.41) || !almostEqual(mc.NetProfit.Min, -34.39) {
+		t.Errorf("expected bootstrapped runs from -34.39 to 46.41, got %+v", mc.NetProfit)
+	}
+	if mc.NetProfitRank <= 0 || mc.NetProfitRank >= 100 || mc.LossPercent <= 0 || mc.LossPercent >= 100 {
+		t.Errorf("expected runs on both sides of the backtest, got %+v", mc)
+	}
+
+	cfg.Method, cfg.SkipPercent = backtest.MonteCarloSkip, 100
+	mc, err = backtest.MonteCarlo(monteCarloRun(), cfg)
+	if err != nil {
+		t.Fatalf("MonteCarlo failed: %v", err)
+	}
+	if mc.NetProfit.Max != 0 || mc.NetProfit.Min != 0 {
+		t.Errorf("expected skipping every trade to make nothing, got %+v", mc.NetProfit)
+	}
+
+	if _, err := backtest.MonteCarlo(&backtest.Result{InitialBalance: 100}, cfg); err == nil {
+		t.Error("expected a backtest without trades to be rejected")
+	}
+	cfg.Method = "reverse"
+	if _, err := backtest.MonteCarlo(monteCarloRun(), cfg); err == nil {
+		t.Error("expected an unknown method to be rejected")
+	}
+}
+
+func TestMonteCarloReport(t *testing.T) {
+	res := monteCarloRun()
+	mc, err := backtest.MonteCarlo(res, backtest.DefaultMonteCarloConfig())
+	if err != nil {
+		t.Fatalf("MonteCarlo failed: %v", err)
+	}
+	rep := report.NewBacktestReport(res)
+	rep.MonteCarlo = append(rep.MonteCarlo, mc)
+
+	var table bytes.Buffer
+	if err := rep.WriteTable(&table); err != nil {
+		t.Fatalf("WriteTable failed: %v", err)
+	}
+	if !strings.Contains(table.String(), "Monte Carlo, 1000 shuffle runs") || !strings.Contains(table.String(), "95%") {
+		t.Errorf("expected a Monte Carlo section, got:\n%s", table.String())
+	}
+	var doc struct {
+		MonteCarlo []struct {
+			Method    string
+			NetProfit struct{ P50 float64 } `json:"net_profit"`
+		} `json:"monte_carlo"`
+	}
+	var buf bytes.Buffer
+	if err := rep.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(doc.MonteCarlo) != 1 || doc.MonteCarlo[0].Method != "shuffle" || !almostEqual(doc.MonteCarlo[0].NetProfit.P50, -1.99) {
+		t.Errorf("unexpected Monte Carlo JSON %+v", doc.MonteCarlo)
+	}
+}
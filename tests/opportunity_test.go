@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/opportunity"
+)
+
+type fixedTrends struct{}
+
+func (fixedTrends) Trend(symbol string, tf domain.Timeframe) (domain.ChartTrend, bool) {
+	return domain.ChartTrend{Symbol: symbol, Timeframe: tf, Trend: domain.Bullish}, true
+}
+
+func (fixedTrends) Latest(tf domain.Timeframe) (domain.MarketTrend, bool) {
+	if tf != domain.Hour1 {
+		return domain.MarketTrend{}, false
+	}
+	return domain.MarketTrend{Exchange: "MEXC", Timeframe: tf, Breadth: -11.3, Trend: domain.Bearish}, true
+}
+
+func TestOpportunityLifecycle(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	candleTime := time.Date(2025, 10, 2, 20, 58, 0, 0, time.UTC)
+	cfg := opportunity.Config{ExpireAfter: 2, MarketTimeframe: domain.Hour1, Clock: func() time.Time { return candleTime.Add(time.Minute) }}
+	svc := opportunity.NewService(cfg, repo, fixedTrends{}, fixedTrends{})
+
+	sig := domain.Signal{Strategy: "Stoch & Bollinger Bands", Exchange: "MEXC", Symbol: "AVNTUSDT", Timeframe: domain.Minute1,
+		Direction: domain.Buy, Time: candleTime, Price: 1.165, Metrics: domain.SignalMetrics{CandleTime: candleTime}}
+	for i := 0; i < 2; i++ {
+		id, err := repo.SaveSignal(ctx, sig)
+		if err != nil {
+			t.Fatalf("SaveSignal failed: %v", err)
+		}
+		sig.ID = id
+		if err := svc.OnSignal(ctx, sig); err != nil {
+			t.Fatalf("OnSignal failed: %v", err)
+		}
+	}
+
+	open := svc.Open()
+	if len(open) != 2 {
+		t.Fatalf("expected 2 open opportunities, got %d", len(open))
+	}
+	first := open[0]
+	if first.Status != domain.OpportunityNew || first.ChartTrend != domain.Bullish || first.MarketTrend != domain.Bearish ||
+		first.MarketExchange != "MEXC" || first.MarketBreadth != -11.3 || first.Signal.Price != 1.165 || first.WGHM != domain.WGHMNone {
+		t.Errorf("unexpected opportunity: %+v", first)
+	}
+
+	if err := svc.MarkSeen(ctx, first.ID); err != nil {
+		t.Fatalf("MarkSeen failed: %v", err)
+	}
+	if err := svc.MarkActedOn(ctx, open[1].ID); err != nil {
+		t.Fatalf("MarkActedOn failed: %v", err)
+	}
+	if err := svc.MarkSeen(ctx, open[1].ID); err == nil {
+		t.Errorf("expected an error for an opportunity that was acted on")
+	}
+
+	// The signal candle itself and candles of other timeframes do not age the opportunity.
+	next := func(closeTime time.Time, tf domain.Timeframe) domain.Candle {
+		return domain.Candle{Symbol: "AVNTUSDT", Timeframe: tf, CloseTime: closeTime}
+	}
+	for _, c := range []domain.Candle{
+		next(candleTime, domain.Minute1),
+		next(candleTime.Add(time.Hour), domain.Hour1),
+		next(candleTime.Add(time.Minute), domain.Minute1),
+	} {
+		if err := svc.OnCandle(ctx, c); err != nil {
+			t.Fatalf("OnCandle failed: %v", err)
+		}
+	}
+	if open := svc.Open(); len(open) != 1 || open[0].Age != 1 || open[0].Status != domain.OpportunitySeen {
+		t.Fatalf("expected one seen opportunity of age 1, got %+v", open)
+	}
+
+	// A restarted service continues from the stored state.
+	restarted := opportunity.NewService(cfg, repo, nil, nil)
+	if err := restarted.Load(ctx); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if open := restarted.Open(); len(open) != 1 || open[0].ID != first.ID || open[0].Signal.Strategy != sig.Strategy {
+		t.Fatalf("expected the seen opportunity after a restart, got %+v", open)
+	}
+	if err := restarted.OnCandle(ctx, next(candleTime.Add(2*time.Minute), domain.Minute1)); err != nil {
+		t.Fatalf("OnCandle failed: %v", err)
+	}
+	if open := restarted.Open(); len(open) != 0 {
+		t.Fatalf("expected the opportunity to expire, got %+v", open)
+	}
+
+	stored, err := repo.ListOpportunities(ctx)
+	if err != nil {
+		t.Fatalf("ListOpportunities failed: %v", err)
+	}
+	want := []domain.OpportunityStatus{domain.OpportunityExpired, domain.OpportunityActedOn}
+	if len(stored) != len(want) {
+		t.Fatalf("expected %d stored opportunities, got %d", len(want), len(stored))
+	}
+	for i, o := range stored {
+		if o.Status != want[i] {
+			t.Errorf("opportunity %d: expected status %s, got %s", o.ID, want[i], o.Status)
+		}
+	}
+}
+
+func TestOpportunityHandler(t *testing.T) {
+	candleTime := time.Date(2025, 10, 2, 20, 58, 0, 0, time.UTC)
+	svc := opportunity.NewService(opportunity.DefaultConfig(), nil, nil, nil)
+	sig := domain.Signal{Strategy: "Stoch & Bollinger Bands", Exchange: "MEXC", Symbol: "AVNTUSDT", Timeframe: domain.Minute1,
+		Direction: domain.Buy, Time: candleTime, Price: 1.165, Metrics: domain.SignalMetrics{CandleTime: candleTime, StochD: math.NaN()}}
+	if err := svc.OnSignal(context.Background(), sig); err != nil {
+		t.Fatalf("OnSignal failed: %v", err)
+	}
+	server := httptest.NewServer(opportunity.NewHandler(svc))
+	defer server.Close()
+
+	list := func() []map[string]any {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/opportunities")
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var cards []map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&cards); err != nil {
+			t.Fatalf("could not decode the opportunities: %v", err)
+		}
+		return cards
+	}
+	post := func(path string) int {
+		t.Helper()
+		resp, err := http.Post(server.URL+path, "", nil)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	cards := list()
+	if len(cards) != 1 || cards[0]["wghm"] != domain.WGHMNone || cards[0]["stoch_d"] != nil || cards[0]["status"] != "new" {
+		t.Fatalf("expected one new opportunity with its WGHM state, got %+v", cards)
+	}
+	if code := post("/opportunities/1/seen"); code != http.StatusNoContent {
+		t.Errorf("expected marking it seen to succeed, got %d", code)
+	}
+	if cards := list(); len(cards) != 1 || cards[0]["status"] != "seen" {
+		t.Errorf("expected the opportunity to be seen, got %+v", cards)
+	}
+	if code := post("/opportunities/1/acted-on"); code != http.StatusNoContent {
+		t.Errorf("expected marking it acted on to succeed, got %d", code)
+	}
+	if cards := list(); len(cards) != 0 {
+		t.Errorf("expected the opportunity to leave the list, got %+v", cards)
+	}
+	if code := post("/opportunities/1/seen"); code != http.StatusNotFound {
+		t.Errorf("expected an opportunity that left the list not to be found, got %d", code)
+	}
+	if code := post("/opportunities/1/delete"); code != http.StatusNotFound {
+		t.Errorf("expected an unknown action not to be found, got %d", code)
+	}
+}
+
+func TestOpportunityTransitionsWhileAging(t *testing.T) {
+	ctx := context.Background()
+	candleTime := time.Date(2025, 10, 2, 20, 58, 0, 0, time.UTC)
+	cfg := opportunity.DefaultConfig()
+	cfg.ExpireAfter = 0
+	svc := opportunity.NewService(cfg, nil, nil, nil)
+	for i := 0; i < 50; i++ {
+		sig := domain.Signal{Symbol: "AVNTUSDT", Timeframe: domain.Minute1, Direction: domain.Buy, Time: candleTime}
+		if err := svc.OnSignal(ctx, sig); err != nil {
+			t.Fatalf("OnSignal failed: %v", err)
+		}
+	}
+
+	// Run with -race: the transitions of the handler and the aging of the candles share the opportunities.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 50; i++ {
+			c := domain.Candle{Symbol: "AVNTUSDT", Timeframe: domain.Minute1, CloseTime: candleTime.Add(time.Duration(i) * time.Minute)}
+			if err := svc.OnCandle(ctx, c); err != nil {
+				t.Errorf("OnCandle failed: %v", err)
+			}
+		}
+	}()
+	for id := int64(1); id <= 50; id++ {
+		if err := svc.MarkSeen(ctx, id); err != nil {
+			t.Errorf("MarkSeen failed: %v", err)
+		}
+	}
+	<-done
+	for _, o := range svc.Open() {
+		if o.Status != domain.OpportunitySeen || o.Age != 50 {
+			t.Errorf("expected every opportunity seen and aged 50 candles, got %+v", o)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/optimize"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/report"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+)
+
+func TestParseParam(t *testing.T) {
+	p, err := optimize.ParseParam("bb_stddev=1.5:2.5:0.1")
+	if err != nil {
+		t.Fatalf("ParseParam failed: %v", err)
+	}
+	values := p.Values()
+	if len(values) != 11 || values[0] != 1.5 || values[10] != 2.5 || values[3] != 1.8 {
+		t.Errorf("expected 1.5 to 2.5 in steps of 0.1, got %v", values)
+	}
+	if p, err := optimize.ParseParam("bb_period=20"); err != nil || !reflect.DeepEqual(p.Values(), []float64{20}) {
+		t.Errorf("expected the fixed value 20, got %v (%v)", p.Values(), err)
+	}
+	for _, bad := range []string{"bb_period", "=1:2:1", "bb_period=1:2", "bb_period=a:2:1", "bb_period=2:1:1", "bb_period=1:2:0"} {
+		if _, err := optimize.ParseParam(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestGridAndRandom(t *testing.T) {
+	space := []optimize.Param{{Name: "a", Min: 1, Max: 3, Step: 1}, {Name: "b", Min: 10, Max: 20, Step: 10}}
+	grid := optimize.Grid(space)
+	if len(grid) != 6 {
+		t.Fatalf("expected 6 points, got %v", grid)
+	}
+	seen := map[string]bool{}
+	for _, p := range grid {
+		seen[p.String()] = true
+	}
+	if len(seen) != 6 || !seen["a=2 b=20"] {
+		t.Errorf("expected every combination once, got %v", grid)
+	}
+
+	random := optimize.Random(space, 20, 7)
+	if len(random) != 20 {
+		t.Fatalf("expected 20 samples, got %d", len(random))
+	}
+	for _, p := range random {
+		if !seen[p.String()] {
+			t.Errorf("sample %s is not on the grid", p)
+		}
+	}
+	if !reflect.DeepEqual(random, optimize.Random(space, 20, 7)) {
+		t.Error("expected the same seed to draw the same samples")
+	}
+}
+
+func TestObjectiveScore(t *testing.T) {
+	m := backtest.Metrics{NetProfit: 50, ProfitFactor: 1.5, MaxDrawdownPercent: 12, TotalTrades: 8}
+	if score, ok := (optimize.Objective{Metric: "profit_factor"}).Score(m); !ok || score != 1.5 {
+		t.Errorf("expected a feasible score of 1.5, got %v %v", score, ok)
+	}
+	if score, ok := (optimize.Objective{Metric: "max_drawdown"}).Score(m); !ok || score != -12 {
+		t.Errorf("expected the drawdown to score as -12, got %v %v", score, ok)
+	}
+	if _, ok := (optimize.Objective{Metric: "net_profit", MaxDrawdown: 10}).Score(m); ok {
+		t.Error("expected a 12% drawdown to break a 10% constraint")
+	}
+	if _, ok := (optimize.Objective{Metric: "net_profit", MinTrades: 10}).Score(m); ok {
+		t.Error("expected 8 trades to break a 10 trade minimum")
+	}
+	if _, ok := (optimize.Objective{Metric: "sharpe"}).Score(backtest.Metrics{Sharpe: backtest.Ratio(math.NaN())}); ok {
+		t.Error("expected an undefined Sharpe ratio not to be feasible")
+	}
+	if err := (optimize.Objective{Metric: "luck"}).Validate(); err == nil {
+		t.Error("expected an unknown metric to be rejected")
+	}
+}
+
+func TestOptimizeRunRanksTrials(t *testing.T) {
+	// A fake backtest whose net profit is a*b, with a drawdown of a percent.
+	var calls atomic.Int32
+	run := func(ctx context.Context, p optimize.Params) (*backtest.Result, error) {
+		calls.Add(1)
+		if p["a"] == 2 && p["b"] == 2 {
+			return nil, fmt.Errorf("no data")
+		}
+		profit := p["a"] * p["b"]
+		return &backtest.Result{
+			InitialBalance: 100,
+			FinalEquity:    100 + profit,
+			Equity:         []backtest.EquityPoint{{Equity: 100 - p["a"]}},
+			Trades:         []backtest.Trade{{PnL: profit}},
+		}, nil
+	}
+	space := []optimize.Param{{Name: "a", Min: 1, Max: 3, Step: 1}, {Name: "b", Min: 1, Max: 3, Step: 1}}
+	trials, err := optimize.Run(context.Background(), optimize.Grid(space), optimize.Objective{Metric: "net_profit", MaxDrawdown: 2.5}, 4, run)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(trials) != 9 || calls.Load() != 9 {
+		t.Fatalf("expected 9 trials, got %d after %d backtests", len(trials), calls.Load())
+	}
+	// a=3 breaks the drawdown constraint and a=2 b=2 fails, so the best is a=2 b=3.
+	if best := trials[0]; best.Params.String() != "a=2 b=3" || best.Score != 6 || !best.Feasible {
+		t.Errorf("expected a=2 b=3 to rank first with 6, got %s with %v", best.Params, best.Score)
+	}
+	for i, tr := range trials {
+		if i < 5 && !tr.Feasible {
+			t.Errorf("expected the 5 feasible trials first, trial %d is %s", i, tr.Params)
+		}
+		if i > 0 && tr.Feasible && tr.Score > trials[i-1].Score {
+			t.Errorf("trial %d (%v) ranks below a lower score (%v)", i, tr.Score, trials[i-1].Score)
+		}
+	}
+
+	var table bytes.Buffer
+	if err := report.WriteTrialsTable(&table, trials, []string{"a", "b"}, 3); err != nil {
+		t.Fatalf("WriteTrialsTable failed: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(table.String()), "\n"); len(lines) != 4 || !strings.Contains(lines[0], "SCORE") {
+		t.Errorf("expected a header and 3 results, got:\n%s", table.String())
+	}
+	var csv bytes.Buffer
+	if err := report.WriteTrialsCSV(&csv, trials, []string{"a", "b"}); err != nil {
+		t.Fatalf("WriteTrialsCSV failed: %v", err)
+	}
+	if !strings.Contains(csv.String(), "no data") || strings.Count(csv.String(), "\n") != 10 {
+		t.Errorf("expected a header and 9 rows including the failed trial, got:\n%s", csv.String())
+	}
+
+	heatmap := report.TrialsHeatmap("net profit", trials, "a", "b")
+	if !reflect.DeepEqual(heatmap.X, []float64{1, 2, 3}) || heatmap.Values[2][1] != 6 || !math.IsNaN(heatmap.Values[1][1]) || !math.IsNaN(heatmap.Values[0][2]) {
+		t.Errorf("unexpected heatmap %+v", heatmap)
+	}
+	if svg := heatmap.SVG(); !strings.HasPrefix(svg, "<svg") || strings.Count(svg, "<rect") != 10 {
+		t.Errorf("expected an SVG with a background and 9 cells, got:\n%s", svg)
+	}
+}
+
+func TestEngineBacktestAppliesParams(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	storeRamp(t, repo, "BTCUSDT", start, 120)
+
+	cfg := backtest.DefaultConfig()
+	cfg.Symbol, cfg.Timeframe, cfg.From, cfg.To, cfg.WarmUp = "BTCUSDT", domain.Minute1, start, start.Add(time.Hour), 0
+	params := optimize.Params{"bb_period": 10, "bb_stddev": 1.5}
+	short, err := backtest.LoadHistory(context.Background(), repo, cfg.Symbol, cfg.From, cfg.To)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if _, err := optimize.EngineBacktest(cfg, short, "stoch-bb")(context.Background(), params); err == nil {
+		t.Error("expected a history loaded without the warm-up of the strategy to fail the backtest")
+	}
+
+	warmUp, err := optimize.WarmUpPeriod(cfg, "stoch-bb", []optimize.Params{params, {"bb_periods": 10}})
+	if err != nil {
+		t.Fatalf("WarmUpPeriod failed: %v", err)
+	}
+	if warmUp != 19*time.Minute {
+		t.Errorf("expected a warm-up of 19m for the stochastic, got %s", warmUp)
+	}
+	history, err := backtest.LoadHistory(context.Background(), repo, cfg.Symbol, cfg.From.Add(-warmUp), cfg.To)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	run := optimize.EngineBacktest(cfg, history, "stoch-bb")
+	res, err := run(context.Background(), params)
+	if err != nil {
+		t.Fatalf("backtest failed: %v", err)
+	}
+	if len(res.Candles) != 59 {
+		t.Errorf("expected the in-memory history to replay 59 closed candles, got %d", len(res.Candles))
+	}
+	if _, err := run(context.Background(), optimize.Params{"bb_periods": 10}); err == nil {
+		t.Error("expected an unknown parameter to fail the backtest")
+	}
+	if _, err := optimize.StrategyFactory("macd", nil); err == nil {
+		t.Error("expected an unknown strategy to be rejected")
+	}
+}
+
+func TestOptimizeDefinitionParams(t *testing.T) {
+	const path = "../strategies/stoch-bb.json"
+	names, err := optimize.ParamNames(path)
+	if err != nil {
+		t.Fatalf("ParamNames failed: %v", err)
+	}
+	want := []string{"bb.period", "bb.stddev", "stoch.d", "stoch.period", "stoch.smooth"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("expected the indicator parameters %v, got %v", want, names)
+	}
+
+	def, err := strategy.LoadDefinition(path)
+	if err != nil {
+		t.Fatalf("LoadDefinition failed: %v", err)
+	}
+	changed, err := optimize.WithParams(def, optimize.Params{"bb.period": 10})
+	if err != nil {
+		t.Fatalf("WithParams failed: %v", err)
+	}
+	if changed.Indicators["bb"].Params["period"] != 10 || changed.Indicators["bb"].Params["stddev"] != 2 || def.Indicators["bb"].Params["period"] != 20 {
+		t.Errorf("expected a copy with only bb.period changed, got %+v (original %+v)", changed.Indicators, def.Indicators)
+	}
+	if _, err := optimize.WithParams(def, optimize.Params{"bb.width": 1}); err == nil {
+		t.Error("expected an unknown parameter to be rejected")
+	}
+	if _, err := optimize.WithParams(def, optimize.Params{"bb.period": 0}); err == nil {
+		t.Error("expected an invalid parameter value to be rejected")
+	}
+
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	storeRamp(t, repo, "BTCUSDT", start, 120)
+	cfg := backtest.DefaultConfig()
+	cfg.Symbol, cfg.Timeframe, cfg.From, cfg.To, cfg.WarmUp = "BTCUSDT", domain.Minute1, start, start.Add(time.Hour), 0
+	res, err := optimize.EngineBacktest(cfg, repo, path)(context.Background(), optimize.Params{"bb.period": 10})
+	if err != nil || len(res.Candles) == 0 {
+		t.Errorf("expected the definition to be backtested, got %v", err)
+	}
+}
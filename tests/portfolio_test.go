@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/report"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+)
+
+// runPortfolio backtests BTCUSDT and ETHUSDT, whose tickers come 10 seconds after those of BTCUSDT,
+// with a strategy that buys on the first candle of both pairs.
+func runPortfolio(t *testing.T, symbols []string, maxPositions int, allocation backtest.Allocation) *backtest.Result {
+	t.Helper()
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	storeRamp(t, repo, "BTCUSDT", start, 12)
+	storeRamp(t, repo, "ETHUSDT", start.Add(10*time.Second), 12)
+
+	cfg := backtest.PortfolioConfig{Symbols: symbols, MaxPositions: maxPositions, Allocation: allocation}
+	cfg.Config = backtest.DefaultConfig()
+	cfg.Timeframe, cfg.From, cfg.To, cfg.WarmUp = domain.Minute1, start, start.Add(time.Hour), 0
+	engine := backtest.NewPortfolioEngine(cfg, repo)
+	engine.Add(domain.Minute1, func() strategy.Strategy {
+		return &scripted{script: []domain.Direction{domain.Buy, "", "", "", "", "", "", ""}}
+	})
+	res, err := engine.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return res
+}
+
+func TestPortfolioPositionLimit(t *testing.T) {
+	// The first candles of both pairs close at 00:01 and both buy. BTCUSDT fills first, at 00:01:30,
+	// and takes the only position.
+	res := runPortfolio(t, []string{"BTCUSDT", "ETHUSDT"}, 1, backtest.Allocation{})
+	if len(res.Trades) != 1 || res.Trades[0].Symbol != "BTCUSDT" {
+		t.Fatalf("expected one BTCUSDT trade, got %+v", res.Trades)
+	}
+	if len(res.Rejected) != 1 || res.Rejected[0].Symbol != "ETHUSDT" {
+		t.Errorf("expected the ETHUSDT entry to be rejected, got %+v", res.Rejected)
+	}
+	if res.Symbol != "BTCUSDT,ETHUSDT" || len(res.Candles) != 0 {
+		t.Errorf("expected a portfolio result without candles, got %s with %d candles", res.Symbol, len(res.Candles))
+	}
+
+	// With ETHUSDT first in priority, BTCUSDT must leave the position to the ETHUSDT signal of the
+	// same candle, although its order could fill earlier.
+	res = runPortfolio(t, []string{"ETHUSDT", "BTCUSDT"}, 1, backtest.Allocation{})
+	if len(res.Trades) != 1 || res.Trades[0].Symbol != "ETHUSDT" {
+		t.Fatalf("expected one ETHUSDT trade, got %+v", res.Trades)
+	}
+	if len(res.Rejected) != 1 || res.Rejected[0].Symbol != "BTCUSDT" {
+		t.Errorf("expected the BTCUSDT entry to be rejected, got %+v", res.Rejected)
+	}
+}
+
+func TestPortfolioSharesTheBalance(t *testing.T) {
+	res := runPortfolio(t, []string{"BTCUSDT", "ETHUSDT"}, 2, backtest.Allocation{Kind: backtest.AllocateEqual})
+	if len(res.Trades) != 2 || len(res.Rejected) != 0 {
+		t.Fatalf("expected a trade on both pairs, got %+v (rejected %+v)", res.Trades, res.Rejected)
+	}
+	var pnl float64
+	for _, tr := range res.Trades {
+		// Both enter at 103 with half of the 10000 balance and exit at 111 at the end.
+		if !almostEqual(tr.EntryPrice*tr.Quantity, 5000) || tr.EntryPrice != 103 || tr.ExitPrice != 111 {
+			t.Errorf("expected %s to enter with 5000 at 103, got %v at %v", tr.Symbol, tr.EntryPrice*tr.Quantity, tr.EntryPrice)
+		}
+		pnl += tr.PnL
+	}
+	if !almostEqual(res.FinalEquity, 10000+pnl) {
+		t.Errorf("expected the final equity to add up the P&L of both pairs, got %v", res.FinalEquity)
+	}
+	// Equity points are shared by the candles of both pairs that close at the same time.
+	for i := 1; i < len(res.Equity); i++ {
+		if !res.Equity[i].Time.After(res.Equity[i-1].Time) {
+			t.Fatalf("expected one equity point per candle close, got %+v", res.Equity)
+		}
+	}
+
+	var table bytes.Buffer
+	if err := report.NewBacktestReport(res).WriteTable(&table); err != nil {
+		t.Fatalf("WriteTable failed: %v", err)
+	}
+	if !strings.Contains(table.String(), "PAIR") || !strings.Contains(table.String(), "ETHUSDT") {
+		t.Errorf("expected a breakdown by pair, got:\n%s", table.String())
+	}
+
+	// Without a limit, the whole balance of the plan leaves nothing for the second pair.
+	res = runPortfolio(t, []string{"BTCUSDT", "ETHUSDT"}, 0, backtest.Allocation{})
+	if len(res.Trades) != 1 || len(res.Rejected) != 1 {
+		t.Errorf("expected the second entry to find no free cash, got %+v (rejected %+v)", res.Trades, res.Rejected)
+	}
+}
+
+func TestParseAllocation(t *testing.T) {
+	for in, want := range map[string]backtest.Allocation{
+		"plan":       {Kind: backtest.AllocatePlan},
+		"equal":      {Kind: backtest.AllocateEqual},
+		"percent:25": {Kind: backtest.AllocatePercent, Value: 25},
+	} {
+		if got, err := backtest.ParseAllocation(in); err != nil || got != want {
+			t.Errorf("%s: expected %+v, got %+v (%v)", in, want, got, err)
+		}
+	}
+	for _, bad := range []string{"", "half", "percent:", "percent:-5", "percent:10x"} {
+		if _, err := backtest.ParseAllocation(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
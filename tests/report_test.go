@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/report"
+)
+
+func TestTrendReportOutputs(t *testing.T) {
+	start := time.Date(2025, 10, 2, 20, 0, 0, 0, time.UTC)
+	points := []report.TrendPoint{
+		{Time: start, Price: 100, Trend: domain.Neutral, Value: 0},
+		{Time: start.Add(time.Hour), Price: math.NaN(), Trend: domain.Bullish, Value: 1.5},
+		{Time: start.Add(2 * time.Hour), Price: 102, Trend: domain.Bearish, Value: -0.5},
+	}
+
+	var csv bytes.Buffer
+	if err := report.WriteTrendCSV(&csv, "strength", points); err != nil {
+		t.Fatalf("WriteTrendCSV failed with an unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 4 || lines[0] != "time,price,trend,strength" {
+		t.Fatalf("unexpected CSV output:\n%s", csv.String())
+	}
+	if lines[2] != "2025-10-02T21:00:00Z,,bullish,1.5000" {
+		t.Errorf("unexpected CSV row for a point without price: %q", lines[2])
+	}
+
+	svg := report.TrendChart("Chart trend BTCUSDT 1h", "strength", points).SVG()
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, "Chart trend BTCUSDT 1h") {
+		t.Errorf("expected a titled SVG document, got:\n%s", svg)
+	}
+	// The price line is split around the missing price into two single-point segments plus the strength line.
+	if n := strings.Count(svg, "<polyline"); n != 3 {
+		t.Errorf("expected 3 polylines, got %d", n)
+	}
+	if !strings.Contains(svg, report.TrendColor(domain.Bullish)) || !strings.Contains(svg, report.TrendColor(domain.Bearish)) {
+		t.Errorf("expected bullish and bearish shading in the SVG")
+	}
+}
@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/optimize"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/report"
+)
+
+// storedRun returns a run of a backtest that made one trade with a P&L.
+func storedRun(t *testing.T, pnl float64, params map[string]string) domain.BacktestRun {
+	t.Helper()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	res := &backtest.Result{
+		Symbol: "BTCUSDT", Timeframe: domain.Hour1, From: start, To: start.Add(24 * time.Hour),
+		InitialBalance: 1000, FinalEquity: 1000 + pnl,
+		Trades: []backtest.Trade{{Symbol: "BTCUSDT", Direction: domain.Buy, EntryTime: start, ExitTime: start.Add(time.Hour), PnL: pnl}},
+	}
+	run, err := report.NewBacktestReport(res).NewBacktestRun("strategies/rsi-trend.json", "ab12cd34ef56ab12cd34", params)
+	if err != nil {
+		t.Fatalf("NewBacktestRun failed: %v", err)
+	}
+	return run
+}
+
+func TestBacktestRunStorage(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	run := storedRun(t, 50, map[string]string{"fees": "VIP0"})
+	id, err := repo.SaveBacktestRun(ctx, run)
+	if err != nil {
+		t.Fatalf("SaveBacktestRun failed: %v", err)
+	}
+	got, err := repo.GetBacktestRun(ctx, id)
+	if err != nil || got == nil {
+		t.Fatalf("GetBacktestRun failed: %v %v", got, err)
+	}
+	if got.Strategy != run.Strategy || got.DefinitionHash != run.DefinitionHash || got.Params["fees"] != "VIP0" ||
+		!got.From.Equal(run.From) || got.Timeframe != domain.Hour1 || got.EngineVersion != backtest.EngineVersion {
+		t.Errorf("expected the stored run back, got %+v", got)
+	}
+	var metrics struct {
+		NetProfit float64 `json:"net_profit"`
+	}
+	var trades []backtest.Trade
+	if err := json.Unmarshal(got.Metrics, &metrics); err != nil || metrics.NetProfit != 50 {
+		t.Errorf("expected the metrics to be stored, got %s (%v)", got.Metrics, err)
+	}
+	if err := json.Unmarshal(got.Trades, &trades); err != nil || len(trades) != 1 || trades[0].PnL != 50 {
+		t.Errorf("expected the trades to be stored, got %s (%v)", got.Trades, err)
+	}
+	if missing, err := repo.GetBacktestRun(ctx, id+1); err != nil || missing != nil {
+		t.Errorf("expected no run for an unknown ID, got %v %v", missing, err)
+	}
+
+	other := storedRun(t, -20, nil)
+	other.Symbol = "ETHUSDT"
+	if _, err := repo.SaveBacktestRun(ctx, other); err != nil {
+		t.Fatalf("SaveBacktestRun failed: %v", err)
+	}
+	if runs, err := repo.ListBacktestRuns(ctx, "", 0); err != nil || len(runs) != 2 || runs[0].Symbol != "ETHUSDT" {
+		t.Errorf("expected both runs, newest first, got %+v (%v)", runs, err)
+	}
+	if runs, err := repo.ListBacktestRuns(ctx, "BTCUSDT", 0); err != nil || len(runs) != 1 || runs[0].ID != id {
+		t.Errorf("expected the BTCUSDT run, got %+v (%v)", runs, err)
+	}
+	if runs, err := repo.ListBacktestRuns(ctx, "", 1); err != nil || len(runs) != 1 {
+		t.Errorf("expected the limit to apply, got %d runs (%v)", len(runs), err)
+	}
+}
+
+func TestBacktestRunComparison(t *testing.T) {
+	a := storedRun(t, 50, map[string]string{"fees": "VIP0", "balance": "1000"})
+	b := storedRun(t, 80, map[string]string{"fees": "VIP3", "balance": "1000"})
+	a.ID, b.ID = 1, 2
+
+	var diff bytes.Buffer
+	if err := report.WriteRunComparison(&diff, []domain.BacktestRun{a, b}, true); err != nil {
+		t.Fatalf("WriteRunComparison failed: %v", err)
+	}
+	out := diff.String()
+	for _, want := range []string{"RUN 1", "RUN 2", "CHANGE", "* fees", "VIP3", "* net_profit", "+30.00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected the diff to contain %q, got:\n%s", want, out)
+		}
+	}
+	for _, same := range []string{"balance", "strategy", "total_trades"} {
+		if strings.Contains(out, same) {
+			t.Errorf("expected the diff to leave out %q, got:\n%s", same, out)
+		}
+	}
+
+	var compare bytes.Buffer
+	if err := report.WriteRunComparison(&compare, []domain.BacktestRun{a, b, b}, false); err != nil {
+		t.Fatalf("WriteRunComparison failed: %v", err)
+	}
+	if out := compare.String(); !strings.Contains(out, "total_trades") || strings.Contains(out, "CHANGE") {
+		t.Errorf("expected every row and no change column for three runs, got:\n%s", out)
+	}
+
+	var list bytes.Buffer
+	if err := report.WriteRuns(&list, []domain.BacktestRun{b, a}); err != nil {
+		t.Fatalf("WriteRuns failed: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(list.String()), "\n"); len(lines) != 3 || !strings.Contains(lines[1], "ab12cd34ef56 ") {
+		t.Errorf("expected a header and two runs with short hashes, got:\n%s", list.String())
+	}
+}
+
+func TestOptimizationRunsAreStored(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	window := func(from time.Time, pnl float64) *backtest.Result {
+		return &backtest.Result{
+			Symbol: "BTCUSDT", Timeframe: domain.Hour1, From: from, To: from.Add(24 * time.Hour),
+			InitialBalance: 1000, FinalEquity: 1000 + pnl,
+			Trades: []backtest.Trade{{Symbol: "BTCUSDT", Direction: domain.Buy, EntryTime: from, ExitTime: from.Add(time.Hour), PnL: pnl}},
+		}
+	}
+	first, third := window(start, 30), window(start.Add(48*time.Hour), -10)
+	stitched := &backtest.Result{Symbol: "BTCUSDT", Timeframe: domain.Hour1, From: start, To: third.To, InitialBalance: 1000, FinalEquity: 1020,
+		Trades: append(append([]backtest.Trade{}, first.Trades...), third.Trades...)}
+	wf := &optimize.WalkForwardResult{
+		Windows: []optimize.WindowResult{
+			{Params: optimize.Params{"bb_period": 20}, OutOfSample: first},
+			{},
+			{Params: optimize.Params{"bb_period": 12.5}, OutOfSample: third},
+		},
+		OutOfSample: stitched,
+	}
+	runs, err := report.NewWalkForwardRuns(wf, "stoch-bb", "ab12cd34ef56ab12cd34", map[string]string{"objective": "sharpe"})
+	if err != nil {
+		t.Fatalf("NewWalkForwardRuns failed: %v", err)
+	}
+	for _, run := range runs {
+		if _, err := repo.SaveBacktestRun(ctx, run); err != nil {
+			t.Fatalf("SaveBacktestRun failed: %v", err)
+		}
+	}
+
+	// The window without parameters has no out-of-sample backtest to store.
+	stored, err := repo.ListBacktestRuns(ctx, "BTCUSDT", 0)
+	if err != nil || len(stored) != 3 {
+		t.Fatalf("expected two windows and the stitched run, got %+v (%v)", stored, err)
+	}
+	want := []struct{ label, bbPeriod string }{{"stitched", ""}, {"window 3", "12.5"}, {"window 1", "20"}}
+	for i, w := range want {
+		run := stored[i]
+		if run.Params["walk_forward"] != w.label || run.Params["bb_period"] != w.bbPeriod || run.Params["objective"] != "sharpe" {
+			t.Errorf("run %d: expected %s with bb_period %q, got %v", i, w.label, w.bbPeriod, run.Params)
+		}
+	}
+	if !stored[0].From.Equal(start) || !stored[0].To.Equal(third.To) || !stored[1].From.Equal(third.From) {
+		t.Errorf("expected the periods of the windows and of the stitched run, got %+v", stored)
+	}
+}
@@ -2,9 +2,12 @@ package tests
 
 import (
 	"context"
+	"database/sql"
+	"math"
 	"math/big"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
 	"github.com/dorpsen/cryptotradingbot-starter/internal/storage"
@@ -83,3 +86,196 @@ func TestSaveTicker(t *testing.T) {
 		t.Errorf("retrieved ticker does not match saved ticker.\nretrieved:  %+v\noriginal:   %+v", retrievedTicker, ticker)
 	}
 }
+
+// bigFloat converts a float64 to a *big.Float for building test tickers.
+func bigFloat(f float64) *big.Float {
+	return new(big.Float).SetFloat64(f)
+}
+
+func TestSaveAndListSignals(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	candleTime := time.Date(2025, 10, 2, 20, 58, 0, 0, time.UTC)
+	signal := domain.Signal{
+		Strategy:  "Stoch & Bollinger Bands",
+		Exchange:  "MEXC",
+		Symbol:    "AVNTUSDT",
+		Timeframe: domain.Minute1,
+		Direction: domain.Buy,
+		Time:      candleTime.Add(time.Minute),
+		Price:     1.165,
+		Metrics: domain.SignalMetrics{
+			CandleTime: candleTime,
+			BBWidth:    1.4,
+			StochK:     0,
+			StochD:     math.NaN(),
+			Volume24h:  3814656.28,
+			Change24h:  -2.43,
+		},
+		Plan: &domain.TradePlan{
+			Entry:      1.165,
+			StopLoss:   1.1,
+			TakeProfit: 1.3,
+			Size:       domain.SizeRule{Kind: domain.SizeRiskPercent, Value: 1},
+		},
+	}
+
+	id, err := repo.SaveSignal(context.Background(), signal)
+	if err != nil {
+		t.Fatalf("SaveSignal failed with an unexpected error: %v", err)
+	}
+
+	signals, err := repo.ListSignals(context.Background(), "AVNTUSDT", candleTime)
+	if err != nil {
+		t.Fatalf("ListSignals failed with an unexpected error: %v", err)
+	}
+	if len(signals) != 1 {
+		t.Fatalf("expected 1 signal, got %d", len(signals))
+	}
+
+	got := signals[0]
+	if got.ID != id || got.Strategy != signal.Strategy || got.Direction != domain.Buy || got.Timeframe != domain.Minute1 {
+		t.Errorf("retrieved signal does not match saved signal.\nretrieved:  %+v\noriginal:   %+v", got, signal)
+	}
+	if !got.Metrics.CandleTime.Equal(candleTime) || got.Metrics.BBWidth != 1.4 || got.Metrics.Change24h != -2.43 {
+		t.Errorf("retrieved metrics do not match saved metrics: %+v", got.Metrics)
+	}
+	if got.Plan == nil || *got.Plan != *signal.Plan {
+		t.Errorf("retrieved trade plan does not match saved plan: %+v", got.Plan)
+	}
+	if !math.IsNaN(got.Metrics.StochD) {
+		t.Errorf("expected a missing %%D to round-trip as NaN, got %v", got.Metrics.StochD)
+	}
+}
+
+func TestOlderDatabaseGetsTheNewColumns(t *testing.T) {
+	dbFile := "test_migrate.db"
+	os.Remove(dbFile)
+	defer os.Remove(dbFile)
+
+	// The signals table as it was before the trade plans.
+	db, err := sql.Open("sqlite3", dbFile)
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	_, err = db.Exec(`CREATE TABLE signals (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, strategy TEXT NOT NULL, exchange TEXT NOT NULL,
+		symbol TEXT NOT NULL, timeframe TEXT NOT NULL, direction TEXT NOT NULL,
+		signal_time INTEGER NOT NULL, price REAL NOT NULL, candle_time INTEGER NOT NULL,
+		bb_width REAL, stoch_k REAL, stoch_d REAL, volume_24h REAL, change_24h REAL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("could not create the old table: %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		repo, err := storage.NewSqliteRepository(ctx, dbFile)
+		if err != nil {
+			t.Fatalf("could not open the older database: %v", err)
+		}
+		at := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+		plan := &domain.TradePlan{Entry: 100, StopLoss: 95, TakeProfit: 110, Size: domain.SizeRule{Kind: domain.SizeRiskPercent, Value: 1}}
+		sig := domain.Signal{Strategy: "Test", Exchange: "BINANCE", Symbol: "BTCUSDT", Timeframe: domain.Hour1, Direction: domain.Buy, Time: at, Price: 100, Plan: plan}
+		if _, err := repo.SaveSignal(ctx, sig); err != nil {
+			t.Fatalf("SaveSignal failed: %v", err)
+		}
+		signals, err := repo.ListSignals(ctx, "BTCUSDT", at.Add(-time.Hour))
+		repo.Close()
+		if err != nil {
+			t.Fatalf("ListSignals failed: %v", err)
+		}
+		if len(signals) != i+1 || signals[i].Plan == nil || *signals[i].Plan != *plan {
+			t.Fatalf("expected the plans to be stored in the older table, got %+v", signals)
+		}
+	}
+}
+
+func TestTickerStatisticsAreStored(t *testing.T) {
+	dbFile := "test_migrate_ticks.db"
+	os.Remove(dbFile)
+	defer os.Remove(dbFile)
+	at := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	// A ticker stored before the ticks table had the 24h statistics.
+	db, err := sql.Open("sqlite3", dbFile)
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	_, err = db.Exec(`CREATE TABLE ticks (
+		event_type TEXT NOT NULL, event_time INTEGER NOT NULL, symbol TEXT NOT NULL,
+		last_price TEXT NOT NULL, volume TEXT NOT NULL, open_time INTEGER NOT NULL,
+		close_time INTEGER NOT NULL, count INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (symbol, event_time))`)
+	if err == nil {
+		_, err = db.Exec(`INSERT INTO ticks VALUES ('24hrTicker', ?, 'BTCUSDT', '100', '5', 0, 0, 0, CURRENT_TIMESTAMP)`, at.UnixMilli())
+	}
+	db.Close()
+	if err != nil {
+		t.Fatalf("could not create the old table: %v", err)
+	}
+
+	ctx := context.Background()
+	repo, err := storage.NewSqliteRepository(ctx, dbFile)
+	if err != nil {
+		t.Fatalf("could not open the older database: %v", err)
+	}
+	defer repo.Close()
+	ticker := marketTicker("BTCUSDT", at.Add(time.Second), 101, 2500000.5)
+	ticker.Volume = domain.BigString{Float: bigFloat(6)}
+	ticker.PriceChangePercent = domain.BigString{Float: bigFloat(-2.43)}
+	if err := repo.SaveTicker(ctx, ticker); err != nil {
+		t.Fatalf("SaveTicker failed: %v", err)
+	}
+
+	tickers, err := repo.ListTickers(ctx, "BTCUSDT", at, at.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("ListTickers failed: %v", err)
+	}
+	if len(tickers) != 2 {
+		t.Fatalf("expected 2 tickers, got %d", len(tickers))
+	}
+	if old := tickers[0]; old.QuoteVolume.Float != nil || old.PriceChangePercent.Float != nil {
+		t.Errorf("expected the older ticker without statistics, got %v and %v", old.QuoteVolume, old.PriceChangePercent)
+	}
+	if got := tickers[1]; got.QuoteVolume.Float64Value() != 2500000.5 || got.PriceChangePercent.Float64Value() != -2.43 {
+		t.Errorf("expected the statistics of the ticker, got %v and %v", got.QuoteVolume, got.PriceChangePercent)
+	}
+	got, err := repo.GetTickerByEventTime(ctx, ticker.EventTime)
+	if err != nil || got == nil || got.QuoteVolume.Float64Value() != 2500000.5 {
+		t.Errorf("expected GetTickerByEventTime to return the statistics, got %+v, %v", got, err)
+	}
+}
+
+func TestListTickersOfOneOrAllSymbols(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	at := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, symbol := range []string{"BTCUSDT", "ETHUSDT", "BTCUSDT"} {
+		ticker := marketTicker(symbol, at.Add(time.Duration(i)*time.Second), 100, 1000)
+		ticker.Volume = domain.BigString{Float: bigFloat(1)}
+		if err := repo.SaveTicker(ctx, ticker); err != nil {
+			t.Fatalf("SaveTicker failed: %v", err)
+		}
+	}
+
+	tickers, err := repo.ListTickers(ctx, "BTCUSDT", at, at.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("ListTickers failed: %v", err)
+	}
+	if len(tickers) != 2 || tickers[0].Symbol != "BTCUSDT" || tickers[1].Symbol != "BTCUSDT" {
+		t.Errorf("expected the 2 BTCUSDT tickers, got %+v", tickers)
+	}
+
+	tickers, err = repo.ListTickers(ctx, "", at, at.Add(2*time.Second))
+	if err != nil {
+		t.Fatalf("ListTickers failed: %v", err)
+	}
+	if len(tickers) != 2 || tickers[0].Symbol != "BTCUSDT" || tickers[1].Symbol != "ETHUSDT" {
+		t.Errorf("expected the tickers of all symbols before the end, got %+v", tickers)
+	}
+}
@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/app"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+)
+
+// buyOnRise emits a buy signal whenever a candle closes higher than the previous one.
+type buyOnRise struct {
+	candlesSeen int
+}
+
+func (s *buyOnRise) Name() string { return "Buy on rise" }
+func (s *buyOnRise) WarmUp() int  { return 3 }
+
+func (s *buyOnRise) OnCandle(in strategy.Input) []domain.Signal {
+	s.candlesSeen++
+	n := len(in.Candles)
+	if in.Candles[n-1].Close > in.Candles[n-2].Close {
+		return []domain.Signal{strategy.NewSignal(s, in, domain.Buy)}
+	}
+	return nil
+}
+
+func (s *buyOnRise) OnTicker(in strategy.Input, ticker domain.Ticker) []domain.Signal {
+	return nil
+}
+
+// signalCollector records the signals passed to it.
+type signalCollector struct {
+	signals []domain.Signal
+}
+
+func (c *signalCollector) OnSignal(ctx context.Context, signal domain.Signal) error {
+	c.signals = append(c.signals, signal)
+	return nil
+}
+
+func TestStrategyRunnerWarmsUpAndEmitsSignals(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	runner := app.NewStrategyRunner("BINANCE", repo)
+	instance := &buyOnRise{}
+	runner.Add("BTCUSDT", domain.Minute1, func() strategy.Strategy { return instance })
+	collector := &signalCollector{}
+	runner.AddSignalHandler(collector)
+
+	candles := makeCandles(10, 11, 12, 11, 13)
+	for _, c := range candles {
+		if err := runner.OnCandle(ctx, c); err != nil {
+			t.Fatalf("OnCandle failed with an unexpected error: %v", err)
+		}
+	}
+	// Candles of another timeframe are ignored.
+	other := candles[0]
+	other.Timeframe = domain.Hour1
+	runner.OnCandle(ctx, other)
+
+	if instance.candlesSeen != 3 {
+		t.Errorf("expected the strategy to be called after its warm-up of 3 candles, got %d calls", instance.candlesSeen)
+	}
+	if len(collector.signals) != 2 {
+		t.Fatalf("expected 2 signals, got %+v", collector.signals)
+	}
+	sig := collector.signals[1]
+	if sig.ID == 0 || sig.Exchange != "BINANCE" || sig.Price != 13 || !sig.Metrics.CandleTime.Equal(candles[4].CloseTime) {
+		t.Errorf("expected a saved signal with a metrics snapshot, got %+v", sig)
+	}
+}
+
+func TestStochBBBuysOnLowerBandTouchWithOversoldCross(t *testing.T) {
+	closes := make([]float64, 0, 30)
+	for i := 0; i < 25; i++ {
+		closes = append(closes, 100+float64(i%2))
+	}
+	closes = append(closes, 97, 94, 92, 91)
+	candles := makeCandles(closes...)
+	// The last candle dips through the lower band but closes near its high: %K crosses above %D.
+	candles = append(candles, candles[len(candles)-1])
+	last := &candles[len(candles)-1]
+	last.OpenTime = last.OpenTime.Add(time.Minute)
+	last.CloseTime = last.CloseTime.Add(time.Minute)
+	last.Open, last.Low, last.High, last.Close = 91, 88, 94, 93.5
+
+	s := strategy.NewStochBB(strategy.DefaultStochBBConfig())
+	in := strategy.Input{Exchange: "MEXC", Symbol: "BTCUSDT", Timeframe: domain.Minute1}
+
+	for i := s.WarmUp(); i < len(candles); i++ {
+		in.Candles = candles[:i]
+		if signals := s.OnCandle(in); len(signals) != 0 {
+			t.Fatalf("expected no signal before the band touch, got %+v at candle %d", signals, i)
+		}
+	}
+
+	in.Candles = candles
+	signals := s.OnCandle(in)
+	if len(signals) != 1 || signals[0].Direction != domain.Buy {
+		t.Fatalf("expected a buy signal, got %+v", signals)
+	}
+	m := signals[0].Metrics
+	if m.BBWidth < 1 || m.StochK <= m.StochD || signals[0].Direction.Moment() != "Possible buy moment" {
+		t.Errorf("unexpected signal metrics: %+v", m)
+	}
+
+	// The same setup is filtered out when the band width minimum is not met.
+	cfg := strategy.DefaultStochBBConfig()
+	cfg.MinBBWidth = 50
+	if signals := strategy.NewStochBB(cfg).OnCandle(in); len(signals) != 0 {
+		t.Errorf("expected the band width minimum to filter the signal, got %+v", signals)
+	}
+}
+
+func TestRSIDivergenceBuysWithTradePlan(t *testing.T) {
+	closes := []float64{}
+	for i := 0; i < 20; i++ {
+		closes = append(closes, 100+float64(i%2))
+	}
+	// A fast drop to a first low, a rebound, then a slow drift to a lower low and a bounce.
+	closes = append(closes, 97, 94, 91, 88, 86, 89, 92, 95, 96, 95, 94, 93, 92, 91, 90, 89, 88, 85.5, 87, 89, 91)
+	candles := makeCandles(closes...)
+
+	s := strategy.NewRSIDivergence(strategy.DefaultRSIDivergenceConfig())
+	in := strategy.Input{Exchange: "BINANCE", Symbol: "ADAUSDT", Timeframe: domain.Hour1}
+
+	var signals []domain.Signal
+	for i := 2; i <= len(candles); i++ {
+		in.Candles = candles[:i]
+		signals = append(signals, s.OnCandle(in)...)
+	}
+	if len(signals) != 1 || signals[0].Direction != domain.Buy {
+		t.Fatalf("expected one buy signal, got %+v", signals)
+	}
+
+	sig := signals[0]
+	// The divergence is confirmed three candles after the second low, at the close of 91.
+	if sig.Price != 91 || sig.Plan == nil {
+		t.Fatalf("expected a signal at 91 with a trade plan, got %+v", sig)
+	}
+	plan := sig.Plan
+	if plan.Entry != 91 || plan.StopLoss >= 84.5 || !almostEqual(plan.RewardRisk(), 2) || plan.Size.Kind != domain.SizeRiskPercent {
+		t.Errorf("unexpected trade plan: %+v", plan)
+	}
+}
+
+func TestTradePlanQuantity(t *testing.T) {
+	plan := domain.TradePlan{Entry: 100, StopLoss: 95, TakeProfit: 110, Size: domain.SizeRule{Kind: domain.SizeRiskPercent, Value: 1}}
+	// Risking 1% of 10000 USDT with a 5 USDT stop distance buys 20 units.
+	if q := plan.Quantity(10000); !almostEqual(q, 20) {
+		t.Errorf("expected a quantity of 20, got %v", q)
+	}
+	// The quantity is capped by the balance.
+	plan.StopLoss = 99.99
+	if q := plan.Quantity(10000); !almostEqual(q, 100) {
+		t.Errorf("expected the quantity to be capped at 100, got %v", q)
+	}
+	plan.Size = domain.SizeRule{Kind: domain.SizeFixedQuote, Value: 500}
+	if q := plan.Quantity(10000); !almostEqual(q, 5) {
+		t.Errorf("expected a quantity of 5, got %v", q)
+	}
+}
@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/candle"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/trend"
+)
+
+func TestAggregatorClosesCandlesPerTimeframe(t *testing.T) {
+	agg := candle.NewAggregator(domain.Minute5, domain.Minute1)
+	start := time.Date(2025, 10, 2, 20, 0, 0, 0, time.UTC)
+
+	if closed := agg.Add("BTCUSDT", start, 100, 1); len(closed) != 0 {
+		t.Fatalf("expected no closed candles on the first tick, got %d", len(closed))
+	}
+	agg.Add("BTCUSDT", start.Add(20*time.Second), 105, 1)
+	agg.Add("BTCUSDT", start.Add(40*time.Second), 95, 1)
+
+	closed := agg.Add("BTCUSDT", start.Add(70*time.Second), 101, 1)
+	if len(closed) != 1 || closed[0].Timeframe != domain.Minute1 {
+		t.Fatalf("expected only the 1m candle to close, got %+v", closed)
+	}
+	c := closed[0]
+	if c.Open != 100 || c.High != 105 || c.Low != 95 || c.Close != 95 || c.Volume != 3 {
+		t.Errorf("unexpected candle: %+v", c)
+	}
+
+	closed = agg.Add("BTCUSDT", start.Add(5*time.Minute), 102, 1)
+	if len(closed) != 2 || closed[0].Timeframe != domain.Minute1 || closed[1].Timeframe != domain.Minute5 {
+		t.Fatalf("expected the 1m and 5m candles to close, got %+v", closed)
+	}
+}
+
+func TestChartTrendResumesFromStoredState(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	closes := make([]float64, 60)
+	for i := range closes {
+		closes[i] = 100 + float64(i)
+	}
+	candles := makeCandles(closes...)
+	cfg := trend.ChartConfig{FastPeriod: 5, SlowPeriod: 20, NeutralBand: 0.1}
+
+	first := trend.NewChartTrendService(cfg, repo)
+	if err := first.Resume(ctx, "BTCUSDT", candles[:40]); err != nil {
+		t.Fatalf("Resume failed with an unexpected error: %v", err)
+	}
+
+	// A restarted service continues from the stored state; history it already processed is skipped.
+	second := trend.NewChartTrendService(cfg, repo)
+	if err := second.Resume(ctx, "BTCUSDT", candles); err != nil {
+		t.Fatalf("Resume failed with an unexpected error: %v", err)
+	}
+	resumed, ok := second.Trend("BTCUSDT", domain.Minute1)
+	if !ok {
+		t.Fatalf("expected a 1m trend after resuming")
+	}
+
+	full := trend.NewChartTrendService(cfg, nil)
+	if err := full.Resume(ctx, "BTCUSDT", candles); err != nil {
+		t.Fatalf("Resume failed with an unexpected error: %v", err)
+	}
+	expected, _ := full.Trend("BTCUSDT", domain.Minute1)
+
+	if resumed.Samples != 60 || !almostEqual(resumed.SlowEMA, expected.SlowEMA) {
+		t.Errorf("resumed state differs from a full recalculation.\nresumed:  %+v\nexpected: %+v", resumed, expected)
+	}
+	if resumed.Trend != domain.Bullish || second.Overall("BTCUSDT") != domain.Bullish {
+		t.Errorf("expected a bullish trend for rising prices, got %s", resumed.Trend)
+	}
+}
+
+func TestOverallTrendWeighsTimeframesByRank(t *testing.T) {
+	trends := func(t1m, t5m, t1h domain.Trend) []domain.ChartTrend {
+		return []domain.ChartTrend{
+			{Timeframe: domain.Minute1, Trend: t1m},
+			{Timeframe: domain.Minute5, Trend: t5m},
+			{Timeframe: domain.Hour1, Trend: t1h},
+		}
+	}
+	for _, tc := range []struct {
+		trends []domain.ChartTrend
+		want   domain.Trend
+	}{
+		{trends(domain.Bullish, domain.Bullish, domain.Bearish), domain.Neutral},
+		{trends(domain.Bearish, domain.Neutral, domain.Bullish), domain.Bullish},
+		{trends(domain.Bullish, domain.Bearish, domain.Neutral), domain.Bearish},
+	} {
+		if got := trend.OverallTrend(tc.trends); got != tc.want {
+			t.Errorf("expected %s for %+v, got %s", tc.want, tc.trends, got)
+		}
+	}
+}
+
+// orderedTrendRepo records whether the chart trends were saved in candle order.
+type orderedTrendRepo struct {
+	mu         sync.Mutex
+	last       time.Time
+	outOfOrder int
+}
+
+func (r *orderedTrendRepo) SaveChartTrend(ctx context.Context, t domain.ChartTrend) error {
+	time.Sleep(time.Microsecond) // Lets the saves of concurrent candles interleave.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !t.CandleTime.After(r.last) {
+		r.outOfOrder++
+	}
+	r.last = t.CandleTime
+	return nil
+}
+
+func (r *orderedTrendRepo) LoadChartTrends(ctx context.Context, symbol string) ([]domain.ChartTrend, error) {
+	return nil, nil
+}
+
+func TestChartTrendSavesInCandleOrder(t *testing.T) {
+	candles := makeCandles(make([]float64, 500)...)
+	repo := &orderedTrendRepo{}
+	service := trend.NewChartTrendService(trend.DefaultChartConfig(), repo)
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := next.Add(1) - 1; i < int64(len(candles)); i = next.Add(1) - 1 {
+				if err := service.OnCandle(context.Background(), candles[i]); err != nil {
+					t.Errorf("OnCandle failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stored, _ := service.Trend("BTCUSDT", domain.Minute1)
+	if repo.outOfOrder != 0 || !repo.last.Equal(stored.CandleTime) {
+		t.Errorf("expected the saves in candle order ending at %s, got %d out of order ending at %s", stored.CandleTime, repo.outOfOrder, repo.last)
+	}
+}
+
+// marketTicker builds an all-market ticker for the market trend tests.
+func marketTicker(symbol string, at time.Time, price, quoteVolume float64) domain.Ticker {
+	return domain.Ticker{
+		EventTime:   at.UnixMilli(),
+		Symbol:      symbol,
+		LastPrice:   domain.BigString{Float: bigFloat(price)},
+		QuoteVolume: domain.BigString{Float: bigFloat(quoteVolume)},
+	}
+}
+
+func TestMarketTrendBreadth(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	cfg := trend.MarketConfig{Timeframes: []domain.Timeframe{domain.Minute1}, QuoteAsset: "USDT", Threshold: 10}
+	svc := trend.NewMarketTrendService("MEXC", cfg, repo)
+	start := time.Date(2025, 10, 2, 20, 58, 0, 0, time.UTC)
+
+	svc.OnTickers(ctx, []domain.Ticker{
+		marketTicker("AUSDT", start, 100, 1000),
+		marketTicker("BUSDT", start, 100, 1000),
+		marketTicker("CUSDT", start, 100, 2000),
+		marketTicker("ABTC", start, 100, 1000),
+	})
+	svc.OnTickers(ctx, []domain.Ticker{
+		marketTicker("AUSDT", start.Add(30*time.Second), 110, 1000),
+		marketTicker("BUSDT", start.Add(30*time.Second), 95, 1000),
+		marketTicker("CUSDT", start.Add(30*time.Second), 90, 2000),
+		marketTicker("ABTC", start.Add(30*time.Second), 200, 1000),
+	})
+	completed, err := svc.OnTickers(ctx, []domain.Ticker{marketTicker("AUSDT", start.Add(time.Minute), 110, 1000)})
+	if err != nil {
+		t.Fatalf("OnTickers failed with an unexpected error: %v", err)
+	}
+	if len(completed) != 1 {
+		t.Fatalf("expected one completed period, got %d", len(completed))
+	}
+
+	m := completed[0]
+	// One of three USDT pairs up, two down: breadth = 33.3% - 66.7% = -33.3%.
+	if m.Pairs != 3 || m.Advancing != 1 || m.Declining != 2 || !almostEqual(m.Breadth, -100.0/3) {
+		t.Errorf("unexpected breadth: %+v", m)
+	}
+	// (10*1000 - 5*1000 - 10*2000) / 4000 = -3.75%.
+	if !almostEqual(m.VolumeWeightedChange, -3.75) || m.Trend != domain.Bearish {
+		t.Errorf("unexpected volume weighted change or trend: %+v", m)
+	}
+
+	stored, err := repo.ListMarketTrends(ctx, "MEXC", domain.Minute1, start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ListMarketTrends failed with an unexpected error: %v", err)
+	}
+	if len(stored) != 1 || !stored[0].Time.Equal(start.Add(time.Minute)) || stored[0].Trend != domain.Bearish {
+		t.Errorf("unexpected stored market trends: %+v", stored)
+	}
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/app"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/strategy"
+)
+
+// scripted signals the directions of its script, one entry per candle; "" means no signal.
+type scripted struct {
+	script []domain.Direction
+	calls  int
+}
+
+func (s *scripted) Name() string { return "Scripted" }
+func (s *scripted) WarmUp() int  { return 1 }
+
+func (s *scripted) OnCandle(in strategy.Input) []domain.Signal {
+	dir := s.script[s.calls%len(s.script)]
+	s.calls++
+	if dir == "" {
+		return nil
+	}
+	return []domain.Signal{strategy.NewSignal(s, in, dir)}
+}
+
+func (s *scripted) OnTicker(in strategy.Input, ticker domain.Ticker) []domain.Signal {
+	return nil
+}
+
+// runScript feeds one candle per script entry to a runner with the given trigger rules and
+// returns the emitted signals as a string like "B..S".
+func runScript(t *testing.T, trigger strategy.Trigger, script string) string {
+	t.Helper()
+	directions := map[rune]domain.Direction{'B': domain.Buy, 'S': domain.Sell, '.': ""}
+	s := &scripted{}
+	for _, r := range script {
+		s.script = append(s.script, directions[r])
+	}
+
+	runner := app.NewStrategyRunner("BINANCE", nil)
+	runner.SetTrigger(trigger)
+	runner.Add("BTCUSDT", domain.Minute1, func() strategy.Strategy { return s })
+	collector := &signalCollector{}
+	runner.AddSignalHandler(collector)
+
+	candles := makeCandles(make([]float64, len(script))...)
+	emitted := make(map[time.Time]domain.Direction)
+	for _, c := range candles {
+		if err := runner.OnCandle(context.Background(), c); err != nil {
+			t.Fatalf("OnCandle failed: %v", err)
+		}
+	}
+	for _, sig := range collector.signals {
+		emitted[sig.Time] = sig.Direction
+	}
+	var out strings.Builder
+	for _, c := range candles {
+		switch emitted[c.CloseTime] {
+		case domain.Buy:
+			out.WriteByte('B')
+		case domain.Sell:
+			out.WriteByte('S')
+		default:
+			out.WriteByte('.')
+		}
+	}
+	return out.String()
+}
+
+func TestSignalTriggerRules(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		trigger strategy.Trigger
+		script  string
+		want    string
+	}{
+		{"edge", strategy.DefaultTrigger(), "BBB.BB", "B...B."},
+		{"edge re-armed by the opposite direction", strategy.DefaultTrigger(), "BBSBB", "B.SB."},
+		{"edge re-armed after two quiet candles", strategy.Trigger{Mode: strategy.EdgeTriggered, ReArmAfter: 2}, "B.B..B", "B....B"},
+		{"level", strategy.Trigger{Mode: strategy.LevelTriggered}, "BBB.B", "BBB.B"},
+		{"level with cooldown", strategy.Trigger{Mode: strategy.LevelTriggered, Cooldown: 3 * time.Minute}, "BBBBBBB", "B..B..B"},
+		{"edge with cooldown", strategy.Trigger{Mode: strategy.EdgeTriggered, ReArmAfter: 1, Cooldown: 3 * time.Minute}, "B.B.B.", "B...B."},
+	} {
+		if got := runScript(t, tc.trigger, tc.script); got != tc.want {
+			t.Errorf("%s: script %s: expected %s, got %s", tc.name, tc.script, tc.want, got)
+		}
+	}
+}
+
+func TestSignalTriggerDropsDuplicates(t *testing.T) {
+	s := &scripted{script: []domain.Direction{domain.Buy}}
+	runner := app.NewStrategyRunner("BINANCE", nil)
+	runner.SetTrigger(strategy.Trigger{Mode: strategy.LevelTriggered})
+	runner.Add("BTCUSDT", domain.Minute1, func() strategy.Strategy { return s })
+	collector := &signalCollector{}
+	runner.AddSignalHandler(collector)
+
+	c := makeCandles(10)[0]
+	for i := 0; i < 3; i++ {
+		runner.OnCandle(context.Background(), c)
+	}
+	if len(collector.signals) != 1 {
+		t.Errorf("expected the signal of a replayed candle once, got %d", len(collector.signals))
+	}
+}
+
+func TestDefinitionTrigger(t *testing.T) {
+	def, err := strategy.ParseDefinition([]byte(`{
+	  "name": "x", "timeframes": ["1m"], "entry": "close > 0",
+	  "trigger": {"mode": "level", "cooldown": "2m"}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseDefinition failed with an unexpected error: %v", err)
+	}
+	factory, _ := def.Factory()
+	triggered, ok := factory().(strategy.Triggered)
+	want := strategy.Trigger{Mode: strategy.LevelTriggered, Cooldown: 2 * time.Minute, ReArmAfter: 1}
+	if !ok || !reflect.DeepEqual(triggered.Trigger(), want) {
+		t.Errorf("expected trigger %+v, got %+v", want, triggered)
+	}
+
+	_, err = strategy.ParseDefinition([]byte(`{
+	  "name": "x", "timeframes": ["1m"], "entry": "close > 0",
+	  "trigger": {"mode": "once", "cooldown": "soon", "rearm_after": 0}
+	}`))
+	for _, want := range []string{`trigger.mode: must be "edge" or "level"`, "trigger.cooldown", "trigger.rearm_after: must be at least 1"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to contain %q, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/universe"
+)
+
+// membershipRecorder records the pairs a selector adds and removes.
+type membershipRecorder struct {
+	events []string
+}
+
+func (m *membershipRecorder) AddPair(ctx context.Context, symbol string) error {
+	m.events = append(m.events, "+"+symbol)
+	return nil
+}
+
+func (m *membershipRecorder) RemovePair(ctx context.Context, symbol string) error {
+	m.events = append(m.events, "-"+symbol)
+	return nil
+}
+
+func changeTicker(symbol string, at time.Time, quoteVolume, change float64) domain.Ticker {
+	t := marketTicker(symbol, at, 1, quoteVolume)
+	t.PriceChangePercent = domain.BigString{Float: bigFloat(change)}
+	return t
+}
+
+func TestUniverseSelectorAppliesFiltersWithHysteresis(t *testing.T) {
+	ctx := context.Background()
+	minVolume, maxChange := 1_000_000.0, 10.0
+	cfg := universe.Config{
+		QuoteAsset:       "USDT",
+		Volume24h:        universe.Bounds{Min: &minVolume},
+		Change24h:        universe.Bounds{Max: &maxChange},
+		VolumeHysteresis: 10,
+		ChangeHysteresis: 1,
+		MinHold:          time.Minute,
+		MaxPairs:         2,
+	}
+	recorder := &membershipRecorder{}
+	selector := universe.NewSelector(cfg, recorder)
+	start := time.Date(2025, 10, 2, 20, 0, 0, 0, time.UTC)
+
+	changes := selector.OnTickers(ctx, []domain.Ticker{
+		changeTicker("AUSDT", start, 2_000_000, 1),
+		changeTicker("BUSDT", start, 500_000, 1),    // Too little volume.
+		changeTicker("CUSDT", start, 3_000_000, 12), // Too bullish.
+		changeTicker("DBTC", start, 9_000_000, 1),   // Other quote asset.
+		changeTicker("EUSDT", start, 1_500_000, -3),
+		changeTicker("FUSDT", start, 1_200_000, 0), // No place left.
+	})
+	if got := selector.Pairs(); !reflect.DeepEqual(got, []string{"AUSDT", "EUSDT"}) {
+		t.Fatalf("expected AUSDT and EUSDT to be selected, got %v", got)
+	}
+	if len(changes) != 2 || !strings.Contains(changes[0].Reason, "24h volume 2000000 within [1000000, ∞]") {
+		t.Errorf("unexpected changes: %+v", changes)
+	}
+
+	// Within the hysteresis, and too soon after being added: nothing changes.
+	selector.OnTickers(ctx, []domain.Ticker{
+		changeTicker("AUSDT", start.Add(2*time.Minute), 950_000, 1),
+		changeTicker("EUSDT", start.Add(30*time.Second), 100, -3),
+	})
+	if got := selector.Pairs(); len(got) != 2 {
+		t.Fatalf("expected no pair to leave yet, got %v", got)
+	}
+
+	// Beyond the hysteresis after the minimum hold: AUSDT leaves and FUSDT takes its place.
+	changes = selector.OnTickers(ctx, []domain.Ticker{
+		changeTicker("AUSDT", start.Add(3*time.Minute), 2_000_000, 11.5),
+		changeTicker("FUSDT", start.Add(3*time.Minute), 1_200_000, 0),
+	})
+	if len(changes) != 2 || changes[0].Added || !strings.Contains(changes[0].Reason, "24h change 11.50% above maximum 11.00%") {
+		t.Errorf("unexpected changes: %+v", changes)
+	}
+	want := []string{"+AUSDT", "+EUSDT", "-AUSDT", "+FUSDT"}
+	if !reflect.DeepEqual(recorder.events, want) {
+		t.Errorf("expected membership events %v, got %v", want, recorder.events)
+	}
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dorpsen/cryptotradingbot-starter/internal/backtest"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/domain"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/optimize"
+	"github.com/dorpsen/cryptotradingbot-starter/internal/report"
+)
+
+func TestWalkForwardWindows(t *testing.T) {
+	day := 24 * time.Hour
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	windows, err := optimize.Windows(from, from.Add(25*day), 10*day, 5*day, false)
+	if err != nil {
+		t.Fatalf("Windows failed: %v", err)
+	}
+	if len(windows) != 3 {
+		t.Fatalf("expected 3 windows, got %+v", windows)
+	}
+	for i, w := range windows {
+		if !w.InFrom.Equal(from.Add(time.Duration(i)*5*day)) || !w.OutFrom.Equal(w.InTo) || w.OutTo.Sub(w.OutFrom) != 5*day {
+			t.Errorf("window %d: unexpected %+v", i, w)
+		}
+	}
+
+	windows, err = optimize.Windows(from, from.Add(23*day), 10*day, 5*day, true)
+	if err != nil {
+		t.Fatalf("Windows failed: %v", err)
+	}
+	last := windows[len(windows)-1]
+	if len(windows) != 3 || !last.InFrom.Equal(from) || !last.OutTo.Equal(from.Add(23*day)) {
+		t.Errorf("expected 3 anchored windows cut off at the end, got %+v", windows)
+	}
+	if _, err := optimize.Windows(from, from.Add(5*day), 10*day, 5*day, false); err == nil {
+		t.Error("expected a period shorter than the in-sample window to be rejected")
+	}
+}
+
+func TestWalkForward(t *testing.T) {
+	day := 24 * time.Hour
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	windows, _ := optimize.Windows(from, from.Add(20*day), 10*day, 5*day, false)
+
+	// In-sample, the net profit is a percent of the balance, so a=3 wins; out-of-sample every
+	// window makes 10%.
+	var balances []float64
+	newBacktest := func(cfg backtest.Config) optimize.Backtest {
+		return func(ctx context.Context, p optimize.Params) (*backtest.Result, error) {
+			res := &backtest.Result{From: cfg.From, To: cfg.To, InitialBalance: cfg.InitialBalance, Timeframe: domain.Hour1}
+			profit := cfg.InitialBalance * p["a"] / 100
+			if cfg.From.Equal(windows[0].OutFrom) || cfg.From.Equal(windows[1].OutFrom) {
+				balances = append(balances, cfg.InitialBalance)
+				profit = cfg.InitialBalance / 10
+			}
+			res.FinalEquity = cfg.InitialBalance + profit
+			res.Trades = []backtest.Trade{{EntryTime: cfg.From, ExitTime: cfg.From.Add(time.Hour), PnL: profit}}
+			res.Equity = []backtest.EquityPoint{{Time: cfg.From.Add(time.Hour), Equity: res.FinalEquity}}
+			return res, nil
+		}
+	}
+	cfg := backtest.DefaultConfig()
+	cfg.Symbol, cfg.InitialBalance = "BTCUSDT", 100
+	points := optimize.Grid([]optimize.Param{{Name: "a", Min: 1, Max: 3, Step: 1}})
+	wf, err := optimize.WalkForward(context.Background(), cfg, windows, points, optimize.Objective{Metric: "net_profit"}, 2, newBacktest)
+	if err != nil {
+		t.Fatalf("WalkForward failed: %v", err)
+	}
+	if len(wf.Windows) != 2 {
+		t.Fatalf("expected 2 windows, got %d", len(wf.Windows))
+	}
+	for i, wr := range wf.Windows {
+		if wr.Params["a"] != 3 || wr.Err != nil {
+			t.Errorf("window %d: expected a=3 to be chosen, got %v (%v)", i, wr.Params, wr.Err)
+		}
+	}
+	// The second window trades with the balance the first one ended with.
+	if len(balances) != 2 || balances[0] != 100 || !almostEqual(balances[1], 110) {
+		t.Errorf("expected the out-of-sample balance to carry over from 100 to 110, got %v", balances)
+	}
+	if !almostEqual(wf.OutOfSample.FinalEquity, 121) || len(wf.OutOfSample.Trades) != 2 || !wf.OutOfSample.From.Equal(windows[0].OutFrom) {
+		t.Errorf("expected the stitched out-of-sample run to end at 121 with 2 trades, got %+v", wf.OutOfSample)
+	}
+	if !almostEqual(wf.Metrics.NetProfitPercent, 21) {
+		t.Errorf("expected a 21%% out-of-sample profit, got %v", wf.Metrics.NetProfitPercent)
+	}
+	// 10% in 5 days out-of-sample against 3% in 10 days in-sample.
+	if !almostEqual(wf.Efficiency, (10.0/5)/(3.0/10)) {
+		t.Errorf("unexpected walk-forward efficiency %v", wf.Efficiency)
+	}
+	if len(wf.Stability) != 1 || wf.Stability[0].Mode != 3 || wf.Stability[0].ModeShare != 100 || wf.Stability[0].StdDev != 0 {
+		t.Errorf("expected a perfectly stable a=3, got %+v", wf.Stability)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteWalkForward(&buf, wf, []string{"a"}); err != nil {
+		t.Fatalf("WriteWalkForward failed: %v", err)
+	}
+	for _, want := range []string{"OOS P&L %", "MODE SHARE", "Walk-forward efficiency", "121.00"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected the report to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}